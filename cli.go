@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/audit"
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/closing"
+	"github.com/sbutakov/wallet/pkg/projection"
+	"github.com/sbutakov/wallet/pkg/statement"
+)
+
+const usage = `Usage:
+  wallet                                           start service
+  wallet apikey create -name NAME -scopes S1,S2 [-customer ID]
+                                                   create api key, secret is printed only once,
+                                                   key of customer acts only on behalf of it
+  wallet apikey list                               list api keys
+  wallet apikey revoke -id ID                      revoke api key
+  wallet audit verify                              verify hash chain of audit log
+  wallet statement -account ID [-from T] [-to T] [-format csv|jsonl|txt]
+                                                   write statement of account to stdout, period is
+                                                   month to date unless set by RFC 3339 times or dates
+  wallet balances -as-of T                         write balances of all accounts at RFC 3339 time
+                                                   or end of date to stdout as CSV
+  wallet snapshots take -day D                     take snapshots of balances at end of day
+  wallet snapshots verify -from D [-to D]          verify snapshots of days against payments,
+                                                   period is until today unless set
+  wallet projections status                        list positions of projections and their lag
+  wallet projections rebuild -name NAME            rebuild read model of projection from all events`
+
+// runCommand run command line tool instead of service
+func runCommand(args []string, authService *auth.Service, auditService *audit.Service,
+	statementService *statement.Service, closingService *closing.Service,
+	projectionService *projection.Service, out io.Writer) error {
+
+	if len(args) < 2 {
+		return errors.New(usage)
+	}
+
+	ctx := context.Background()
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	switch args[0] {
+	case "apikey":
+		return runAPIKeyCommand(ctx, args, authService, encoder, out)
+	case "audit":
+		return runAuditCommand(ctx, args, auditService, encoder)
+	case "statement":
+		return runStatementCommand(ctx, args, statementService, out)
+	case "balances":
+		return runBalancesCommand(ctx, args, statementService, out)
+	case "snapshots":
+		return runSnapshotsCommand(ctx, args, closingService, encoder, out)
+	case "projections":
+		return runProjectionsCommand(ctx, args, projectionService, encoder, out)
+	}
+	return errors.New(usage)
+}
+
+func runAuditCommand(ctx context.Context, args []string, auditService *audit.Service, encoder *json.Encoder) error {
+	if args[1] != "verify" {
+		return errors.New(usage)
+	}
+
+	result, err := auditService.Verify(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "verified %d entries", result.Entries)
+	}
+	return encoder.Encode(result)
+}
+
+func runStatementCommand(
+	ctx context.Context, args []string, statementService *statement.Service, out io.Writer) error {
+
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(out)
+	accountID := flags.String("account", "", "identifier of account")
+	from := flags.String("from", "", "start of period, default is start of month")
+	to := flags.String("to", "", "end of period exclusive, default is now")
+	format := flags.String("format", statement.FormatCSV, "format: "+strings.Join(statement.Formats, ","))
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	var err error
+	end := time.Now().UTC()
+	if *to != "" {
+		if end, err = statement.ParseTime(*to); err != nil {
+			return errors.Wrap(err, "to")
+		}
+	}
+	start := statement.StartOfMonth(end)
+	if *from != "" {
+		if start, err = statement.ParseTime(*from); err != nil {
+			return errors.Wrap(err, "from")
+		}
+	}
+
+	st, err := statementService.Prepare(ctx, *accountID, start, end, *format)
+	if err != nil {
+		return err
+	}
+	return statementService.Write(ctx, st, out)
+}
+
+func runBalancesCommand(
+	ctx context.Context, args []string, statementService *statement.Service, out io.Writer) error {
+
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(out)
+	asOf := flags.String("as-of", "", "time of balances, date is end of day")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	at, err := statement.ParseAsOf(*asOf)
+	if err != nil {
+		return errors.Wrap(err, "as-of")
+	}
+	balances, err := statementService.Balances(ctx, at)
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(out)
+	w.Write([]string{"account_id", "currency", "balance", "as_of"}) // nolint: errcheck
+	for _, balance := range balances {
+		w.Write([]string{ // nolint: errcheck
+			balance.AccountID,
+			balance.Currency,
+			strconv.FormatFloat(balance.Balance, 'f', 2, 64),
+			balance.AsOf.Format(time.RFC3339),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func runSnapshotsCommand(ctx context.Context, args []string, closingService *closing.Service,
+	encoder *json.Encoder, out io.Writer) error {
+
+	flags := flag.NewFlagSet(args[0]+" "+args[1], flag.ContinueOnError)
+	flags.SetOutput(out)
+	switch args[1] {
+	case "take":
+		day := flags.String("day", "", "day of snapshots")
+		if err := flags.Parse(args[2:]); err != nil {
+			return err
+		}
+		d, err := closing.ParseDay(*day)
+		if err != nil {
+			return errors.Wrap(err, "day")
+		}
+
+		taken, err := closingService.Snapshot(ctx, d)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%d snapshots of %s taken\n", taken, *day)
+		return err
+
+	case "verify":
+		from := flags.String("from", "", "first day")
+		to := flags.String("to", "", "day after last day, default is today")
+		if err := flags.Parse(args[2:]); err != nil {
+			return err
+		}
+		start, err := closing.ParseDay(*from)
+		if err != nil {
+			return errors.Wrap(err, "from")
+		}
+		end := time.Now()
+		if *to != "" {
+			if end, err = closing.ParseDay(*to); err != nil {
+				return errors.Wrap(err, "to")
+			}
+		}
+
+		result, err := closingService.Verify(ctx, start, end)
+		if err != nil {
+			return err
+		}
+		if err = encoder.Encode(result); err != nil {
+			return err
+		}
+		if len(result.Mismatches) > 0 {
+			return errors.Errorf("%d of %d snapshots differ from payments", len(result.Mismatches), result.Snapshots)
+		}
+		return nil
+	}
+	return errors.New(usage)
+}
+
+func runProjectionsCommand(ctx context.Context, args []string, projectionService *projection.Service,
+	encoder *json.Encoder, out io.Writer) error {
+
+	flags := flag.NewFlagSet(args[0]+" "+args[1], flag.ContinueOnError)
+	flags.SetOutput(out)
+	switch args[1] {
+	case "status":
+		statuses, err := projectionService.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return encoder.Encode(statuses)
+
+	case "rebuild":
+		name := flags.String("name", "", "projection: "+strings.Join(projection.Names, ","))
+		if err := flags.Parse(args[2:]); err != nil {
+			return err
+		}
+		status, err := projectionService.Rebuild(ctx, *name)
+		if err != nil {
+			return err
+		}
+		return encoder.Encode(status)
+	}
+	return errors.New(usage)
+}
+
+func runAPIKeyCommand(
+	ctx context.Context, args []string, authService *auth.Service, encoder *json.Encoder, out io.Writer) error {
+
+	flags := flag.NewFlagSet(args[0]+" "+args[1], flag.ContinueOnError)
+	flags.SetOutput(out)
+	switch args[1] {
+	case "create":
+		name := flags.String("name", "", "name of api key owner")
+		customerID := flags.String("customer", "", "identifier of customer represented by api key")
+		scopes := flags.String("scopes", "", "comma separated scopes: "+strings.Join(auth.Scopes, ","))
+		if err := flags.Parse(args[2:]); err != nil {
+			return err
+		}
+
+		key, secret, err := authService.CreateKey(ctx, *customerID, *name, strings.Split(*scopes, ","))
+		if err != nil {
+			return err
+		}
+		return encoder.Encode(map[string]interface{}{
+			"key":    key,
+			"secret": secret,
+		})
+
+	case "list":
+		keys, err := authService.ListKeys(ctx)
+		if err != nil {
+			return err
+		}
+		return encoder.Encode(keys)
+
+	case "revoke":
+		id := flags.String("id", "", "identifier of api key")
+		if err := flags.Parse(args[2:]); err != nil {
+			return err
+		}
+		if err := authService.RevokeKey(ctx, *id); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(out, "api key %s revoked\n", *id)
+		return err
+	}
+	return errors.New(usage)
+}
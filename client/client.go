@@ -0,0 +1,218 @@
+// Package client provides Go client of v2 of wallet HTTP API
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/logging"
+	"github.com/sbutakov/wallet/pkg/payment"
+)
+
+// IdempotencyKeyHeader header carries key identifying call, it is same for all attempts of call,
+// server makes transfer once for all attempts with the same key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Option configures client
+type Option func(*options)
+
+type options struct {
+	httpClient kithttp.HTTPClient
+	apiKey     string
+	token      string
+	retries    int
+	backoff    time.Duration
+}
+
+// WithHTTPClient set http client sending requests, http.DefaultClient is used by default
+func WithHTTPClient(client kithttp.HTTPClient) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+// WithAPIKey authenticate requests by api key
+func WithAPIKey(key string) Option {
+	return func(o *options) {
+		o.apiKey = key
+	}
+}
+
+// WithBearerToken authenticate requests by JWT bearer token
+func WithBearerToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithRetries set number of retries of failed call and delay before first retry,
+// delay is doubled on every next retry
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.retries = retries
+		o.backoff = backoff
+	}
+}
+
+// Client client of wallet HTTP API
+type Client struct {
+	createAccount endpoint.Endpoint
+	listAccounts  endpoint.Endpoint
+	transferMoney endpoint.Endpoint
+	listPayments  endpoint.Endpoint
+}
+
+// New is constructor, base url is address of wallet service, e.g. http://localhost:8080
+func New(baseURL string, opts ...Option) (*Client, error) {
+	base, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, errors.Wrap(err, "error on parse base url")
+	}
+	if base.Scheme == "" || base.Host == "" {
+		return nil, errors.Errorf("base url %q must be absolute", baseURL)
+	}
+
+	o := &options{
+		httpClient: http.DefaultClient,
+		retries:    3,
+		backoff:    100 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	// idempotent calls are safe to repeat when server may have processed them
+	makeEndpoint := func(method, path string, idempotent bool, enc kithttp.EncodeRequestFunc,
+		result func() interface{}) endpoint.Endpoint {
+
+		target := *base
+		target.Path += path
+		e := kithttp.NewClient(method, &target, enc, decodeResponse(result),
+			kithttp.SetClient(o.httpClient),
+			kithttp.ClientBefore(o.setHeaders),
+		).Endpoint()
+		return retry(idempotent, o.retries, o.backoff)(e)
+	}
+
+	return &Client{
+		createAccount: makeEndpoint(http.MethodPost, "/v2/accounts/", false, kithttp.EncodeJSONRequest,
+			func() interface{} { return new(accountResult) }),
+		listAccounts: makeEndpoint(http.MethodGet, "/v2/accounts/", true, encodeEmptyRequest,
+			func() interface{} { return new([]*accountResult) }),
+		transferMoney: makeEndpoint(http.MethodPost, "/v2/payments/", true, kithttp.EncodeJSONRequest,
+			func() interface{} { return new(paymentResult) }),
+		listPayments: makeEndpoint(http.MethodGet, "/v2/payments/", true, encodeEmptyRequest,
+			func() interface{} { return new([]*paymentResult) }),
+	}, nil
+}
+
+// CreateAccount create account, customer id is optional
+func (c *Client) CreateAccount(
+	ctx context.Context, customerID, name, currency string, balance float64) (*account.Account, error) {
+
+	response, err := c.createAccount(ctx, createAccountRequest{
+		CustomerID: customerID,
+		Name:       name,
+		Balance:    decimal(balance),
+		Currency:   currency,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.(*accountResult).account(), nil
+}
+
+// ListAccounts return accounts visible to client
+func (c *Client) ListAccounts(ctx context.Context) ([]*account.Account, error) {
+	response, err := c.listAccounts(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	results := *response.(*[]*accountResult)
+	accounts := make([]*account.Account, len(results))
+	for i, result := range results {
+		accounts[i] = result.account()
+	}
+	return accounts, nil
+}
+
+// TransferMoney transfer amount between accounts, transfer is made once however many
+// times call is repeated
+func (c *Client) TransferMoney(
+	ctx context.Context, accountFromID, accountToID string, amount float64) (*payment.Payment, error) {
+
+	response, err := c.transferMoney(ctx, transferMoneyRequest{
+		AccountFrom: accountFromID,
+		AccountTo:   accountToID,
+		Amount:      decimal(amount),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.(*paymentResult).payment(), nil
+}
+
+// ListPayments return payments visible to client
+func (c *Client) ListPayments(ctx context.Context) ([]*payment.Payment, error) {
+	response, err := c.listPayments(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	results := *response.(*[]*paymentResult)
+	payments := make([]*payment.Payment, len(results))
+	for i, result := range results {
+		payments[i] = result.payment()
+	}
+	return payments, nil
+}
+
+// setHeaders set credentials, idempotency key and request identifier of call
+func (o *options) setHeaders(ctx context.Context, r *http.Request) context.Context {
+	r.Header.Set("Accept", "application/json")
+	if o.apiKey != "" {
+		r.Header.Set(auth.APIKeyHeader, o.apiKey)
+	}
+	if o.token != "" {
+		r.Header.Set("Authorization", "Bearer "+o.token)
+	}
+	if key := idempotencyKeyFromContext(ctx); key != "" {
+		r.Header.Set(IdempotencyKeyHeader, key)
+	}
+	if id := logging.RequestIDFromContext(ctx); id != "" {
+		r.Header.Set(logging.RequestIDHeader, id)
+	}
+	return ctx
+}
+
+func encodeEmptyRequest(context.Context, *http.Request, interface{}) error {
+	return nil
+}
+
+// decodeResponse decode result of successful response into value made by result,
+// other responses are decoded as problem details
+func decodeResponse(result func() interface{}) kithttp.DecodeResponseFunc {
+	return func(_ context.Context, r *http.Response) (interface{}, error) {
+		if r.StatusCode < 200 || r.StatusCode > 299 {
+			return nil, decodeProblem(r)
+		}
+
+		value := result()
+		resp := struct {
+			Data interface{} `json:"data"`
+		}{Data: value}
+		if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+			return nil, errors.Wrap(err, "error on decode response")
+		}
+		return value, nil
+	}
+}
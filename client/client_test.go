@@ -0,0 +1,174 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/endpoints"
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/metadata"
+	"github.com/sbutakov/wallet/pkg/payment"
+)
+
+const (
+	dummyAccountFrom = "7b6c7d2a-3f0e-4d8e-9a51-1f3c2b4a5d60"
+	dummyAccountTo   = "2c1d9e8f-6a5b-4c3d-8e7f-0a1b2c3d4e5f"
+)
+
+type dummyService struct {
+}
+
+func (d *dummyService) List(context.Context, metadata.Filter) ([]*account.Account, error) {
+	return []*account.Account{{ID: dummyAccountFrom, Name: "dummy", Currency: "usd", Balance: 10}}, nil
+}
+
+func (d *dummyService) ListByCustomer(context.Context, string) ([]*account.Account, error) {
+	return nil, nil
+}
+
+func (d *dummyService) Create(_ context.Context, customerID, name, currency string, balance float64,
+	_ string, _ metadata.Metadata) (*account.Account, error) {
+	return &account.Account{
+		ID: dummyAccountFrom, CustomerID: customerID, Name: name, Currency: currency, Balance: balance}, nil
+}
+
+func (d *dummyService) PaymentList(context.Context, payment.Filter) ([]*payment.Payment, error) {
+	return []*payment.Payment{{ID: "1", Amount: 5, AccountFrom: dummyAccountFrom, AccountTo: dummyAccountTo}}, nil
+}
+
+func (d *dummyService) TransferMoney(
+	_ context.Context, _, _ string, amount float64, _ payment.Details) (*payment.Payment, error) {
+
+	if amount > 10 {
+		return nil, errors.Wrap(payment.ErrorNotEnoughMoney, "error on transfer money")
+	}
+	return &payment.Payment{ID: "1", Amount: amount, AccountFrom: dummyAccountFrom, AccountTo: dummyAccountTo}, nil
+}
+
+func makeServer() *httptest.Server {
+	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	version := endpoints.WithVersion(endpoints.V2)
+	router := chi.NewRouter()
+	router.Mount("/v2/accounts", endpoints.MakeAccountEndpoints(&dummyService{}, nil, nil, logger, version))
+	router.Mount("/v2/payments", endpoints.MakePaymentEndpoints(&dummyService{}, logger, version))
+	return httptest.NewServer(router)
+}
+
+func TestClient(t *testing.T) {
+	server := makeServer()
+	defer server.Close()
+
+	c, err := New(server.URL, WithAPIKey("dummy"))
+	if err != nil {
+		t.Fatal("unexpected error on init client")
+	}
+
+	ctx := context.Background()
+	acc, err := c.CreateAccount(ctx, "", "dummy", "usd", 10.5)
+	if err != nil || acc.ID != dummyAccountFrom || acc.Balance != 10.5 {
+		t.Errorf("unexpected result of create account %v, %v", acc, err)
+	}
+
+	accounts, err := c.ListAccounts(ctx)
+	if err != nil || len(accounts) != 1 || accounts[0].Name != "dummy" {
+		t.Errorf("unexpected result of list accounts %v, %v", accounts, err)
+	}
+
+	pay, err := c.TransferMoney(ctx, dummyAccountFrom, dummyAccountTo, 5.25)
+	if err != nil || pay.Amount != 5.25 {
+		t.Errorf("unexpected result of transfer money %v, %v", pay, err)
+	}
+
+	payments, err := c.ListPayments(ctx)
+	if err != nil || len(payments) != 1 || payments[0].AccountTo != dummyAccountTo {
+		t.Errorf("unexpected result of list payments %v, %v", payments, err)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	server := makeServer()
+	defer server.Close()
+
+	c, err := New(server.URL)
+	if err != nil {
+		t.Fatal("unexpected error on init client")
+	}
+
+	_, err = c.TransferMoney(context.Background(), dummyAccountFrom, dummyAccountTo, 20)
+	if errors.Cause(err) != payment.ErrorNotEnoughMoney {
+		t.Errorf("expected error of domain, got %v", err)
+	}
+	if e, ok := err.(*Error); !ok || e.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("expected error with status code, got %v", err)
+	}
+
+	_, err = c.TransferMoney(context.Background(), "dummy", dummyAccountTo, 1)
+	e := apperror.From(err)
+	if e.Code != "validation_failed" || e.Kind != apperror.KindInvalidArgument || len(e.Fields) != 1 {
+		t.Errorf("expected validation error, got %v", err)
+	}
+
+	if _, err = New("localhost"); err == nil {
+		t.Error("expected error on relative base url")
+	}
+}
+
+func TestClient_Retry(t *testing.T) {
+	statuses := map[string][]int{
+		"GET /v2/accounts/":  {http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK},
+		"POST /v2/payments/": {http.StatusServiceUnavailable, http.StatusGatewayTimeout, http.StatusOK},
+		"POST /v2/accounts/": {http.StatusServiceUnavailable, http.StatusGatewayTimeout, http.StatusOK},
+	}
+	attempts := map[string]int{}
+	keys := map[string][]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := r.Method + " " + r.URL.Path
+		status := statuses[call][attempts[call]]
+		attempts[call]++
+		keys[call] = append(keys[call], r.Header.Get(IdempotencyKeyHeader))
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			return
+		}
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"data":[]}`)) // nolint: errcheck
+		} else {
+			w.Write([]byte(`{"data":{"amount":"1.00","balance":"1.00"}}`)) // nolint: errcheck
+		}
+	}))
+	defer server.Close()
+
+	c, err := New(server.URL, WithRetries(3, time.Millisecond))
+	if err != nil {
+		t.Fatal("unexpected error on init client")
+	}
+
+	if _, err = c.ListAccounts(context.Background()); err != nil || attempts["GET /v2/accounts/"] != 3 {
+		t.Errorf("expected read retried on server failures, attempts %d, %v", attempts["GET /v2/accounts/"], err)
+	}
+
+	// transfer is deduplicated by server with idempotency key
+	_, err = c.TransferMoney(context.Background(), dummyAccountFrom, dummyAccountTo, 1)
+	transfers := keys["POST /v2/payments/"]
+	if err != nil || len(transfers) != 3 || transfers[0] == "" || transfers[0] != transfers[2] {
+		t.Errorf("expected transfer retried with same idempotency key, got %v, %v", transfers, err)
+	}
+
+	// other write is not repeated when server may have processed it
+	_, err = c.CreateAccount(context.Background(), "", "dummy", "usd", 1)
+	if e, ok := err.(*Error); !ok || e.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("expected gateway timeout, got %v", err)
+	}
+	if attempts["POST /v2/accounts/"] != 2 {
+		t.Errorf("expected write retried once, attempts %d", attempts["POST /v2/accounts/"])
+	}
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/alias"
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/closing"
+	"github.com/sbutakov/wallet/pkg/customer"
+	"github.com/sbutakov/wallet/pkg/metadata"
+	"github.com/sbutakov/wallet/pkg/payment"
+	"github.com/sbutakov/wallet/pkg/paymentrequest"
+)
+
+// maxProblemSize limit of problem details read from response
+const maxProblemSize = 64 << 10
+
+// knownErrors errors of domain packages returned by cause of Error with same code
+var knownErrors = map[string]error{}
+
+func init() {
+	for _, err := range []*apperror.Error{
+		account.ErrorNotFound,
+		account.ErrorBalanceValue,
+		account.ErrorUnsupportedCurrency,
+		payment.ErrorDifferentCurrencies,
+		payment.ErrorIncorrectAmount,
+		payment.ErrorNotEnoughMoney,
+		payment.ErrorTransferYourself,
+		payment.ErrorMoneyTransfer,
+		payment.ErrorUnknownCategory,
+		payment.ErrorReservedExternalID,
+		metadata.ErrorDuplicateExternalID,
+		closing.ErrorPeriodClosed,
+		alias.ErrorInvalid,
+		alias.ErrorNotFound,
+		alias.ErrorTaken,
+		alias.ErrorNotVerified,
+		alias.ErrorNoAccount,
+		paymentrequest.ErrorNotFound,
+		paymentrequest.ErrorNotPending,
+		paymentrequest.ErrorExpired,
+		paymentrequest.ErrorIncorrectExpiry,
+		customer.ErrorNotFound,
+		customer.ErrorNotOwner,
+		auth.ErrorUnauthorized,
+		auth.ErrorForbidden,
+		auth.ErrorInvalidToken,
+	} {
+		knownErrors[err.Code] = err
+	}
+}
+
+// Error error responded by wallet API, its cause is matching error of domain package,
+// e.g. errors.Cause(err) == payment.ErrorNotEnoughMoney, or typed error made
+// of problem details when code is unknown to client
+type Error struct {
+	StatusCode int
+	Problem    apperror.Problem
+	// RetryAfter delay requested by server before retry of rate limited call
+	RetryAfter time.Duration
+
+	cause error
+}
+
+func (e *Error) Error() string {
+	if e.Problem.Detail != "" {
+		return e.Problem.Detail
+	}
+	if e.Problem.Title != "" {
+		return e.Problem.Title
+	}
+	return http.StatusText(e.StatusCode)
+}
+
+// Cause return error of domain package
+func (e *Error) Cause() error {
+	return e.cause
+}
+
+// Unwrap return error of domain package
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Temporary report error is caused by failure of server or gateway
+func (e *Error) Temporary() bool {
+	return e.StatusCode >= http.StatusInternalServerError
+}
+
+// decodeProblem make error of unsuccessful response
+func decodeProblem(r *http.Response) error {
+	e := &Error{StatusCode: r.StatusCode}
+	if seconds, err := strconv.Atoi(r.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		e.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	data, _ := io.ReadAll(io.LimitReader(r.Body, maxProblemSize)) // nolint: errcheck
+	if json.Unmarshal(data, &e.Problem) != nil || e.Problem.Code == "" {
+		e.Problem = apperror.Problem{
+			Title:  http.StatusText(r.StatusCode),
+			Status: r.StatusCode,
+			Code:   apperror.CodeInternal,
+		}
+	}
+
+	if known, ok := knownErrors[e.Problem.Code]; ok {
+		e.cause = known
+		return e
+	}
+	e.cause = &apperror.Error{
+		Kind:    statusKind(r.StatusCode),
+		Code:    e.Problem.Code,
+		Message: e.Error(),
+		Fields:  e.Problem.InvalidParams,
+	}
+	return e
+}
+
+// statusKind kind of error responded with http status code
+func statusKind(status int) apperror.Kind {
+	for _, kind := range []apperror.Kind{
+		apperror.KindInvalidArgument,
+		apperror.KindNotFound,
+		apperror.KindFailedPrecondition,
+		apperror.KindUnauthenticated,
+		apperror.KindPermissionDenied,
+		apperror.KindConflict,
+		apperror.KindTooLarge,
+		apperror.KindRateLimited,
+	} {
+		if apperror.HTTPStatus(kind) == status {
+			return kind
+		}
+	}
+	return apperror.KindInternal
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/satori/go.uuid"
+)
+
+type contextKey int
+
+const contextKeyIdempotency contextKey = iota
+
+func idempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(contextKeyIdempotency).(string)
+	return key
+}
+
+// retry middleware assigns idempotency key to call and repeats failed attempts with
+// exponential backoff. Idempotent calls (reads and transfers deduplicated by server with
+// the key) are repeated on any server failure, other writes only when request was rejected
+// before processing: connection was not established, service was unavailable or call was
+// rate limited, then delay requested by server is respected
+func retry(idempotent bool, retries int, backoff time.Duration) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			ctx = context.WithValue(ctx, contextKeyIdempotency, uuid.NewV4().String())
+			delay := backoff
+			for attempt := 0; ; attempt++ {
+				response, err := next(ctx, request)
+				if err == nil || attempt >= retries || !retryable(idempotent, err) {
+					return response, err
+				}
+
+				wait := delay
+				if e, ok := err.(*Error); ok && e.RetryAfter > wait {
+					wait = e.RetryAfter
+				}
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, err
+				case <-timer.C:
+				}
+				delay *= 2
+			}
+		}
+	}
+}
+
+func retryable(idempotent bool, err error) bool {
+	if e, ok := err.(*Error); ok {
+		if e.StatusCode == http.StatusTooManyRequests {
+			return true
+		}
+		if idempotent {
+			return e.Temporary()
+		}
+		return e.StatusCode == http.StatusServiceUnavailable
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if idempotent {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
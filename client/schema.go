@@ -0,0 +1,101 @@
+package client
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/metadata"
+	"github.com/sbutakov/wallet/pkg/payment"
+)
+
+// decimal money amount encoded as decimal string by v2 of API
+type decimal float64
+
+func (d decimal) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(strconv.FormatFloat(float64(d), 'f', -1, 64))), nil
+}
+
+func (d *decimal) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return errors.Wrap(err, "error on decode amount")
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return errors.Wrap(err, "error on decode amount")
+	}
+	*d = decimal(f)
+	return nil
+}
+
+type createAccountRequest struct {
+	CustomerID string  `json:"customer_id,omitempty"`
+	Name       string  `json:"name"`
+	Balance    decimal `json:"balance"`
+	Currency   string  `json:"currency"`
+}
+
+type transferMoneyRequest struct {
+	AccountFrom string  `json:"account_from"`
+	AccountTo   string  `json:"account_to"`
+	Amount      decimal `json:"amount"`
+}
+
+type accountResult struct {
+	ID         string            `json:"id"`
+	CustomerID string            `json:"customer_id"`
+	Name       string            `json:"name"`
+	Currency   string            `json:"currency"`
+	Balance    decimal           `json:"balance"`
+	ExternalID string            `json:"external_id"`
+	Metadata   metadata.Metadata `json:"metadata"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+func (r *accountResult) account() *account.Account {
+	return &account.Account{
+		ID:         r.ID,
+		CustomerID: r.CustomerID,
+		Name:       r.Name,
+		Currency:   r.Currency,
+		Balance:    float64(r.Balance),
+		ExternalID: r.ExternalID,
+		Metadata:   r.Metadata,
+		CreatedAt:  r.CreatedAt,
+	}
+}
+
+type paymentResult struct {
+	ID          string            `json:"id"`
+	Amount      decimal           `json:"amount"`
+	Currency    string            `json:"currency"`
+	AccountTo   string            `json:"account_to"`
+	AccountFrom string            `json:"account_from"`
+	Direction   string            `json:"direction"`
+	ExternalID  string            `json:"external_id"`
+	Metadata    metadata.Metadata `json:"metadata"`
+	Description string            `json:"description"`
+	Category    string            `json:"category"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+func (r *paymentResult) payment() *payment.Payment {
+	return &payment.Payment{
+		ID:          r.ID,
+		Amount:      float64(r.Amount),
+		Currency:    r.Currency,
+		AccountTo:   r.AccountTo,
+		AccountFrom: r.AccountFrom,
+		Direction:   r.Direction,
+		ExternalID:  r.ExternalID,
+		Metadata:    r.Metadata,
+		Description: r.Description,
+		Category:    r.Category,
+		CreatedAt:   r.CreatedAt,
+	}
+}
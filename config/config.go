@@ -4,18 +4,40 @@ import (
 	"github.com/kelseyhightower/envconfig"
 	"github.com/pkg/errors"
 
+	"github.com/sbutakov/wallet/endpoints"
 	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/closing"
+	"github.com/sbutakov/wallet/pkg/health"
+	"github.com/sbutakov/wallet/pkg/logging"
+	"github.com/sbutakov/wallet/pkg/paymentrequest"
 	"github.com/sbutakov/wallet/pkg/postgres"
+	"github.com/sbutakov/wallet/pkg/projection"
+	"github.com/sbutakov/wallet/pkg/ratelimit"
+	"github.com/sbutakov/wallet/pkg/reporting"
+	"github.com/sbutakov/wallet/pkg/tracing"
 )
 
 // Config service configuration
 type Config struct {
 	Service struct {
-		ListenAddress string
+		ListenAddress      string
+		GRPCListenAddress  string
+		AdminListenAddress string
 	}
 
-	Account  account.Config
-	Postgres postgres.Config
+	API            endpoints.Config
+	Account        account.Config
+	Auth           auth.Config
+	Closing        closing.Config
+	Health         health.Config
+	Log            logging.Config
+	PaymentRequest paymentrequest.Config
+	Postgres       postgres.Config
+	Projection     projection.Config
+	RateLimit      ratelimit.Config
+	Reporting      reporting.Config
+	Tracing        tracing.Config
 }
 
 // LoadConfigFromEnv load configuration from environment variables
@@ -25,12 +47,55 @@ func LoadConfigFromEnv() (*Config, error) {
 		return nil, errors.Wrap(err, "error on parse config")
 	}
 
+	if err := envconfig.Process("api", &config.API); err != nil {
+		return nil, errors.Wrap(err, "error on parse config")
+	}
+
 	if err := envconfig.Process("account", &config.Account); err != nil {
 		return nil, errors.Wrap(err, "error on parse config")
 	}
 
+	if err := envconfig.Process("auth", &config.Auth); err != nil {
+		return nil, errors.Wrap(err, "error on parse config")
+	}
+	if err := config.Auth.JWT.Validate(); err != nil {
+		return nil, errors.Wrap(err, "error on parse config")
+	}
+
+	if err := envconfig.Process("closing", &config.Closing); err != nil {
+		return nil, errors.Wrap(err, "error on parse config")
+	}
+
+	if err := envconfig.Process("health", &config.Health); err != nil {
+		return nil, errors.Wrap(err, "error on parse config")
+	}
+
+	if err := envconfig.Process("log", &config.Log); err != nil {
+		return nil, errors.Wrap(err, "error on parse config")
+	}
+
+	if err := envconfig.Process("paymentrequest", &config.PaymentRequest); err != nil {
+		return nil, errors.Wrap(err, "error on parse config")
+	}
+
 	if err := envconfig.Process("postgres", &config.Postgres); err != nil {
 		return nil, errors.Wrap(err, "error on parse config")
 	}
+
+	if err := envconfig.Process("projection", &config.Projection); err != nil {
+		return nil, errors.Wrap(err, "error on parse config")
+	}
+
+	if err := envconfig.Process("ratelimit", &config.RateLimit); err != nil {
+		return nil, errors.Wrap(err, "error on parse config")
+	}
+
+	if err := envconfig.Process("reporting", &config.Reporting); err != nil {
+		return nil, errors.Wrap(err, "error on parse config")
+	}
+
+	if err := envconfig.Process("tracing", &config.Tracing); err != nil {
+		return nil, errors.Wrap(err, "error on parse config")
+	}
 	return config, nil
 }
@@ -2,8 +2,11 @@ package endpoints
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/go-kit/kit/endpoint"
@@ -12,97 +15,280 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/importer"
+	"github.com/sbutakov/wallet/pkg/metadata"
+	"github.com/sbutakov/wallet/pkg/statement"
+	"github.com/sbutakov/wallet/pkg/validation"
 )
 
 type accountCreateRequest struct {
-	Name     string  `json:"name"`
-	Balance  float64 `json:"balance"`
-	Currency string  `json:"currency"`
+	CustomerID string            `json:"customer_id" validate:"uuid"`
+	Name       string            `json:"name" validate:"required,max=50,name"`
+	Balance    float64           `json:"balance" validate:"precision=2"`
+	Currency   string            `json:"currency" validate:"required,currency"`
+	ExternalID string            `json:"external_id" validate:"max=64"`
+	Metadata   metadata.Metadata `json:"metadata" validate:"metadata"`
 }
 
 // AccountService interface for creating and viewing accounts
 type AccountService interface {
-	List() ([]*account.Account, error)
-	Create(name, currency string, balance float64) (*account.Account, error)
+	List(ctx context.Context, filter metadata.Filter) ([]*account.Account, error)
+	ListByCustomer(ctx context.Context, customerID string) ([]*account.Account, error)
+	Create(ctx context.Context, customerID, name, currency string, balance float64,
+		externalID string, meta metadata.Metadata) (*account.Account, error)
 }
 
-// MakeAccountEndpoints init router for handling create and view accounts
-func MakeAccountEndpoints(service AccountService, logger kitlog.Logger) http.Handler {
+// StatementService interface for generating statements and balances at time of accounts
+type StatementService interface {
+	Prepare(ctx context.Context, accountID string, from, to time.Time, format string) (*statement.Statement, error)
+	Write(ctx context.Context, st *statement.Statement, w io.Writer) error
+	Balance(ctx context.Context, accountID string, at time.Time) (*statement.Balance, error)
+	Balances(ctx context.Context, at time.Time) ([]*statement.Balance, error)
+}
+
+// ImportService interface for bulk import of accounts
+type ImportService interface {
+	Import(ctx context.Context, r io.Reader, opts importer.Options) (*importer.Report, error)
+}
+
+// MakeAccountEndpoints init router for handling create, import and view accounts, their statements
+// and balances at time
+func MakeAccountEndpoints(service AccountService, statements StatementService, imports ImportService,
+	logger kitlog.Logger, opts ...Option) http.Handler {
+
+	o := newOptions(opts)
 	router := chi.NewRouter()
 	router.Method(http.MethodPost, "/", kithttp.NewServer(
-		createAccount(service), decodeAccountCreateRequest, encodeAccountCreateResponse,
-		[]kithttp.ServerOption{
+		o.endpoint("create_account", createAccount(service)),
+		decodeAccountCreateRequest,
+		encodeAccountCreateResponse,
+		o.server("create_account",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodPost, "/import", kithttp.NewServer(
+		o.endpoint("import_accounts", importAccounts(imports)),
+		decodeImportAccountsRequest,
+		encodeResult,
+		o.server("import_accounts",
 			kithttp.ServerErrorLogger(logger),
-			kithttp.ServerErrorEncoder(encodeAccountError),
-		}...))
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
 
 	router.Method(http.MethodGet, "/", kithttp.NewServer(
-		listAccount(service), decodeListAccountsRequest, encodeListAccountResponse,
-		[]kithttp.ServerOption{
+		o.endpoint("list_accounts", listAccount(service)),
+		decodeListAccountsRequest,
+		encodeListAccountResponse,
+		o.server("list_accounts",
 			kithttp.ServerErrorLogger(logger),
-			kithttp.ServerErrorEncoder(encodeAccountError),
-		}...))
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodGet, "/{id}/statement", kithttp.NewServer(
+		o.endpoint("account_statement", accountStatement(statements)),
+		decodeAccountStatementRequest,
+		encodeAccountStatementResponse(statements, logger),
+		o.server("account_statement",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodGet, "/{id}/balance", kithttp.NewServer(
+		o.endpoint("account_balance", accountBalance(statements)),
+		decodeAccountBalanceRequest,
+		encodeResult,
+		o.server("account_balance",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodGet, "/balances", kithttp.NewServer(
+		o.endpoint("account_balances", accountBalances(statements)),
+		decodeAccountBalanceRequest,
+		encodeResult,
+		o.server("account_balances",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
 
 	return router
 }
 
 func createAccount(service AccountService) endpoint.Endpoint {
-	return func(_ context.Context, request interface{}) (interface{}, error) {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(accountCreateRequest)
-		return service.Create(req.Name, req.Currency, req.Balance)
+		return service.Create(ctx, req.CustomerID, req.Name, req.Currency, req.Balance, req.ExternalID, req.Metadata)
 	}
 }
 
-func decodeAccountCreateRequest(_ context.Context, r *http.Request) (interface{}, error) {
+func decodeAccountCreateRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	if versionFromContext(ctx) == V2 {
+		req := accountCreateRequestV2{}
+		if err := decodeJSONRequest(r, &req); err != nil {
+			return nil, err
+		}
+		return req.endpointRequest(), nil
+	}
+
 	req := accountCreateRequest{}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		return nil, errors.Wrap(err, "error on decode request")
+	if err := decodeJSONRequest(r, &req); err != nil {
+		return nil, err
 	}
 	return req, nil
 }
 
 func encodeAccountCreateResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	if err, ok := response.(error); ok {
-		encodeAccountError(ctx, err, w)
+		encodeError(ctx, err, w)
 		return nil
 	}
 	resp := response.(*account.Account)
-	return json.NewEncoder(w).Encode(schemaResponse{
-		Result: resp,
-	})
+	return encodeResult(ctx, w, resp)
 }
 
 func listAccount(service AccountService) endpoint.Endpoint {
-	return func(_ context.Context, _ interface{}) (response interface{}, err error) {
-		return service.List()
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		filter, _ := request.(metadata.Filter)
+		return service.List(ctx, filter)
 	}
 }
 
-func decodeListAccountsRequest(_ context.Context, _ *http.Request) (interface{}, error) {
-	return nil, nil
+func decodeListAccountsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return decodeMetadataFilter(r)
 }
 
 func encodeListAccountResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	if err, ok := response.(error); ok {
-		encodeAccountError(ctx, err, w)
+		encodeError(ctx, err, w)
 		return nil
 	}
 	resp := response.([]*account.Account)
-	return json.NewEncoder(w).Encode(schemaResponse{
-		Result: resp,
-	})
+	return encodeResult(ctx, w, resp)
+}
+
+// maxImportBodySize limit of size of imported CSV in bytes
+const maxImportBodySize = 32 << 20
+
+type importAccountsRequest struct {
+	Body    io.Reader
+	Options importer.Options
 }
 
-func encodeAccountError(_ context.Context, err error, w http.ResponseWriter) {
-	switch err {
-	case account.ErrorUnsupportedCurrency:
-	case account.ErrorBalanceValue:
-		w.WriteHeader(http.StatusBadRequest)
-	default:
-		w.WriteHeader(http.StatusInternalServerError)
+func importAccounts(service ImportService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(importAccountsRequest)
+		report, err := service.Import(ctx, req.Body, req.Options)
+		if _, ok := errors.Cause(err).(*http.MaxBytesError); ok {
+			return nil, ErrorRequestTooLarge
+		}
+		return report, err
 	}
+}
+
+// decodeImportAccountsRequest decode mode of import, body is CSV read by import
+func decodeImportAccountsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := importAccountsRequest{Body: http.MaxBytesReader(nil, r.Body, maxImportBodySize)}
+	query := r.URL.Query()
+
+	var err error
+	if value := query.Get("dry_run"); value != "" {
+		if req.Options.DryRun, err = strconv.ParseBool(value); err != nil {
+			return nil, errors.Wrap(ErrorBadQuery, "dry_run: "+err.Error())
+		}
+	}
+	if value := query.Get("chunk_size"); value != "" {
+		if req.Options.ChunkSize, err = strconv.Atoi(value); err != nil || req.Options.ChunkSize < 0 {
+			return nil, errors.Wrap(ErrorBadQuery, "chunk_size must be non-negative integer")
+		}
+	}
+	return req, nil
+}
+
+type accountStatementRequest struct {
+	ID     string `json:"id" validate:"uuid"`
+	From   time.Time
+	To     time.Time
+	Format string
+}
 
-	json.NewEncoder(w).Encode(schemaResponse{ // nolint: errcheck
-		Error: err.Error(),
-	})
+func accountStatement(service StatementService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(accountStatementRequest)
+		return service.Prepare(ctx, req.ID, req.From, req.To, req.Format)
+	}
+}
+
+// decodeAccountStatementRequest decode period of statement, bounds are RFC 3339 times or dates,
+// period is month to date unless set
+func decodeAccountStatementRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := accountStatementRequest{ID: chi.URLParam(r, "id"), Format: r.URL.Query().Get("format")}
+	if err := validation.Validate(req); err != nil {
+		return nil, err
+	}
+
+	var err error
+	req.To = time.Now().UTC()
+	if value := r.URL.Query().Get("to"); value != "" {
+		if req.To, err = statement.ParseTime(value); err != nil {
+			return nil, errors.Wrap(ErrorBadQuery, "to: "+err.Error())
+		}
+	}
+	req.From = statement.StartOfMonth(req.To)
+	if value := r.URL.Query().Get("from"); value != "" {
+		if req.From, err = statement.ParseTime(value); err != nil {
+			return nil, errors.Wrap(ErrorBadQuery, "from: "+err.Error())
+		}
+	}
+	return req, nil
+}
+
+// encodeAccountStatementResponse stream statement, failure after statement is started cannot be
+// reported by status, so it is logged and statement is left without closing balance
+func encodeAccountStatementResponse(service StatementService, logger kitlog.Logger) kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		st := response.(*statement.Statement)
+		w.Header().Set("Content-Type", statement.ContentTypes[st.Format])
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="statement-%s-%s-%s.%s"`,
+			st.Account.ID, st.From.Format("20060102"), st.To.Format("20060102"), st.Format))
+		if err := service.Write(ctx, st, w); err != nil {
+			logger.Log("endpoint", "account_statement", "err", err) // nolint: errcheck
+		}
+		return nil
+	}
+}
+
+type accountBalanceRequest struct {
+	ID   string `json:"id" validate:"uuid"`
+	AsOf time.Time
+}
+
+func accountBalance(service StatementService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(accountBalanceRequest)
+		return service.Balance(ctx, req.ID, req.AsOf)
+	}
+}
+
+func accountBalances(service StatementService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return service.Balances(ctx, request.(accountBalanceRequest).AsOf)
+	}
+}
+
+// decodeAccountBalanceRequest decode time of balance from as_of, RFC 3339 time or date meaning
+// end of day, current balance is asked unless set
+func decodeAccountBalanceRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := accountBalanceRequest{ID: chi.URLParam(r, "id"), AsOf: time.Now().UTC()}
+	if err := validation.Validate(req); err != nil {
+		return nil, err
+	}
+
+	if value := r.URL.Query().Get("as_of"); value != "" {
+		var err error
+		if req.AsOf, err = statement.ParseAsOf(value); err != nil {
+			return nil, errors.Wrap(ErrorBadQuery, "as_of: "+err.Error())
+		}
+	}
+	return req, nil
 }
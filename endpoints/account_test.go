@@ -2,32 +2,74 @@ package endpoints
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-kit/kit/log"
 
 	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/importer"
+	"github.com/sbutakov/wallet/pkg/metadata"
+	"github.com/sbutakov/wallet/pkg/payment"
+	"github.com/sbutakov/wallet/pkg/statement"
 )
 
 type dummyStorage struct {
 }
 
-func (d *dummyStorage) CreateAccount(name, currency string, balance float64) (*account.Account, error) {
-	return &account.Account{}, nil
+func (d *dummyStorage) CreateAccount(_ context.Context, _, name, currency string, balance float64,
+	externalID string, meta metadata.Metadata) (*account.Account, error) {
+
+	return &account.Account{ExternalID: externalID, Metadata: meta}, nil
+}
+
+func (d *dummyStorage) AssertAccount(_ context.Context, id string) (*account.Account, error) {
+	return &account.Account{ID: id, Currency: "usd"}, nil
+}
+
+func (d *dummyStorage) ListAccount(context.Context, metadata.Filter) ([]*account.Account, error) {
+	return nil, nil
+}
+
+func (d *dummyStorage) ListCustomerAccounts(context.Context, string, metadata.Filter) ([]*account.Account, error) {
+	return nil, nil
+}
+
+func (d *dummyStorage) StatementPayments(
+	_ context.Context, _ string, from, _ time.Time, sink statement.Sink) error {
+
+	if err := sink.Opening(100); err != nil {
+		return err
+	}
+	return sink.Payment(&payment.Payment{ID: "1", Amount: 30.5, Direction: "outgoing", CreatedAt: from})
 }
 
-func (d *dummyStorage) AssertAccount(id string) (*account.Account, error) {
-	return &account.Account{}, nil
+func (d *dummyStorage) BalanceAt(context.Context, string, time.Time) (float64, error) {
+	return 10.005, nil
 }
 
-func (d *dummyStorage) ListAccount() ([]*account.Account, error) {
+func (d *dummyStorage) BalancesAt(context.Context, string, time.Time) ([]*statement.Balance, error) {
+	return []*statement.Balance{{AccountID: dummyUUID, Currency: "usd", Balance: 1.5}}, nil
+}
+
+func (d *dummyStorage) ExistingExternalReferences(context.Context, []string) ([]string, error) {
 	return nil, nil
 }
 
+func (d *dummyStorage) ImportAccounts(_ context.Context, rows []*importer.Row) ([]*account.Account, error) {
+	accounts := make([]*account.Account, len(rows))
+	for i := range rows {
+		accounts[i] = &account.Account{ID: dummyUUID}
+	}
+	return accounts, nil
+}
+
 func TestMakeAccountEndpoints(t *testing.T) {
 	var logger log.Logger
 	logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
@@ -35,7 +77,7 @@ func TestMakeAccountEndpoints(t *testing.T) {
 
 	storage := &dummyStorage{}
 	service, err := account.New(account.Config{AllowedCurrency: []string{"usd"}}, storage)
-	server := httptest.NewServer(MakeAccountEndpoints(service, logger))
+	server := httptest.NewServer(MakeAccountEndpoints(service, statement.New(&dummyStorage{}), nil, logger))
 
 	body, err := json.Marshal(accountCreateRequest{
 		Name:     "dummy",
@@ -64,3 +106,87 @@ func TestMakeAccountEndpoints(t *testing.T) {
 		t.Error("unexpected nil no result")
 	}
 }
+
+func TestAccountStatement(t *testing.T) {
+	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	accounts, err := account.New(account.Config{AllowedCurrency: []string{"usd"}}, &dummyStorage{})
+	if err != nil {
+		t.Fatal("unexpected error on init account service")
+	}
+	server := httptest.NewServer(MakeAccountEndpoints(accounts, statement.New(&dummyStorage{}), nil, logger))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/" + dummyUUID + "/statement?from=2026-09-01&to=2026-10-01")
+	if err != nil {
+		t.Fatal("unexpected error on request")
+	}
+	defer response.Body.Close()
+
+	body := new(bytes.Buffer)
+	body.ReadFrom(response.Body) // nolint: errcheck
+	lines := strings.Split(strings.TrimSpace(body.String()), "\n")
+	if response.Header.Get("Content-Type") != "text/csv; charset=utf-8" || len(lines) != 4 {
+		t.Fatalf("unexpected statement %q", body.String())
+	}
+	if !strings.HasPrefix(lines[1], "opening_balance,2026-09-01T00:00:00Z") ||
+		!strings.Contains(lines[2], ",-30.50,69.50,") || !strings.HasPrefix(lines[3], "closing_balance") {
+		t.Errorf("unexpected rows of statement %q", lines)
+	}
+
+	for _, query := range []string{"format=pdf", "from=2026-10-01&to=2026-09-01", "from=yesterday"} {
+		response, err = http.Get(server.URL + "/" + dummyUUID + "/statement?" + query)
+		if err != nil {
+			t.Fatal("unexpected error on request")
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected statement %s rejected, got status %d", query, response.StatusCode)
+		}
+	}
+}
+
+func TestAccountBalance(t *testing.T) {
+	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	statements := statement.New(&dummyStorage{})
+	server := httptest.NewServer(MakeAccountEndpoints(nil, statements, nil, logger, WithVersion(V2)))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/" + dummyUUID + "/balance?as_of=2026-06-30")
+	if err != nil {
+		t.Fatal("unexpected error on request")
+	}
+	defer response.Body.Close()
+
+	balance := map[string]interface{}{}
+	if err = json.NewDecoder(response.Body).Decode(&schemaResponseV2{Data: &balance}); err != nil {
+		t.Fatal("error on decode response")
+	}
+	if balance["balance"] != "10.01" || balance["as_of"] != "2026-07-01T00:00:00Z" {
+		t.Errorf("unexpected balance %v", balance)
+	}
+
+	response, err = http.Get(server.URL + "/balances")
+	if err != nil {
+		t.Fatal("unexpected error on request")
+	}
+	defer response.Body.Close()
+
+	var balances []map[string]interface{}
+	if err = json.NewDecoder(response.Body).Decode(&schemaResponseV2{Data: &balances}); err != nil {
+		t.Fatal("error on decode response")
+	}
+	if len(balances) != 1 || balances[0]["account_id"] != dummyUUID || balances[0]["balance"] != "1.50" {
+		t.Errorf("unexpected balances %v", balances)
+	}
+
+	for _, path := range []string{"/dummy/balance", "/balances?as_of=june", "/balances?as_of=2999-01-01"} {
+		response, err = http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal("unexpected error on request")
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected balance %s rejected, got status %d", path, response.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-kit/kit/endpoint"
+	kitlog "github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/sbutakov/wallet/pkg/alias"
+	"github.com/sbutakov/wallet/pkg/validation"
+)
+
+type aliasCreateRequest struct {
+	Value     string `json:"value" validate:"required,max=254"`
+	AccountID string `json:"account_id" validate:"required,uuid"`
+}
+
+type aliasRequest struct {
+	ID string `json:"id" validate:"uuid"`
+}
+
+type aliasLinkRequest struct {
+	ID        string `json:"-" validate:"uuid"`
+	AccountID string `json:"account_id" validate:"required,uuid"`
+	Default   bool   `json:"default"`
+}
+
+// AliasService interface for managing aliases of accounts
+type AliasService interface {
+	Create(ctx context.Context, value, accountID string) (*alias.Alias, error)
+	Get(ctx context.Context, id string) (*alias.Alias, error)
+	List(ctx context.Context) ([]*alias.Alias, error)
+	Delete(ctx context.Context, id string) error
+	Link(ctx context.Context, id, accountID string, isDefault bool) (*alias.Alias, error)
+	Unlink(ctx context.Context, id, accountID string) (*alias.Alias, error)
+	Verify(ctx context.Context, id string) (*alias.Alias, error)
+}
+
+// MakeAliasEndpoints init router for handling create, view, verify and delete aliases
+// and link accounts to them
+func MakeAliasEndpoints(service AliasService, logger kitlog.Logger, opts ...Option) http.Handler {
+	o := newOptions(opts)
+	router := chi.NewRouter()
+	router.Method(http.MethodPost, "/", kithttp.NewServer(
+		o.endpoint("create_alias", createAlias(service)),
+		decodeAliasCreateRequest,
+		encodeAliasResponse,
+		o.server("create_alias",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodGet, "/", kithttp.NewServer(
+		o.endpoint("list_aliases", listAliases(service)),
+		kithttp.NopRequestDecoder,
+		encodeAliasResponse,
+		o.server("list_aliases",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodGet, "/{id}", kithttp.NewServer(
+		o.endpoint("get_alias", getAlias(service)),
+		decodeAliasRequest,
+		encodeAliasResponse,
+		o.server("get_alias",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodDelete, "/{id}", kithttp.NewServer(
+		o.endpoint("delete_alias", deleteAlias(service)),
+		decodeAliasRequest,
+		encodeAliasResponse,
+		o.server("delete_alias",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodPost, "/{id}/accounts", kithttp.NewServer(
+		o.endpoint("link_alias_account", linkAliasAccount(service)),
+		decodeAliasLinkRequest,
+		encodeAliasResponse,
+		o.server("link_alias_account",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodDelete, "/{id}/accounts/{account_id}", kithttp.NewServer(
+		o.endpoint("unlink_alias_account", unlinkAliasAccount(service)),
+		decodeAliasUnlinkRequest,
+		encodeAliasResponse,
+		o.server("unlink_alias_account",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodPost, "/{id}/verify", kithttp.NewServer(
+		o.endpoint("verify_alias", verifyAlias(service)),
+		decodeAliasRequest,
+		encodeAliasResponse,
+		o.server("verify_alias",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	return router
+}
+
+func createAlias(service AliasService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(aliasCreateRequest)
+		return service.Create(ctx, req.Value, req.AccountID)
+	}
+}
+
+func listAliases(service AliasService) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return service.List(ctx)
+	}
+}
+
+func getAlias(service AliasService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return service.Get(ctx, request.(aliasRequest).ID)
+	}
+}
+
+func deleteAlias(service AliasService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(aliasRequest)
+		if err := service.Delete(ctx, req.ID); err != nil {
+			return nil, err
+		}
+		return req.ID, nil
+	}
+}
+
+func linkAliasAccount(service AliasService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(aliasLinkRequest)
+		return service.Link(ctx, req.ID, req.AccountID, req.Default)
+	}
+}
+
+func unlinkAliasAccount(service AliasService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(aliasLinkRequest)
+		return service.Unlink(ctx, req.ID, req.AccountID)
+	}
+}
+
+func verifyAlias(service AliasService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return service.Verify(ctx, request.(aliasRequest).ID)
+	}
+}
+
+func decodeAliasCreateRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := aliasCreateRequest{}
+	if err := decodeJSONRequest(r, &req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeAliasRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := aliasRequest{ID: chi.URLParam(r, "id")}
+	if err := validation.Validate(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeAliasLinkRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := aliasLinkRequest{}
+	if err := decodeJSONRequest(r, &req); err != nil {
+		return nil, err
+	}
+	req.ID = chi.URLParam(r, "id")
+	if err := validation.Validate(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeAliasUnlinkRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := aliasLinkRequest{ID: chi.URLParam(r, "id"), AccountID: chi.URLParam(r, "account_id")}
+	if err := validation.Validate(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func encodeAliasResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return encodeResult(ctx, w, response)
+}
@@ -0,0 +1,109 @@
+package endpoints
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/alias"
+)
+
+func (d *dummyStorage) FindAlias(_ context.Context, value string) (*alias.Alias, error) {
+	return nil, alias.ErrorNotFound
+}
+
+func (d *dummyStorage) CreateAlias(_ context.Context, a *alias.Alias, acc *account.Account) (*alias.Alias, error) {
+	a.ID = dummyUUID
+	a.Accounts = []*alias.LinkedAccount{{AccountID: acc.ID, Currency: acc.Currency, Default: true}}
+	a.CreatedAt = time.Now()
+	return a, nil
+}
+
+func (d *dummyStorage) GetAlias(_ context.Context, id string) (*alias.Alias, error) {
+	return &alias.Alias{ID: id, Kind: alias.KindPhone, Value: "+12025550123", Accounts: []*alias.LinkedAccount{}}, nil
+}
+
+func (d *dummyStorage) ListAliases(context.Context, string) ([]*alias.Alias, error) {
+	return []*alias.Alias{{ID: dummyUUID, Kind: alias.KindHandle, Value: "alice", Verified: true,
+		Accounts: []*alias.LinkedAccount{}}}, nil
+}
+
+func (d *dummyStorage) DeleteAlias(context.Context, string) error {
+	return nil
+}
+
+func (d *dummyStorage) LinkAliasAccount(
+	_ context.Context, id string, acc *account.Account, isDefault bool) (*alias.Alias, error) {
+
+	return &alias.Alias{ID: id, Accounts: []*alias.LinkedAccount{
+		{AccountID: acc.ID, Currency: acc.Currency, Default: isDefault}}}, nil
+}
+
+func (d *dummyStorage) UnlinkAliasAccount(_ context.Context, id, _ string) (*alias.Alias, error) {
+	return &alias.Alias{ID: id, Accounts: []*alias.LinkedAccount{}}, nil
+}
+
+func (d *dummyStorage) VerifyAlias(_ context.Context, id string) (*alias.Alias, error) {
+	now := time.Now()
+	return &alias.Alias{ID: id, Verified: true, VerifiedAt: &now, Accounts: []*alias.LinkedAccount{}}, nil
+}
+
+func TestMakeAliasEndpoints(t *testing.T) {
+	server := httptest.NewServer(MakeAliasEndpoints(alias.New(&dummyStorage{}), log.NewNopLogger()))
+	defer server.Close()
+
+	do := func(method, path, body string, result interface{}) int {
+		request, err := http.NewRequest(method, server.URL+path, bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatal("unexpected error on make request")
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal("unexpected error on request")
+		}
+		defer response.Body.Close()
+		if result != nil && response.StatusCode == http.StatusOK {
+			if err = json.NewDecoder(response.Body).Decode(&schemaResponse{Result: result}); err != nil {
+				t.Fatal("error on decode response")
+			}
+		}
+		return response.StatusCode
+	}
+
+	created := &alias.Alias{}
+	body := `{"value":"@Alice","account_id":"` + dummyUUID + `"}`
+	if do(http.MethodPost, "/", body, created) != http.StatusOK ||
+		created.Kind != alias.KindHandle || created.Value != "alice" || !created.Verified {
+		t.Errorf("unexpected created alias %v", created)
+	}
+	if code := do(http.MethodPost, "/", `{"value":"a","account_id":"`+dummyUUID+`"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("expected invalid alias rejected, got %d", code)
+	}
+
+	var aliases []*alias.Alias
+	if do(http.MethodGet, "/", "", &aliases) != http.StatusOK || len(aliases) != 1 {
+		t.Errorf("unexpected aliases %v", aliases)
+	}
+
+	linked := &alias.Alias{}
+	body = `{"account_id":"` + dummyUUID + `","default":true}`
+	if do(http.MethodPost, "/"+dummyUUID+"/accounts", body, linked) != http.StatusOK ||
+		len(linked.Accounts) != 1 || !linked.Accounts[0].Default {
+		t.Errorf("unexpected linked alias %v", linked)
+	}
+	if code := do(http.MethodDelete, "/dummy/accounts/"+dummyUUID, "", nil); code != http.StatusBadRequest {
+		t.Errorf("expected invalid identifier rejected, got %d", code)
+	}
+
+	verified := &alias.Alias{}
+	if do(http.MethodPost, "/"+dummyUUID+"/verify", "", verified) != http.StatusOK || !verified.Verified {
+		t.Errorf("unexpected verified alias %v", verified)
+	}
+}
@@ -0,0 +1,122 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-kit/kit/endpoint"
+	kitlog "github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/validation"
+)
+
+type apiKeyCreateRequest struct {
+	CustomerID string   `json:"customer_id" validate:"uuid"`
+	Name       string   `json:"name" validate:"required,max=50,name"`
+	Scopes     []string `json:"scopes" validate:"required"`
+}
+
+type apiKeyCreateResponse struct {
+	Key    *auth.APIKey `json:"key"`
+	Secret string       `json:"secret"`
+}
+
+type apiKeyRevokeRequest struct {
+	ID string `json:"id" validate:"uuid"`
+}
+
+// APIKeyService interface for managing api keys
+type APIKeyService interface {
+	CreateKey(
+		ctx context.Context, customerID, name string, scopes []string) (*auth.APIKey, string, error)
+	ListKeys(ctx context.Context) ([]*auth.APIKey, error)
+	RevokeKey(ctx context.Context, id string) error
+}
+
+// MakeAPIKeyEndpoints init router for handling create, view and revoke api keys
+func MakeAPIKeyEndpoints(service APIKeyService, logger kitlog.Logger, opts ...Option) http.Handler {
+	o := newOptions(opts)
+	router := chi.NewRouter()
+	router.Method(http.MethodPost, "/", kithttp.NewServer(
+		o.endpoint("create_api_key", createAPIKey(service)),
+		decodeAPIKeyCreateRequest,
+		encodeAPIKeyResponse,
+		o.server("create_api_key",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodGet, "/", kithttp.NewServer(
+		o.endpoint("list_api_keys", listAPIKeys(service)),
+		decodeListAPIKeysRequest,
+		encodeAPIKeyResponse,
+		o.server("list_api_keys",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodDelete, "/{id}", kithttp.NewServer(
+		o.endpoint("revoke_api_key", revokeAPIKey(service)),
+		decodeAPIKeyRevokeRequest,
+		encodeAPIKeyResponse,
+		o.server("revoke_api_key",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	return router
+}
+
+func createAPIKey(service APIKeyService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(apiKeyCreateRequest)
+		key, secret, err := service.CreateKey(ctx, req.CustomerID, req.Name, req.Scopes)
+		if err != nil {
+			return nil, err
+		}
+		return &apiKeyCreateResponse{Key: key, Secret: secret}, nil
+	}
+}
+
+func listAPIKeys(service APIKeyService) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return service.ListKeys(ctx)
+	}
+}
+
+func revokeAPIKey(service APIKeyService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(apiKeyRevokeRequest)
+		if err := service.RevokeKey(ctx, req.ID); err != nil {
+			return nil, err
+		}
+		return req.ID, nil
+	}
+}
+
+func decodeAPIKeyCreateRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := apiKeyCreateRequest{}
+	if err := decodeJSONRequest(r, &req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeListAPIKeysRequest(_ context.Context, _ *http.Request) (interface{}, error) {
+	return nil, nil
+}
+
+func decodeAPIKeyRevokeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := apiKeyRevokeRequest{ID: chi.URLParam(r, "id")}
+	if err := validation.Validate(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func encodeAPIKeyResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return encodeResult(ctx, w, response)
+}
@@ -0,0 +1,76 @@
+package endpoints
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/sbutakov/wallet/pkg/auth"
+)
+
+type dummyAPIKeyService struct {
+}
+
+func (d *dummyAPIKeyService) CreateKey(
+	_ context.Context, _, name string, scopes []string) (*auth.APIKey, string, error) {
+
+	return &auth.APIKey{Name: name, Scopes: scopes}, "wk_dummy", nil
+}
+
+func (d *dummyAPIKeyService) ListKeys(context.Context) ([]*auth.APIKey, error) {
+	return nil, nil
+}
+
+func (d *dummyAPIKeyService) RevokeKey(context.Context, string) error {
+	return nil
+}
+
+func TestMakeAPIKeyEndpoints(t *testing.T) {
+	var logger log.Logger
+	logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	logger = log.With(logger, "wallet", log.DefaultTimestampUTC)
+
+	principal := &auth.Principal{}
+	handler := MakeAPIKeyEndpoints(&dummyAPIKeyService{}, logger, WithAuthorization())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	}))
+
+	body, err := json.Marshal(apiKeyCreateRequest{
+		Name:   "dummy",
+		Scopes: []string{auth.ScopeAccountsRead},
+	})
+	if err != nil {
+		t.Fatal("unexpected marshal error")
+	}
+
+	response, err := http.Post(server.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal("unexpected error on request")
+	}
+
+	if response.StatusCode != http.StatusForbidden {
+		t.Error("expected forbidden status without scope")
+	}
+
+	principal.Scopes = []string{auth.ScopeKeysAdmin}
+	response, err = http.Post(server.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal("unexpected error on request")
+	}
+
+	resp := schemaResponse{}
+	if err = json.NewDecoder(response.Body).Decode(&resp); err != nil {
+		t.Fatal("error on decode response")
+	}
+
+	if response.StatusCode != http.StatusOK || resp.Error != nil {
+		t.Error("unexpected error on response")
+	}
+}
@@ -0,0 +1,67 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-kit/kit/endpoint"
+	kitlog "github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/sbutakov/wallet/pkg/audit"
+)
+
+// AuditService interface for viewing audit log
+type AuditService interface {
+	List(ctx context.Context, filter audit.Filter) ([]*audit.Entry, error)
+}
+
+// MakeAuditEndpoints init router for handling view audit log,
+// entries are filtered by entity_type, entity_id and paginated by after and limit
+func MakeAuditEndpoints(service AuditService, logger kitlog.Logger, opts ...Option) http.Handler {
+	o := newOptions(opts)
+	router := chi.NewRouter()
+	router.Method(http.MethodGet, "/", kithttp.NewServer(
+		o.endpoint("list_audit_entries", listAuditEntries(service)),
+		decodeListAuditEntriesRequest,
+		encodeAuditResponse,
+		o.server("list_audit_entries",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	return router
+}
+
+func listAuditEntries(service AuditService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return service.List(ctx, request.(audit.Filter))
+	}
+}
+
+func decodeListAuditEntriesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		EntityType: query.Get("entity_type"),
+		EntityID:   query.Get("entity_id"),
+	}
+
+	var err error
+	if after := query.Get("after"); after != "" {
+		if filter.AfterID, err = strconv.ParseInt(after, 10, 64); err != nil {
+			return nil, ErrorBadQuery
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil {
+			return nil, ErrorBadQuery
+		}
+	}
+	return filter, nil
+}
+
+func encodeAuditResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return encodeResult(ctx, w, response)
+}
@@ -0,0 +1,50 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/sbutakov/wallet/pkg/audit"
+)
+
+type dummyAuditService struct {
+	filter audit.Filter
+}
+
+func (d *dummyAuditService) List(_ context.Context, filter audit.Filter) ([]*audit.Entry, error) {
+	d.filter = filter
+	return nil, nil
+}
+
+func TestMakeAuditEndpoints(t *testing.T) {
+	var logger log.Logger
+	logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	logger = log.With(logger, "wallet", log.DefaultTimestampUTC)
+
+	service := &dummyAuditService{}
+	server := httptest.NewServer(MakeAuditEndpoints(service, logger))
+
+	response, err := http.Get(server.URL + "/?after=abc")
+	if err != nil {
+		t.Fatal("unexpected error on request")
+	}
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Error("expected bad request status on malformed query")
+	}
+
+	response, err = http.Get(server.URL + "/?entity_type=account&entity_id=dummy&after=10&limit=5")
+	if err != nil {
+		t.Fatal("unexpected error on request")
+	}
+
+	expected := audit.Filter{EntityType: "account", EntityID: "dummy", AfterID: 10, Limit: 5}
+	if response.StatusCode != http.StatusOK || service.filter != expected {
+		t.Error("expected filter decoded from query")
+	}
+}
@@ -0,0 +1,74 @@
+package endpoints
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/sbutakov/wallet/pkg/auth"
+)
+
+// endpointScopes scopes required for calling endpoints, endpoint without scope cannot be called
+var endpointScopes = map[string]string{
+	"create_account":          auth.ScopeAccountsWrite,
+	"list_accounts":           auth.ScopeAccountsRead,
+	"account_statement":       auth.ScopePaymentsRead,
+	"import_accounts":         auth.ScopeAccountsWrite,
+	"account_balance":         auth.ScopeAccountsRead,
+	"account_balances":        auth.ScopeAccountsRead,
+	"transfer_money":          auth.ScopePaymentsWrite,
+	"list_payments":           auth.ScopePaymentsRead,
+	"stream_payments":         auth.ScopePaymentsRead,
+	"create_customer":         auth.ScopeCustomersWrite,
+	"list_customers":          auth.ScopeCustomersRead,
+	"get_customer":            auth.ScopeCustomersRead,
+	"list_customer_accounts":  auth.ScopeAccountsRead,
+	"create_api_key":          auth.ScopeKeysAdmin,
+	"list_api_keys":           auth.ScopeKeysAdmin,
+	"revoke_api_key":          auth.ScopeKeysAdmin,
+	"list_audit_entries":      auth.ScopeAuditRead,
+	"report_balances":         auth.ScopeReportsRead,
+	"report_daily_volume":     auth.ScopeReportsRead,
+	"report_top_accounts":     auth.ScopeReportsRead,
+	"close_period":            auth.ScopePeriodsAdmin,
+	"list_period_closings":    auth.ScopeReportsRead,
+	"list_projections":        auth.ScopeReportsRead,
+	"rebuild_projection":      auth.ScopeProjectionsAdmin,
+	"list_account_summaries":  auth.ScopeAccountsRead,
+	"create_alias":            auth.ScopeAliasesWrite,
+	"list_aliases":            auth.ScopeAliasesRead,
+	"get_alias":               auth.ScopeAliasesRead,
+	"delete_alias":            auth.ScopeAliasesWrite,
+	"link_alias_account":      auth.ScopeAliasesWrite,
+	"unlink_alias_account":    auth.ScopeAliasesWrite,
+	"verify_alias":            auth.ScopeAliasesAdmin,
+	"create_payment_request":  auth.ScopePaymentRequestsWrite,
+	"list_payment_requests":   auth.ScopePaymentRequestsRead,
+	"get_payment_request":     auth.ScopePaymentRequestsRead,
+	"accept_payment_request":  auth.ScopePaymentsWrite,
+	"decline_payment_request": auth.ScopePaymentRequestsWrite,
+	"cancel_payment_request":  auth.ScopePaymentRequestsWrite,
+}
+
+// WithAuthorization reject calls of clients not granted scope required by endpoint,
+// principal must be stored in context by auth.Middleware
+func WithAuthorization() Option {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, authorizationMiddleware)
+	}
+}
+
+func authorizationMiddleware(name string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			scope, ok := endpointScopes[name]
+			if !ok {
+				return nil, auth.ErrorForbidden
+			}
+			if err := auth.Authorize(ctx, scope); err != nil {
+				return nil, err
+			}
+			return next(ctx, request)
+		}
+	}
+}
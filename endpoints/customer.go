@@ -0,0 +1,126 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-kit/kit/endpoint"
+	kitlog "github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/sbutakov/wallet/pkg/customer"
+	"github.com/sbutakov/wallet/pkg/validation"
+)
+
+type customerCreateRequest struct {
+	Name  string `json:"name" validate:"required,max=50,name"`
+	Email string `json:"email" validate:"max=254,email"`
+}
+
+type customerRequest struct {
+	ID string `json:"id" validate:"uuid"`
+}
+
+// CustomerService interface for creating and viewing customers
+type CustomerService interface {
+	Create(ctx context.Context, name, email string) (*customer.Customer, error)
+	List(ctx context.Context) ([]*customer.Customer, error)
+	Get(ctx context.Context, id string) (*customer.Customer, error)
+}
+
+// MakeCustomerEndpoints init router for handling create and view customers and their accounts
+func MakeCustomerEndpoints(
+	customers CustomerService, accounts AccountService, logger kitlog.Logger, opts ...Option) http.Handler {
+
+	o := newOptions(opts)
+	router := chi.NewRouter()
+	router.Method(http.MethodPost, "/", kithttp.NewServer(
+		o.endpoint("create_customer", createCustomer(customers)),
+		decodeCustomerCreateRequest,
+		encodeCustomerResponse,
+		o.server("create_customer",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodGet, "/", kithttp.NewServer(
+		o.endpoint("list_customers", listCustomers(customers)),
+		decodeListCustomersRequest,
+		encodeCustomerResponse,
+		o.server("list_customers",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodGet, "/{id}", kithttp.NewServer(
+		o.endpoint("get_customer", getCustomer(customers)),
+		decodeCustomerRequest,
+		encodeCustomerResponse,
+		o.server("get_customer",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodGet, "/{id}/accounts", kithttp.NewServer(
+		o.endpoint("list_customer_accounts", listCustomerAccounts(accounts)),
+		decodeCustomerRequest,
+		encodeCustomerResponse,
+		o.server("list_customer_accounts",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	return router
+}
+
+func createCustomer(service CustomerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(customerCreateRequest)
+		return service.Create(ctx, req.Name, req.Email)
+	}
+}
+
+func listCustomers(service CustomerService) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return service.List(ctx)
+	}
+}
+
+func getCustomer(service CustomerService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(customerRequest)
+		return service.Get(ctx, req.ID)
+	}
+}
+
+func listCustomerAccounts(service AccountService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(customerRequest)
+		return service.ListByCustomer(ctx, req.ID)
+	}
+}
+
+func decodeCustomerCreateRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := customerCreateRequest{}
+	if err := decodeJSONRequest(r, &req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeListCustomersRequest(_ context.Context, _ *http.Request) (interface{}, error) {
+	return nil, nil
+}
+
+func decodeCustomerRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := customerRequest{ID: chi.URLParam(r, "id")}
+	if err := validation.Validate(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func encodeCustomerResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return encodeResult(ctx, w, response)
+}
@@ -1,6 +1,57 @@
 package endpoints
 
+import (
+	"github.com/go-kit/kit/endpoint"
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	kithttp "github.com/go-kit/kit/transport/http"
+)
+
+// contentTypeJSON content type of responses with result
+const contentTypeJSON = "application/json; charset=utf-8"
+
 type schemaResponse struct {
 	Result interface{} `json:"result"`
 	Error  interface{} `json:"error"`
 }
+
+// Option configures endpoints made by Make*Endpoints functions
+type Option func(*options)
+
+type options struct {
+	middlewares   []func(name string) endpoint.Middleware
+	serverOptions []func(name string) kithttp.ServerOption
+
+	grpcServerOptions []func(name string) kitgrpc.ServerOption
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// endpoint wrap endpoint with middlewares, first added middleware is outermost
+func (o *options) endpoint(name string, e endpoint.Endpoint) endpoint.Endpoint {
+	for i := len(o.middlewares) - 1; i >= 0; i-- {
+		e = o.middlewares[i](name)(e)
+	}
+	return e
+}
+
+// server append server options of endpoint to base options
+func (o *options) server(name string, base ...kithttp.ServerOption) []kithttp.ServerOption {
+	for _, opt := range o.serverOptions {
+		base = append(base, opt(name))
+	}
+	return base
+}
+
+// grpcServer append gRPC server options of endpoint to base options
+func (o *options) grpcServer(name string, base ...kitgrpc.ServerOption) []kitgrpc.ServerOption {
+	for _, opt := range o.grpcServerOptions {
+		base = append(base, opt(name))
+	}
+	return base
+}
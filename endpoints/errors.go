@@ -0,0 +1,36 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/logging"
+	"github.com/sbutakov/wallet/pkg/ratelimit"
+)
+
+var (
+	// ErrorMalformedRequest request body cannot be decoded
+	ErrorMalformedRequest = apperror.New(apperror.KindInvalidArgument, "malformed_request", "malformed request")
+	// ErrorBadQuery query params are malformed
+	ErrorBadQuery = apperror.New(apperror.KindInvalidArgument, "bad_query", "bad query params")
+)
+
+// encodeError write error as RFC 7807 problem details, status is chosen by kind of error
+// caused err, so errors wrapped by services are mapped same as original ones
+func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
+	if limited, ok := err.(*ratelimit.LimitError); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(limited.RetryAfterSeconds()))
+	}
+	problem := apperror.NewProblem(err)
+	problem.RequestID = logging.RequestIDFromContext(ctx)
+	apperror.WriteProblem(w, problem)
+}
+
+// malformedRequest wrap error of decoding request
+func malformedRequest(err error) error {
+	return errors.Wrap(ErrorMalformedRequest, err.Error())
+}
@@ -0,0 +1,69 @@
+package endpoints
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/go-kit/kit/log"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/payment"
+	"github.com/sbutakov/wallet/pkg/statement"
+)
+
+const dummyUUID = "7b6c7d2a-3f0e-4d8e-9a51-1f3c2b4a5d60"
+
+func TestEncodeError(t *testing.T) {
+	var logger log.Logger
+	logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	logger = log.With(logger, "wallet", log.DefaultTimestampUTC)
+
+	accounts, err := account.New(account.Config{AllowedCurrency: []string{"usd"}}, &dummyStorage{})
+	if err != nil {
+		t.Fatal("unexpected error on init account service")
+	}
+
+	router := chi.NewRouter()
+	router.Mount("/accounts", MakeAccountEndpoints(accounts, statement.New(&dummyStorage{}), nil, logger))
+	router.Mount("/payments", MakePaymentEndpoints(payment.New(&dummyStorage{}), logger))
+	server := httptest.NewServer(router)
+
+	cases := []struct {
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"/accounts", `{"name":"dummy","currency":"eur","balance":1}`, http.StatusBadRequest, "unsupported_currency"},
+		{"/accounts", `{"name":"dummy","currency":"usd","balance":0}`, http.StatusBadRequest, "invalid_balance"},
+		{"/accounts", `{"name":`, http.StatusBadRequest, "malformed_request"},
+		{"/payments", `{"account_from":"` + dummyUUID + `","account_to":"` + dummyUUID + `","amount":1}`,
+			http.StatusBadRequest, "transfer_to_self"},
+	}
+
+	for _, c := range cases {
+		response, err := http.Post(server.URL+c.path, "application/json", bytes.NewReader([]byte(c.body)))
+		if err != nil {
+			t.Fatal("unexpected error on request")
+		}
+
+		problem := apperror.Problem{}
+		if err = json.NewDecoder(response.Body).Decode(&problem); err != nil {
+			t.Fatal("error on decode problem")
+		}
+
+		if response.StatusCode != c.status || problem.Status != c.status || problem.Code != c.code {
+			t.Errorf("unexpected problem %+v of request %s", problem, c.body)
+		}
+
+		if response.Header.Get("Content-Type") != apperror.ProblemContentType {
+			t.Error("expected problem content type")
+		}
+	}
+}
@@ -0,0 +1,419 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/go-kit/kit/endpoint"
+	kitlog "github.com/go-kit/kit/log"
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/sbutakov/wallet/api/proto/walletpb"
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/logging"
+	"github.com/sbutakov/wallet/pkg/metadata"
+	"github.com/sbutakov/wallet/pkg/payment"
+	"github.com/sbutakov/wallet/pkg/tracing"
+	"github.com/sbutakov/wallet/pkg/validation"
+)
+
+// GRPCErrorCodeTrailer trailer carries stable code of application error of failed call
+const GRPCErrorCodeTrailer = "wallet-error-code"
+
+// PaymentStreamService interface for transfer, viewing and streaming payments
+type PaymentStreamService interface {
+	PaymentService
+	StreamPayments(ctx context.Context, filter payment.Filter, sink payment.Sink) error
+}
+
+type grpcServer struct {
+	walletpb.UnimplementedAccountsServer
+	walletpb.UnimplementedPaymentsServer
+
+	createAccount  kitgrpc.Handler
+	listAccounts   kitgrpc.Handler
+	transferMoney  kitgrpc.Handler
+	listPayments   kitgrpc.Handler
+	streamPayments kitgrpc.Handler
+}
+
+// RegisterGRPCServices register services wallet.v1.Accounts and wallet.v1.Payments of api/proto/wallet.proto
+// in server, calls are handled by same endpoints as http
+func RegisterGRPCServices(server *grpc.Server,
+	accounts AccountService, payments PaymentStreamService, logger kitlog.Logger, opts ...Option) {
+
+	o := newOptions(opts)
+	s := &grpcServer{
+		createAccount: kitgrpc.NewServer(
+			o.endpoint("create_account", createAccount(accounts)),
+			decodeGRPCCreateAccountRequest,
+			encodeGRPCAccount,
+			o.grpcServer("create_account", kitgrpc.ServerErrorLogger(logger))...),
+		listAccounts: kitgrpc.NewServer(
+			o.endpoint("list_accounts", listAccount(accounts)),
+			decodeGRPCListAccountsRequest,
+			encodeGRPCAccounts,
+			o.grpcServer("list_accounts", kitgrpc.ServerErrorLogger(logger))...),
+		transferMoney: kitgrpc.NewServer(
+			o.endpoint("transfer_money", transferMoney(payments)),
+			decodeGRPCTransferMoneyRequest,
+			encodeGRPCPayment,
+			o.grpcServer("transfer_money", kitgrpc.ServerErrorLogger(logger))...),
+		listPayments: kitgrpc.NewServer(
+			o.endpoint("list_payments", listPayments(payments)),
+			decodeGRPCListPaymentsRequest,
+			encodeGRPCPayments,
+			o.grpcServer("list_payments", kitgrpc.ServerErrorLogger(logger))...),
+		streamPayments: kitgrpc.NewServer(
+			o.endpoint("stream_payments", streamPayments(payments)),
+			decodeGRPCStreamPaymentsRequest,
+			encodeGRPCStreamPaymentsResponse,
+			o.grpcServer("stream_payments", kitgrpc.ServerErrorLogger(logger))...),
+	}
+	walletpb.RegisterAccountsServer(server, s)
+	walletpb.RegisterPaymentsServer(server, s)
+}
+
+func (s *grpcServer) CreateAccount(
+	ctx context.Context, req *walletpb.CreateAccountRequest) (*walletpb.Account, error) {
+
+	_, res, err := s.createAccount.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return res.(*walletpb.Account), nil
+}
+
+func (s *grpcServer) ListAccounts(
+	ctx context.Context, req *walletpb.ListAccountsRequest) (*walletpb.ListAccountsResponse, error) {
+
+	_, res, err := s.listAccounts.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return res.(*walletpb.ListAccountsResponse), nil
+}
+
+func (s *grpcServer) TransferMoney(
+	ctx context.Context, req *walletpb.TransferMoneyRequest) (*walletpb.Payment, error) {
+
+	_, res, err := s.transferMoney.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return res.(*walletpb.Payment), nil
+}
+
+func (s *grpcServer) ListPayments(
+	ctx context.Context, req *walletpb.ListPaymentsRequest) (*walletpb.ListPaymentsResponse, error) {
+
+	_, res, err := s.listPayments.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return res.(*walletpb.ListPaymentsResponse), nil
+}
+
+// StreamPayments send every payment to stream as soon as it is read from storage
+func (s *grpcServer) StreamPayments(
+	req *walletpb.ListPaymentsRequest, stream walletpb.Payments_StreamPaymentsServer) error {
+
+	ctx := stream.Context()
+	if _, _, err := s.streamPayments.ServeGRPC(ctx, grpcStreamPaymentsRequest{req, stream}); err != nil {
+		return grpcError(ctx, err)
+	}
+	return nil
+}
+
+// grpcStreamPaymentsRequest request message of stream call with stream sending payments
+type grpcStreamPaymentsRequest struct {
+	request *walletpb.ListPaymentsRequest
+	stream  walletpb.Payments_StreamPaymentsServer
+}
+
+type streamPaymentsRequest struct {
+	filter payment.Filter
+	sink   payment.Sink
+}
+
+func streamPayments(service PaymentStreamService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(streamPaymentsRequest)
+		return nil, service.StreamPayments(ctx, req.filter, req.sink)
+	}
+}
+
+var grpcKindCodes = map[apperror.Kind]codes.Code{
+	apperror.KindInternal:           codes.Internal,
+	apperror.KindInvalidArgument:    codes.InvalidArgument,
+	apperror.KindNotFound:           codes.NotFound,
+	apperror.KindFailedPrecondition: codes.FailedPrecondition,
+	apperror.KindUnauthenticated:    codes.Unauthenticated,
+	apperror.KindPermissionDenied:   codes.PermissionDenied,
+	apperror.KindConflict:           codes.AlreadyExists,
+	apperror.KindTooLarge:           codes.ResourceExhausted,
+	apperror.KindRateLimited:        codes.ResourceExhausted,
+}
+
+// grpcError make status of failed call, application errors are mapped by their kind and
+// their stable code is sent in trailer, details of internal errors are not exposed
+func grpcError(ctx context.Context, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	e := apperror.From(err)
+	message := e.Message
+	if e.Kind != apperror.KindInternal {
+		message = err.Error()
+	}
+	_ = grpc.SetTrailer(ctx, grpcmetadata.Pairs(GRPCErrorCodeTrailer, e.Code))
+	return status.Error(grpcKindCodes[e.Kind], message)
+}
+
+// GRPCInterceptor runs before handler of every unary and stream call, returned context is passed
+// to handler, error rejects call
+type GRPCInterceptor func(ctx context.Context) (context.Context, error)
+
+// GRPCServerOptions chain interceptors of unary and stream calls, first interceptor runs first
+func GRPCServerOptions(interceptors ...GRPCInterceptor) []grpc.ServerOption {
+	intercept := func(ctx context.Context) (context.Context, error) {
+		for _, interceptor := range interceptors {
+			var err error
+			if ctx, err = interceptor(ctx); err != nil {
+				return ctx, grpcError(ctx, err)
+			}
+		}
+		return ctx, nil
+	}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{},
+			_ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+			ctx, err := intercept(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv interface{}, stream grpc.ServerStream,
+			_ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+			ctx, err := intercept(stream.Context())
+			if err != nil {
+				return err
+			}
+			return handler(srv, &grpcServerStream{ServerStream: stream, ctx: ctx})
+		}),
+	}
+}
+
+// grpcServerStream stream of call with context updated by interceptors
+type grpcServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *grpcServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// GRPCRequestID accept request identifier from x-request-id metadata or generate new one,
+// returns it in header and stores logger with request identifier in context of call
+func GRPCRequestID(logger *zerolog.Logger) GRPCInterceptor {
+	return func(ctx context.Context) (context.Context, error) {
+		md, _ := grpcmetadata.FromIncomingContext(ctx)
+		var id string
+		if values := md.Get(logging.RequestIDHeader); len(values) > 0 {
+			id = values[0]
+		}
+		ctx, id = logging.ContextWithRequestID(ctx, logger, id)
+		_ = grpc.SetHeader(ctx, grpcmetadata.Pairs(logging.RequestIDHeader, id))
+		return ctx, nil
+	}
+}
+
+// GRPCAuthentication authenticate calls by first authenticator recognized credentials sent in metadata
+// as in headers of http requests and store principal in context, rejects calls with invalid credentials
+func GRPCAuthentication(authenticators ...auth.Authenticator) GRPCInterceptor {
+	return func(ctx context.Context) (context.Context, error) {
+		md, _ := grpcmetadata.FromIncomingContext(ctx)
+		r := (&http.Request{Header: http.Header{}}).WithContext(ctx)
+		for key, values := range md {
+			for _, value := range values {
+				r.Header.Add(key, value)
+			}
+		}
+
+		for _, authenticator := range authenticators {
+			principal, err := authenticator.Authenticate(ctx, r)
+			if e, ok := errors.Cause(err).(*auth.TokenError); ok {
+				return ctx, errors.Wrap(auth.ErrorInvalidToken, e.Error())
+			}
+			if err != nil {
+				return ctx, err
+			}
+			if principal != nil {
+				return auth.WithPrincipal(ctx, principal), nil
+			}
+		}
+		return ctx, nil
+	}
+}
+
+// grpcTraceParentToContext continue trace of traceparent request metadata
+func grpcTraceParentToContext(ctx context.Context, md grpcmetadata.MD) context.Context {
+	values := md.Get(tracing.TraceParentHeader)
+	if len(values) == 0 {
+		return ctx
+	}
+	sc, err := tracing.ParseTraceParent(values[0])
+	if err != nil {
+		return ctx
+	}
+	return tracing.ContextWithRemoteParent(ctx, sc)
+}
+
+func decodeGRPCCreateAccountRequest(_ context.Context, request interface{}) (interface{}, error) {
+	msg := request.(*walletpb.CreateAccountRequest)
+	req := accountCreateRequest{
+		CustomerID: msg.CustomerId,
+		Name:       msg.Name,
+		Currency:   msg.Currency,
+		Balance:    msg.Balance,
+		ExternalID: msg.ExternalId,
+		Metadata:   msg.Metadata,
+	}
+	if err := validation.Validate(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeGRPCListAccountsRequest(_ context.Context, request interface{}) (interface{}, error) {
+	msg := request.(*walletpb.ListAccountsRequest)
+	req := metadataFilterRequest{ExternalID: msg.ExternalId, Metadata: msg.Metadata}
+	if err := validation.Validate(req); err != nil {
+		return nil, err
+	}
+	return metadata.Filter{ExternalID: req.ExternalID, Metadata: req.Metadata}, nil
+}
+
+func decodeGRPCTransferMoneyRequest(_ context.Context, request interface{}) (interface{}, error) {
+	msg := request.(*walletpb.TransferMoneyRequest)
+	req := transferMoneyRequest{
+		AccountFrom: msg.AccountFrom,
+		AccountTo:   msg.AccountTo,
+		Amount:      msg.Amount,
+		ExternalID:  msg.ExternalId,
+		Metadata:    msg.Metadata,
+		Description: msg.Description,
+		Category:    msg.Category,
+	}
+	if err := validation.Validate(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeGRPCListPaymentsRequest(_ context.Context, request interface{}) (interface{}, error) {
+	msg := request.(*walletpb.ListPaymentsRequest)
+	filter := metadataFilterRequest{ExternalID: msg.ExternalId, Metadata: msg.Metadata}
+	if err := validation.Validate(filter); err != nil {
+		return nil, err
+	}
+	req := paymentFilterRequest{Query: strings.TrimSpace(msg.Q), Category: msg.Category}
+	if err := validation.Validate(req); err != nil {
+		return nil, err
+	}
+	return payment.Filter{
+		Filter:   metadata.Filter{ExternalID: filter.ExternalID, Metadata: filter.Metadata},
+		Query:    req.Query,
+		Category: req.Category,
+	}, nil
+}
+
+func decodeGRPCStreamPaymentsRequest(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(grpcStreamPaymentsRequest)
+	filter, err := decodeGRPCListPaymentsRequest(ctx, req.request)
+	if err != nil {
+		return nil, err
+	}
+	return streamPaymentsRequest{
+		filter: filter.(payment.Filter),
+		sink: func(p *payment.Payment) error {
+			return req.stream.Send(grpcPayment(p))
+		},
+	}, nil
+}
+
+func grpcAccount(acc *account.Account) *walletpb.Account {
+	return &walletpb.Account{
+		Id:         acc.ID,
+		CustomerId: acc.CustomerID,
+		Name:       acc.Name,
+		Currency:   acc.Currency,
+		Balance:    acc.Balance,
+		CreatedAt:  timestamppb.New(acc.CreatedAt),
+		ExternalId: acc.ExternalID,
+		Metadata:   acc.Metadata,
+	}
+}
+
+func grpcPayment(p *payment.Payment) *walletpb.Payment {
+	return &walletpb.Payment{
+		Id:          p.ID,
+		Amount:      p.Amount,
+		Currency:    p.Currency,
+		AccountTo:   p.AccountTo,
+		AccountFrom: p.AccountFrom,
+		Direction:   p.Direction,
+		CreatedAt:   timestamppb.New(p.CreatedAt),
+		ExternalId:  p.ExternalID,
+		Metadata:    p.Metadata,
+		Description: p.Description,
+		Category:    p.Category,
+	}
+}
+
+func encodeGRPCAccount(_ context.Context, response interface{}) (interface{}, error) {
+	return grpcAccount(response.(*account.Account)), nil
+}
+
+func encodeGRPCAccounts(_ context.Context, response interface{}) (interface{}, error) {
+	accounts := response.([]*account.Account)
+	res := &walletpb.ListAccountsResponse{Accounts: make([]*walletpb.Account, len(accounts))}
+	for i, acc := range accounts {
+		res.Accounts[i] = grpcAccount(acc)
+	}
+	return res, nil
+}
+
+func encodeGRPCPayment(_ context.Context, response interface{}) (interface{}, error) {
+	return grpcPayment(response.(*payment.Payment)), nil
+}
+
+func encodeGRPCPayments(_ context.Context, response interface{}) (interface{}, error) {
+	payments := response.([]*payment.Payment)
+	res := &walletpb.ListPaymentsResponse{Payments: make([]*walletpb.Payment, len(payments))}
+	for i, p := range payments {
+		res.Payments[i] = grpcPayment(p)
+	}
+	return res, nil
+}
+
+// encodeGRPCStreamPaymentsResponse payments are already sent to stream
+func encodeGRPCStreamPaymentsResponse(context.Context, interface{}) (interface{}, error) {
+	return nil, nil
+}
@@ -0,0 +1,166 @@
+package endpoints
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/sbutakov/wallet/api/proto/walletpb"
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/logging"
+	"github.com/sbutakov/wallet/pkg/payment"
+)
+
+// dummyPaymentStreamService waits until first payment is received by client before sending others
+type dummyPaymentStreamService struct {
+	*payment.Service
+	received chan struct{}
+}
+
+func (d *dummyPaymentStreamService) StreamPayments(
+	ctx context.Context, filter payment.Filter, sink payment.Sink) error {
+
+	first := true
+	return d.Service.StreamPayments(ctx, filter, func(p *payment.Payment) error {
+		if err := sink(p); err != nil || !first {
+			return err
+		}
+		first = false
+		<-d.received
+		return nil
+	})
+}
+
+// dialGRPC serve server in memory and dial it
+func dialGRPC(t *testing.T, server *grpc.Server) *grpc.ClientConn {
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener) // nolint: errcheck
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal("unexpected error on dial")
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestRegisterGRPCServices(t *testing.T) {
+	var logger log.Logger
+	logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	logger = log.With(logger, "wallet", log.DefaultTimestampUTC)
+
+	accounts, err := account.New(account.Config{AllowedCurrency: []string{"usd"}}, &dummyStorage{})
+	if err != nil {
+		t.Fatal("unexpected error on init account service")
+	}
+	payments := &dummyPaymentStreamService{Service: payment.New(&dummyStorage{}), received: make(chan struct{})}
+
+	zl := zerolog.Nop()
+	server := grpc.NewServer(GRPCServerOptions(GRPCRequestID(&zl))...)
+	RegisterGRPCServices(server, accounts, payments, logger)
+	conn := dialGRPC(t, server)
+	ctx := context.Background()
+
+	var header grpcmetadata.MD
+	_, err = walletpb.NewAccountsClient(conn).CreateAccount(ctx,
+		&walletpb.CreateAccountRequest{Name: "dummy", Currency: "usd", Balance: 100.5}, grpc.Header(&header))
+	if err != nil {
+		t.Errorf("expected created account, got %v", err)
+	}
+	if len(header.Get(logging.RequestIDHeader)) != 1 {
+		t.Error("expected request identifier in header")
+	}
+
+	client := walletpb.NewPaymentsClient(conn)
+	var trailer grpcmetadata.MD
+	_, err = client.TransferMoney(ctx,
+		&walletpb.TransferMoneyRequest{AccountFrom: dummyUUID, AccountTo: dummyUUID, Amount: 1}, grpc.Trailer(&trailer))
+	if code := trailer.Get(GRPCErrorCodeTrailer); status.Code(err) != codes.InvalidArgument ||
+		len(code) != 1 || code[0] != "transfer_to_self" {
+		t.Errorf("expected invalid argument status, got %v", err)
+	}
+
+	trailer = nil
+	_, err = client.TransferMoney(ctx, &walletpb.TransferMoneyRequest{AccountFrom: "dummy"}, grpc.Trailer(&trailer))
+	if code := trailer.Get(GRPCErrorCodeTrailer); len(code) != 1 || code[0] != "validation_failed" {
+		t.Errorf("expected validation of request message, got %v", err)
+	}
+
+	stream, err := client.StreamPayments(ctx, &walletpb.ListPaymentsRequest{})
+	if err != nil {
+		t.Fatal("unexpected error on stream payments")
+	}
+
+	first, err := stream.Recv()
+	if err != nil || first.Id != "first" {
+		t.Fatalf("expected first payment received before others are read, got %v", err)
+	}
+	close(payments.received)
+
+	ids := []string{first.Id}
+	for {
+		p, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error on receive payment: %v", err)
+		}
+		ids = append(ids, p.Id)
+	}
+	if len(ids) != 3 || ids[2] != "third" {
+		t.Errorf("unexpected streamed payments %v", ids)
+	}
+}
+
+func TestGRPCAuthentication(t *testing.T) {
+	accounts, err := account.New(account.Config{AllowedCurrency: []string{"usd"}}, &dummyStorage{})
+	if err != nil {
+		t.Fatal("unexpected error on init account service")
+	}
+
+	server := grpc.NewServer(GRPCServerOptions(GRPCAuthentication(&dummyAuthenticator{}))...)
+	RegisterGRPCServices(server, accounts, payment.New(&dummyStorage{}), log.NewNopLogger(), WithAuthorization())
+	conn := dialGRPC(t, server)
+
+	for _, c := range []struct {
+		key  string
+		code codes.Code
+	}{
+		{"", codes.Unauthenticated},
+		{"wrong", codes.Unauthenticated},
+		{"dummy", codes.OK},
+	} {
+		ctx := grpcmetadata.AppendToOutgoingContext(context.Background(), auth.APIKeyHeader, c.key)
+		_, err := walletpb.NewAccountsClient(conn).ListAccounts(ctx, &walletpb.ListAccountsRequest{})
+		if status.Code(err) != c.code {
+			t.Errorf("expected status %s of call with key %q, got %v", c.code, c.key, err)
+		}
+	}
+
+	ctx := grpcmetadata.AppendToOutgoingContext(context.Background(), auth.APIKeyHeader, "dummy")
+	stream, err := walletpb.NewPaymentsClient(conn).StreamPayments(ctx, &walletpb.ListPaymentsRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected stream of principal without scope rejected, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-kit/kit/endpoint"
+	kitlog "github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/sbutakov/wallet/pkg/health"
+)
+
+// HealthService interface for checking state of service
+type HealthService interface {
+	Liveness() *health.Report
+	Readiness(ctx context.Context) *health.Report
+}
+
+// MakeLivenessEndpoints init router for handling liveness probe
+func MakeLivenessEndpoints(service HealthService, logger kitlog.Logger) http.Handler {
+	router := chi.NewRouter()
+	router.Method(http.MethodGet, "/", kithttp.NewServer(
+		liveness(service), decodeHealthRequest, encodeHealthResponse,
+		[]kithttp.ServerOption{
+			kithttp.ServerErrorLogger(logger),
+		}...))
+
+	return router
+}
+
+// MakeReadinessEndpoints init router for handling readiness probe
+func MakeReadinessEndpoints(service HealthService, logger kitlog.Logger) http.Handler {
+	router := chi.NewRouter()
+	router.Method(http.MethodGet, "/", kithttp.NewServer(
+		readiness(service), decodeHealthRequest, encodeHealthResponse,
+		[]kithttp.ServerOption{
+			kithttp.ServerErrorLogger(logger),
+		}...))
+
+	return router
+}
+
+func liveness(service HealthService) endpoint.Endpoint {
+	return func(_ context.Context, _ interface{}) (interface{}, error) {
+		return service.Liveness(), nil
+	}
+}
+
+func readiness(service HealthService) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return service.Readiness(ctx), nil
+	}
+}
+
+func decodeHealthRequest(_ context.Context, _ *http.Request) (interface{}, error) {
+	return nil, nil
+}
+
+func encodeHealthResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+	resp := response.(*health.Report)
+	w.Header().Set("Content-Type", contentTypeJSON)
+	if !resp.Healthy() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	return json.NewEncoder(w).Encode(schemaResponse{
+		Result: resp,
+	})
+}
@@ -0,0 +1,45 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/sbutakov/wallet/pkg/health"
+)
+
+func TestMakeReadinessEndpoints(t *testing.T) {
+	var logger log.Logger
+	logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	logger = log.With(logger, "wallet", log.DefaultTimestampUTC)
+
+	service := health.New(health.Config{})
+	server := httptest.NewServer(MakeReadinessEndpoints(service, logger))
+
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal("unexpected error on request")
+	}
+
+	if response.StatusCode != http.StatusOK {
+		t.Error("unexpected status code of ready service")
+	}
+
+	service.Register("dummy", health.CheckerFunc(func(context.Context) error {
+		return errors.New("dummy error")
+	}))
+
+	response, err = http.Get(server.URL)
+	if err != nil {
+		t.Fatal("unexpected error on request")
+	}
+
+	if response.StatusCode != http.StatusServiceUnavailable {
+		t.Error("unexpected status code of not ready service")
+	}
+}
@@ -0,0 +1,88 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/sbutakov/wallet/pkg/metrics"
+)
+
+type contextKey int
+
+const (
+	contextKeyRequestStart contextKey = iota
+	contextKeyVersion
+)
+
+// Instrumentation collects metrics of requests handled by endpoints
+type Instrumentation struct {
+	requests         metrics.Counter
+	requestsDuration metrics.Histogram
+	endpointDuration metrics.Histogram
+}
+
+// NewInstrumentation is constructor, registers metrics in registry
+func NewInstrumentation(registry *metrics.Registry) *Instrumentation {
+	return &Instrumentation{
+		requests: registry.NewCounter(
+			"http_requests_total",
+			"Count of handled http requests.",
+			"route", "method", "status"),
+		requestsDuration: registry.NewHistogram(
+			"http_request_duration_seconds",
+			"Duration of handled http requests in seconds.",
+			metrics.DefaultBuckets,
+			"route", "method", "status"),
+		endpointDuration: registry.NewHistogram(
+			"endpoint_duration_seconds",
+			"Duration of endpoint calls in seconds.",
+			metrics.DefaultBuckets,
+			"endpoint", "success"),
+	}
+}
+
+// WithInstrumentation collect metrics of requests and endpoint calls
+func WithInstrumentation(instrumentation *Instrumentation) Option {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, instrumentation.middleware)
+		o.serverOptions = append(o.serverOptions,
+			func(string) kithttp.ServerOption {
+				return kithttp.ServerBefore(instrumentation.before)
+			},
+			func(name string) kithttp.ServerOption {
+				return kithttp.ServerFinalizer(instrumentation.finalizer(name))
+			})
+	}
+}
+
+func (i *Instrumentation) middleware(name string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			defer func(begin time.Time) {
+				i.endpointDuration.
+					With("endpoint", name, "success", strconv.FormatBool(err == nil)).
+					Observe(time.Since(begin).Seconds())
+			}(time.Now())
+			return next(ctx, request)
+		}
+	}
+}
+
+func (i *Instrumentation) before(ctx context.Context, _ *http.Request) context.Context {
+	return context.WithValue(ctx, contextKeyRequestStart, time.Now())
+}
+
+func (i *Instrumentation) finalizer(name string) kithttp.ServerFinalizerFunc {
+	return func(ctx context.Context, code int, r *http.Request) {
+		labels := []string{"route", name, "method", r.Method, "status", strconv.Itoa(code)}
+		i.requests.With(labels...).Add(1)
+		if begin, ok := ctx.Value(contextKeyRequestStart).(time.Time); ok {
+			i.requestsDuration.With(labels...).Observe(time.Since(begin).Seconds())
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package endpoints
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/metadata"
+	"github.com/sbutakov/wallet/pkg/validation"
+)
+
+type metadataFilterRequest struct {
+	ExternalID string            `json:"external_id" validate:"max=64"`
+	Metadata   metadata.Metadata `json:"metadata" validate:"metadata"`
+}
+
+// decodeMetadataFilter decode filter of listed entities from query params external_id
+// and metadata[key]=value, entities match all pairs of metadata
+func decodeMetadataFilter(r *http.Request) (metadata.Filter, error) {
+	query := r.URL.Query()
+	req := metadataFilterRequest{ExternalID: query.Get("external_id")}
+	for param, values := range query {
+		if !strings.HasPrefix(param, "metadata[") || !strings.HasSuffix(param, "]") {
+			continue
+		}
+		if len(values) > 1 {
+			return metadata.Filter{}, errors.Wrap(ErrorBadQuery, param+" is repeated")
+		}
+		if req.Metadata == nil {
+			req.Metadata = metadata.Metadata{}
+		}
+		req.Metadata[param[len("metadata["):len(param)-1]] = values[0]
+	}
+
+	if err := validation.Validate(req); err != nil {
+		return metadata.Filter{}, err
+	}
+	return metadata.Filter{ExternalID: req.ExternalID, Metadata: req.Metadata}, nil
+}
@@ -0,0 +1,33 @@
+package endpoints
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeMetadataFilter(t *testing.T) {
+	r := httptest.NewRequest("GET", "/?external_id=order-1&metadata[source]=shop&metadata[channel]=web&limit=1", nil)
+	filter, err := decodeMetadataFilter(r)
+	if err != nil {
+		t.Fatal("unexpected error on decode filter")
+	}
+	if filter.ExternalID != "order-1" || len(filter.Metadata) != 2 || filter.Metadata["source"] != "shop" {
+		t.Errorf("unexpected filter %+v", filter)
+	}
+
+	filter, err = decodeMetadataFilter(httptest.NewRequest("GET", "/", nil))
+	if err != nil || filter.ExternalID != "" || filter.Metadata != nil {
+		t.Error("expected empty filter without params")
+	}
+
+	for _, query := range []string{
+		"metadata[source]=shop&metadata[source]=web",
+		"metadata[]=shop",
+		"external_id=" + strings.Repeat("a", 65),
+	} {
+		if _, err = decodeMetadataFilter(httptest.NewRequest("GET", "/?"+query, nil)); err == nil {
+			t.Errorf("expected filter %s rejected", query)
+		}
+	}
+}
@@ -0,0 +1,593 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/alias"
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/audit"
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/closing"
+	"github.com/sbutakov/wallet/pkg/customer"
+	"github.com/sbutakov/wallet/pkg/health"
+	"github.com/sbutakov/wallet/pkg/importer"
+	"github.com/sbutakov/wallet/pkg/metadata"
+	"github.com/sbutakov/wallet/pkg/payment"
+	"github.com/sbutakov/wallet/pkg/paymentrequest"
+	"github.com/sbutakov/wallet/pkg/projection"
+	"github.com/sbutakov/wallet/pkg/reporting"
+	"github.com/sbutakov/wallet/pkg/statement"
+)
+
+const openAPIVersion = "3.0.3"
+
+// apiParam path, query or header param of operation
+type apiParam struct {
+	In          string
+	Name        string
+	Type        string
+	Format      string
+	Style       string
+	Description string
+}
+
+// apiOperation route described in OpenAPI document, request and result are zero values
+// of types decoded from body and returned in result of response, statuses of responses
+// with result are 200 unless listed, unversioned routes are not prefixed by version,
+// operations producing documents other than JSON list their content types, operations
+// consuming document other than JSON set its content type
+type apiOperation struct {
+	Name        string
+	Method      string
+	Path        string
+	Summary     string
+	Params      []apiParam
+	Request     interface{}
+	Result      interface{}
+	Statuses    []int
+	Unversioned bool
+	Produces    []string
+	Consumes    string
+}
+
+var idParam = apiParam{In: "path", Name: "id", Type: "string", Format: "uuid"}
+
+// asOfParam time of balance
+var asOfParam = apiParam{In: "query", Name: "as_of", Type: "string",
+	Description: "RFC 3339 time or date meaning end of day, payments created before time are included, default is now"}
+
+// metadataParams filter of listed entities by external identifier and metadata
+var metadataParams = []apiParam{
+	{In: "query", Name: "external_id", Type: "string"},
+	{In: "query", Name: "metadata", Type: "object", Style: "deepObject",
+		Description: "metadata[key]=value, entities containing all pairs are listed"},
+}
+
+// reportPeriodParams period of report, days of UTC
+var reportPeriodParams = []apiParam{
+	{In: "query", Name: "from", Type: "string", Format: "date", Description: "first day, default is 30 days before to"},
+	{In: "query", Name: "to", Type: "string", Format: "date", Description: "day after last day, default is tomorrow"},
+}
+
+// apiOperations routes mounted by MountRoutes and MountAPI, paths are prefixed by mount point of their router
+// and have no trailing slash
+var apiOperations = []apiOperation{
+	{Name: "create_account", Method: http.MethodPost, Path: "/accounts", Summary: "Create account",
+		Request: accountCreateRequest{}, Result: &account.Account{}},
+	{Name: "list_accounts", Method: http.MethodGet, Path: "/accounts", Summary: "List accounts",
+		Params: metadataParams, Result: []*account.Account{}},
+	{Name: "account_statement", Method: http.MethodGet, Path: "/accounts/{id}/statement",
+		Summary: "Export statement of account", Params: []apiParam{idParam,
+			{In: "query", Name: "from", Type: "string",
+				Description: "start of period, RFC 3339 time or date, default is start of month"},
+			{In: "query", Name: "to", Type: "string",
+				Description: "end of period exclusive, RFC 3339 time or date, default is now"},
+			{In: "query", Name: "format", Type: "string", Description: "csv (default), jsonl or txt"},
+		},
+		Produces: []string{
+			statement.ContentTypes[statement.FormatCSV],
+			statement.ContentTypes[statement.FormatJSONLines],
+			statement.ContentTypes[statement.FormatText],
+		}},
+	{Name: "account_balance", Method: http.MethodGet, Path: "/accounts/{id}/balance",
+		Summary: "Balance of account at time", Params: []apiParam{idParam, asOfParam}, Result: &statement.Balance{}},
+	{Name: "account_balances", Method: http.MethodGet, Path: "/accounts/balances",
+		Summary: "Balances of all accounts at time", Params: []apiParam{asOfParam}, Result: []*statement.Balance{}},
+	{Name: "import_accounts", Method: http.MethodPost, Path: "/accounts/import",
+		Summary: "Import accounts from CSV with header name,currency,balance[,external_reference]",
+		Params: []apiParam{
+			{In: "query", Name: "dry_run", Type: "boolean", Description: "validate rows without creating accounts"},
+			{In: "query", Name: "chunk_size", Type: "integer",
+				Description: "create accounts in transactions of chunk size, default is single transaction"},
+		},
+		Consumes: "text/csv", Result: &importer.Report{}},
+	{Name: "transfer_money", Method: http.MethodPost, Path: "/payments", Summary: "Transfer money",
+		Params: []apiParam{{In: "header", Name: IdempotencyKeyHeader, Type: "string",
+			Description: "up to 64 characters, transfer repeated with the same key returns payment made " +
+				"by first one, key is external_id of payment unless set"}},
+		Request: transferMoneyRequest{}, Result: &payment.Payment{}},
+	{Name: "list_payments", Method: http.MethodGet, Path: "/payments", Summary: "List payments",
+		Params: append(metadataParams[:len(metadataParams):len(metadataParams)],
+			apiParam{In: "query", Name: "q", Type: "string", Description: "words of description"},
+			apiParam{In: "query", Name: "category", Type: "string",
+				Description: strings.Join(payment.Categories, ", ")},
+		),
+		Result: []*payment.Payment{}},
+	{Name: "create_customer", Method: http.MethodPost, Path: "/customers", Summary: "Create customer",
+		Request: customerCreateRequest{}, Result: &customer.Customer{}},
+	{Name: "list_customers", Method: http.MethodGet, Path: "/customers", Summary: "List customers",
+		Result: []*customer.Customer{}},
+	{Name: "get_customer", Method: http.MethodGet, Path: "/customers/{id}", Summary: "Get customer",
+		Params: []apiParam{idParam}, Result: &customer.Customer{}},
+	{Name: "list_customer_accounts", Method: http.MethodGet, Path: "/customers/{id}/accounts",
+		Summary: "List accounts of customer", Params: []apiParam{idParam}, Result: []*account.Account{}},
+	{Name: "create_api_key", Method: http.MethodPost, Path: "/apikeys", Summary: "Create api key",
+		Request: apiKeyCreateRequest{}, Result: &apiKeyCreateResponse{}},
+	{Name: "list_api_keys", Method: http.MethodGet, Path: "/apikeys", Summary: "List api keys",
+		Result: []*auth.APIKey{}},
+	{Name: "revoke_api_key", Method: http.MethodDelete, Path: "/apikeys/{id}", Summary: "Revoke api key",
+		Params: []apiParam{idParam}, Result: ""},
+	{Name: "list_audit_entries", Method: http.MethodGet, Path: "/audit", Summary: "List audit log entries",
+		Params: []apiParam{
+			{In: "query", Name: "entity_type", Type: "string"},
+			{In: "query", Name: "entity_id", Type: "string"},
+			{In: "query", Name: "after", Type: "integer", Format: "int64",
+				Description: "identifier of entry after which entries are listed"},
+			{In: "query", Name: "limit", Type: "integer", Description: "at most 1000, default 100"},
+		},
+		Result: []*audit.Entry{}},
+	{Name: "report_balances", Method: http.MethodGet, Path: "/reports/balances",
+		Summary: "Report total balance of accounts per currency", Result: []*reporting.CurrencyBalance{}},
+	{Name: "report_daily_volume", Method: http.MethodGet, Path: "/reports/daily_volume",
+		Summary: "Report number and sum of transfers per day and currency", Params: reportPeriodParams,
+		Result: []*reporting.DailyVolume{}},
+	{Name: "report_top_accounts", Method: http.MethodGet, Path: "/reports/top_accounts",
+		Summary: "Report accounts sent or received most money",
+		Params: append(reportPeriodParams[:len(reportPeriodParams):len(reportPeriodParams)],
+			apiParam{In: "query", Name: "direction", Type: "string", Description: "senders (default) or receivers"},
+			apiParam{In: "query", Name: "currency", Type: "string", Description: "all currencies when empty"},
+			apiParam{In: "query", Name: "limit", Type: "integer", Description: "at most 100, default 10"},
+		),
+		Result: []*reporting.TopAccount{}},
+	{Name: "close_period", Method: http.MethodPost, Path: "/periods",
+		Summary: "Close days before until, payments of closed days cannot be changed",
+		Request: periodCloseRequest{}, Result: &closing.Closing{}},
+	{Name: "list_projections", Method: http.MethodGet, Path: "/projections",
+		Summary: "List positions of projections and their lag behind events", Result: []*projection.Status{}},
+	{Name: "rebuild_projection", Method: http.MethodPost, Path: "/projections/{name}/rebuild",
+		Summary: "Rebuild read model of projection from all events",
+		Params: []apiParam{{In: "path", Name: "name", Type: "string",
+			Description: strings.Join(projection.Names, ", ")}},
+		Result: &projection.Status{}},
+	{Name: "list_account_summaries", Method: http.MethodGet, Path: "/projections/account_summaries",
+		Summary: "List accounts with counters and last activity of payments, eventually consistent with accounts",
+		Params:  metadataParams, Result: []*projection.AccountSummary{}},
+	{Name: "list_period_closings", Method: http.MethodGet, Path: "/periods", Summary: "List closings of periods",
+		Result: []*closing.Closing{}},
+	{Name: "create_alias", Method: http.MethodPost, Path: "/aliases",
+		Summary: "Create handle, phone number or email alias of account, transfers to alias reach its default account",
+		Request: aliasCreateRequest{}, Result: &alias.Alias{}},
+	{Name: "list_aliases", Method: http.MethodGet, Path: "/aliases", Summary: "List aliases",
+		Result: []*alias.Alias{}},
+	{Name: "get_alias", Method: http.MethodGet, Path: "/aliases/{id}", Summary: "Get alias",
+		Params: []apiParam{idParam}, Result: &alias.Alias{}},
+	{Name: "delete_alias", Method: http.MethodDelete, Path: "/aliases/{id}", Summary: "Delete alias",
+		Params: []apiParam{idParam}, Result: ""},
+	{Name: "link_alias_account", Method: http.MethodPost, Path: "/aliases/{id}/accounts",
+		Summary: "Link account to alias, first account of currency becomes default",
+		Params:  []apiParam{idParam}, Request: aliasLinkRequest{}, Result: &alias.Alias{}},
+	{Name: "unlink_alias_account", Method: http.MethodDelete, Path: "/aliases/{id}/accounts/{account_id}",
+		Summary: "Unlink account from alias",
+		Params:  []apiParam{idParam, {In: "path", Name: "account_id", Type: "string", Format: "uuid"}},
+		Result:  &alias.Alias{}},
+	{Name: "verify_alias", Method: http.MethodPost, Path: "/aliases/{id}/verify",
+		Summary: "Verify phone number or email alias", Params: []apiParam{idParam}, Result: &alias.Alias{}},
+	{Name: "create_payment_request", Method: http.MethodPost, Path: "/payment_requests",
+		Summary: "Request money from payer account or alias, request expires after default expiry unless set",
+		Request: paymentRequestCreateRequest{}, Result: &paymentrequest.Request{}},
+	{Name: "list_payment_requests", Method: http.MethodGet, Path: "/payment_requests",
+		Summary: "List payment requests sent or received by accounts",
+		Params: []apiParam{
+			{In: "query", Name: "account_id", Type: "string", Format: "uuid",
+				Description: "account requesting or requested to pay"},
+			{In: "query", Name: "status", Type: "string", Description: strings.Join(paymentrequest.Statuses, ", ")},
+		},
+		Result: []*paymentrequest.Request{}},
+	{Name: "get_payment_request", Method: http.MethodGet, Path: "/payment_requests/{id}",
+		Summary: "Get payment request", Params: []apiParam{idParam}, Result: &paymentrequest.Request{}},
+	{Name: "accept_payment_request", Method: http.MethodPost, Path: "/payment_requests/{id}/accept",
+		Summary: "Accept payment request by transferring requested amount from payer account",
+		Params:  []apiParam{idParam}, Result: &paymentrequest.Request{}},
+	{Name: "decline_payment_request", Method: http.MethodPost, Path: "/payment_requests/{id}/decline",
+		Summary: "Decline payment request by payer", Params: []apiParam{idParam}, Result: &paymentrequest.Request{}},
+	{Name: "cancel_payment_request", Method: http.MethodPost, Path: "/payment_requests/{id}/cancel",
+		Summary: "Cancel payment request by payee", Params: []apiParam{idParam}, Result: &paymentrequest.Request{}},
+	{Name: "liveness", Method: http.MethodGet, Path: "/healthz", Summary: "Liveness probe",
+		Result: &health.Report{}, Unversioned: true},
+	{Name: "readiness", Method: http.MethodGet, Path: "/readyz", Summary: "Readiness probe",
+		Result: &health.Report{}, Statuses: []int{http.StatusOK, http.StatusServiceUnavailable}, Unversioned: true},
+}
+
+// OpenAPI make OpenAPI document describing routes of version, schemas are generated from types
+// of requests and results, constraints of request fields are taken from validation rules
+func OpenAPI(v Version) map[string]interface{} {
+	g := &schemaGenerator{version: v, schemas: map[string]interface{}{}}
+	g.schema(reflect.TypeOf(apperror.Problem{}), false)
+
+	paths := map[string]interface{}{}
+	for _, op := range apiOperations {
+		item, ok := paths[op.Path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = g.operation(op)
+	}
+
+	return map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]interface{}{
+			"title":   "Wallet",
+			"version": strconv.Itoa(int(v)) + ".0.0",
+		},
+		"servers": []interface{}{
+			map[string]interface{}{"url": v.Prefix()},
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": g.schemas,
+			"securitySchemes": map[string]interface{}{
+				"apiKey": map[string]interface{}{
+					"type": "apiKey",
+					"in":   "header",
+					"name": auth.APIKeyHeader,
+				},
+				"bearer": map[string]interface{}{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+				},
+			},
+		},
+	}
+}
+
+// MakeOpenAPIEndpoint init handler serving OpenAPI document of version
+func MakeOpenAPIEndpoint(v Version) http.Handler {
+	document, err := json.Marshal(OpenAPI(v))
+	if err != nil {
+		panic(err)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", contentTypeJSON)
+		w.Write(document) // nolint: errcheck
+	})
+}
+
+type schemaGenerator struct {
+	version Version
+	schemas map[string]interface{}
+}
+
+func (g *schemaGenerator) operation(op apiOperation) map[string]interface{} {
+	statuses := op.Statuses
+	if len(statuses) == 0 {
+		statuses = []int{http.StatusOK}
+	}
+
+	result := map[string]interface{}{}
+	if op.Result != nil {
+		result = g.schema(reflect.TypeOf(g.version.result(op.Result)), false)
+	}
+
+	envelope := map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": false,
+		"required":             []string{"result", "error"},
+		"properties": map[string]interface{}{
+			"result": result,
+			"error":  map[string]interface{}{"nullable": true},
+		},
+	}
+	if g.version == V2 && !op.Unversioned {
+		envelope = map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": false,
+			"required":             []string{"data"},
+			"properties": map[string]interface{}{
+				"data": result,
+			},
+		}
+	}
+
+	responses := map[string]interface{}{
+		"default": map[string]interface{}{
+			"description": "Error",
+			"content": map[string]interface{}{
+				apperror.ProblemContentType: map[string]interface{}{
+					"schema": ref("Problem"),
+				},
+			},
+		},
+	}
+	for _, status := range statuses {
+		if len(op.Produces) > 0 {
+			content := map[string]interface{}{}
+			for _, contentType := range op.Produces {
+				content[strings.Split(contentType, ";")[0]] = map[string]interface{}{
+					"schema": map[string]interface{}{"type": "string"},
+				}
+			}
+			responses[strconv.Itoa(status)] = map[string]interface{}{
+				"description": http.StatusText(status),
+				"content":     content,
+			}
+			continue
+		}
+		responses[strconv.Itoa(status)] = map[string]interface{}{
+			"description": http.StatusText(status),
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": envelope,
+				},
+			},
+		}
+	}
+
+	operation := map[string]interface{}{
+		"operationId": op.Name,
+		"summary":     op.Summary,
+		"responses":   responses,
+	}
+
+	if op.Unversioned {
+		operation["servers"] = []interface{}{
+			map[string]interface{}{"url": "/"},
+		}
+	}
+
+	if scope, ok := endpointScopes[op.Name]; ok {
+		operation["security"] = []interface{}{
+			map[string]interface{}{"apiKey": []string{}},
+			map[string]interface{}{"bearer": []string{}},
+		}
+		operation["x-required-scope"] = scope
+	}
+
+	if len(op.Params) > 0 {
+		var params []interface{}
+		for _, p := range op.Params {
+			schema := map[string]interface{}{"type": p.Type}
+			if p.Format != "" {
+				schema["format"] = p.Format
+			}
+			param := map[string]interface{}{
+				"in":       p.In,
+				"name":     p.Name,
+				"required": p.In == "path",
+				"schema":   schema,
+			}
+			if p.Description != "" {
+				param["description"] = p.Description
+			}
+			if p.Style != "" {
+				param["style"] = p.Style
+				schema["additionalProperties"] = map[string]interface{}{"type": "string"}
+			}
+			params = append(params, param)
+		}
+		operation["parameters"] = params
+	}
+
+	if op.Consumes != "" {
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				op.Consumes: map[string]interface{}{
+					"schema": map[string]interface{}{"type": "string"},
+				},
+			},
+		}
+	}
+	if op.Request != nil {
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": g.schema(reflect.TypeOf(g.version.request(op.Request)), true),
+				},
+			},
+		}
+	}
+	return operation
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	decimalType    = reflect.TypeOf(decimal(0))
+)
+
+// schema make JSON schema of type, named structs are stored in components and referenced,
+// pointers to structs are never null in responses, so only pointers to scalars are nullable,
+// fields of requests are required by validation rules and of responses unless omitted when empty
+func (g *schemaGenerator) schema(t reflect.Type, request bool) map[string]interface{} {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var schema map[string]interface{}
+	switch {
+	case t == timeType:
+		schema = map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		schema = map[string]interface{}{}
+	case t == decimalType:
+		schema = map[string]interface{}{"type": "string", "format": "decimal", "pattern": decimalPattern.String()}
+	case t.Kind() == reflect.Struct:
+		name := schemaName(t)
+		if _, ok := g.schemas[name]; !ok {
+			g.schemas[name] = nil
+			g.schemas[name] = g.object(t, request)
+		}
+		return ref(name)
+	case t.Kind() == reflect.Slice:
+		schema = map[string]interface{}{"type": "array", "items": g.schema(t.Elem(), request)}
+		nullable = true
+	case t.Kind() == reflect.Map:
+		schema = map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem(), request)}
+	case t.Kind() == reflect.String:
+		schema = map[string]interface{}{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		schema = map[string]interface{}{"type": "boolean"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		schema = map[string]interface{}{"type": "number"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		schema = map[string]interface{}{"type": "integer"}
+		if t.Size() == 8 {
+			schema["format"] = "int64"
+		}
+	default:
+		schema = map[string]interface{}{}
+	}
+
+	if nullable && len(schema) > 0 {
+		schema["nullable"] = true
+	}
+	return schema
+}
+
+func (g *schemaGenerator) object(t reflect.Type, request bool) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		name := tag[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		omitEmpty := len(tag) > 1 && tag[1] == "omitempty"
+
+		property := g.schema(field.Type, request)
+		rules := parseRules(field.Tag.Get("validate"))
+		if request {
+			property = withConstraints(property, field.Type, rules)
+			if _, ok := rules["required"]; ok {
+				required = append(required, name)
+			}
+		} else if !omitEmpty {
+			required = append(required, name)
+		}
+		properties[name] = property
+	}
+
+	object := map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties":           properties,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		object["required"] = required
+	}
+	return object
+}
+
+// withConstraints describe validation rules of request field
+func withConstraints(schema map[string]interface{}, t reflect.Type, rules map[string]string) map[string]interface{} {
+	lengthPrefix := "Length"
+	if t.Kind() == reflect.Slice {
+		lengthPrefix = "Items"
+	}
+
+	for rule, param := range rules {
+		switch rule {
+		case "uuid":
+			schema["format"] = "uuid"
+		case "email":
+			schema["format"] = "email"
+		case "date":
+			schema["format"] = "date"
+		case "currency":
+			schema["pattern"] = "^[a-zA-Z]{3}$"
+		case "name":
+			schema["description"] = "letters, digits, spaces and -_.,'&"
+		case "metadata":
+			schema["maxProperties"] = metadata.MaxKeys
+			schema["additionalProperties"] = map[string]interface{}{
+				"type": "string", "maxLength": metadata.MaxValueLength}
+			schema["description"] = fmt.Sprintf("keys are 1 to %d characters long", metadata.MaxKeyLength)
+		case "min":
+			schema["min"+lengthPrefix], _ = strconv.Atoi(param)
+		case "max":
+			schema["max"+lengthPrefix], _ = strconv.Atoi(param)
+		case "precision":
+			digits, _ := strconv.Atoi(param)
+			if t == decimalType {
+				schema["pattern"] = fmt.Sprintf(`^-?[0-9]+(\.[0-9]{1,%d})?$`, digits)
+				continue
+			}
+			schema["multipleOf"] = 1 / float64(pow10(digits))
+		}
+	}
+	return schema
+}
+
+func parseRules(tag string) map[string]string {
+	rules := map[string]string{}
+	if tag == "" {
+		return rules
+	}
+	for _, item := range strings.Split(tag, ",") {
+		name, param := item, ""
+		if n := strings.Index(item, "="); n >= 0 {
+			name, param = item[:n], item[n+1:]
+		}
+		rules[name] = param
+	}
+	return rules
+}
+
+func pow10(n int) int {
+	result := 1
+	for i := 0; i < n; i++ {
+		result *= 10
+	}
+	return result
+}
+
+// schemaPrefixes prefixes of schema names of types of packages, whose names are used by other packages
+var schemaPrefixes = map[string]string{
+	"audit":    "Audit",
+	"importer": "Import",
+}
+
+func schemaName(t reflect.Type) string {
+	name := t.Name()
+	pkg := t.PkgPath()
+	name = schemaPrefixes[pkg[strings.LastIndex(pkg, "/")+1:]] + name
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func ref(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
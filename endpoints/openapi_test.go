@@ -0,0 +1,324 @@
+package endpoints
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-kit/kit/log"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/alias"
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/audit"
+	"github.com/sbutakov/wallet/pkg/closing"
+	"github.com/sbutakov/wallet/pkg/customer"
+	"github.com/sbutakov/wallet/pkg/health"
+	"github.com/sbutakov/wallet/pkg/importer"
+	"github.com/sbutakov/wallet/pkg/payment"
+	"github.com/sbutakov/wallet/pkg/paymentrequest"
+	"github.com/sbutakov/wallet/pkg/projection"
+	"github.com/sbutakov/wallet/pkg/reporting"
+	"github.com/sbutakov/wallet/pkg/statement"
+)
+
+type dummyCustomerService struct {
+}
+
+func (d *dummyCustomerService) Create(_ context.Context, name, email string) (*customer.Customer, error) {
+	return &customer.Customer{ID: dummyUUID, Name: name, Email: email, CreatedAt: time.Now()}, nil
+}
+
+func (d *dummyCustomerService) List(context.Context) ([]*customer.Customer, error) {
+	return []*customer.Customer{{ID: dummyUUID, Name: "Alice"}}, nil
+}
+
+func (d *dummyCustomerService) Get(_ context.Context, id string) (*customer.Customer, error) {
+	return &customer.Customer{ID: id, Name: "Alice"}, nil
+}
+
+type dummyAuditEntriesService struct {
+}
+
+func (d *dummyAuditEntriesService) List(context.Context, audit.Filter) ([]*audit.Entry, error) {
+	return []*audit.Entry{{ID: 1, Action: audit.ActionCreateAccount, After: json.RawMessage(`{"id":1}`)}}, nil
+}
+
+// openAPIRequests bodies of requests sent to operations, v2 bodies replace them in V2
+var openAPIRequests = map[string]string{
+	"create_account": `{"name":"dummy","currency":"usd","balance":100.5,"external_id":"c-1","metadata":{"a":"b"}}`,
+	"transfer_money": `{"account_from":"` + dummyUUID + `","account_to":"2c1d9e8f-6a5b-4c3d-8e7f-0a1b2c3d4e5f","amount":1,` +
+		`"description":"rent","category":"bills"}`,
+	"create_customer":    `{"name":"Alice","email":"alice@example.com"}`,
+	"create_api_key":     `{"name":"dummy","scopes":["accounts:read"]}`,
+	"close_period":       `{"until":"2026-01-01"}`,
+	"create_alias":       `{"value":"alice","account_id":"` + dummyUUID + `"}`,
+	"link_alias_account": `{"account_id":"` + dummyUUID + `","default":true}`,
+	"create_payment_request": `{"payee_account_id":"` + dummyUUID + `","payer_account_id":"2c1d9e8f-6a5b-4c3d-8e7f-0a1b2c3d4e5f",` +
+		`"amount":10.5,"currency":"usd","memo":"dinner"}`,
+	"import_accounts": "name,currency,balance,external_reference\ndummy,usd,100.50,order-1\ndummy,eur,1,\n",
+}
+
+var openAPIRequestsV2 = map[string]string{
+	"create_account": `{"name":"dummy","currency":"usd","balance":"100.50","external_id":"c-1","metadata":{"a":"b"}}`,
+	"transfer_money": `{"account_from":"` + dummyUUID + `","account_to":"2c1d9e8f-6a5b-4c3d-8e7f-0a1b2c3d4e5f","amount":"1.00",` +
+		`"description":"rent","category":"bills"}`,
+	"create_payment_request": `{"payee_account_id":"` + dummyUUID + `","payer_account_id":"2c1d9e8f-6a5b-4c3d-8e7f-0a1b2c3d4e5f",` +
+		`"amount":"10.50","currency":"usd","memo":"dinner"}`,
+}
+
+func makeOpenAPIServices(t *testing.T) Services {
+	accounts, err := account.New(account.Config{AllowedCurrency: []string{"usd"}}, &dummyStorage{})
+	if err != nil {
+		t.Fatal("unexpected error on init account service")
+	}
+
+	return Services{
+		Accounts:    accounts,
+		Statements:  statement.New(&dummyStorage{}),
+		Imports:     importer.New(accounts, &dummyStorage{}),
+		Customers:   &dummyCustomerService{},
+		Payments:    payment.New(&dummyStorage{}),
+		APIKeys:     &dummyAPIKeyService{},
+		Audit:       &dummyAuditEntriesService{},
+		Reports:     reporting.New(reporting.Config{}, &dummyStorage{}),
+		Periods:     closing.New(closing.Config{}, &dummyStorage{}),
+		Projections: projection.New(projection.Config{}, &dummyStorage{}),
+		Aliases:     alias.New(&dummyStorage{}),
+		PaymentRequests: paymentrequest.New(paymentrequest.Config{DefaultExpiry: time.Hour, MaxExpiry: time.Hour},
+			&dummyStorage{}, payment.New(&dummyStorage{})),
+	}
+}
+
+// makeOpenAPIRouter mount routes of API as main does
+func makeOpenAPIRouter(t *testing.T) chi.Router {
+	var logger log.Logger
+	logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	logger = log.With(logger, "wallet", log.DefaultTimestampUTC)
+
+	services := makeOpenAPIServices(t)
+	router := chi.NewRouter()
+	MountRoutes(router, Config{}, health.New(health.Config{}), func(router chi.Router, v Version) {
+		MountAPI(router, services, logger, WithVersion(v))
+	}, logger)
+	return router
+}
+
+func TestOpenAPI_Routes(t *testing.T) {
+	var routes, documented []string
+	err := chi.Walk(makeOpenAPIRouter(t), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes = append(routes, method+" "+strings.TrimSuffix(strings.Replace(route, "/*", "", -1), "/"))
+		return nil
+	})
+	if err != nil {
+		t.Fatal("unexpected error on walk routes")
+	}
+
+	// routes without version prefix are aliases of V1, probes are served without prefix only
+	mounts := map[string]Version{"": V1}
+	for _, v := range Versions {
+		mounts[v.Prefix()] = v
+	}
+	for prefix, v := range mounts {
+		documented = append(documented, http.MethodGet+" "+prefix+"/openapi.json")
+		for path, item := range OpenAPI(v)["paths"].(map[string]interface{}) {
+			for method, operation := range item.(map[string]interface{}) {
+				if _, unversioned := operation.(map[string]interface{})["servers"]; unversioned {
+					if prefix == "" {
+						documented = append(documented, strings.ToUpper(method)+" "+path)
+					}
+					continue
+				}
+				documented = append(documented, strings.ToUpper(method)+" "+prefix+path)
+			}
+		}
+	}
+
+	sort.Strings(routes)
+	sort.Strings(documented)
+	if strings.Join(routes, "\n") != strings.Join(documented, "\n") {
+		t.Errorf("documented routes %v differ from mounted %v", documented, routes)
+	}
+}
+
+func TestOpenAPI_Conformance(t *testing.T) {
+	for _, v := range Versions {
+		testOpenAPIConformance(t, v)
+	}
+}
+
+func testOpenAPIConformance(t *testing.T, v Version) {
+	data, err := json.Marshal(OpenAPI(v))
+	if err != nil {
+		t.Fatal("unexpected error on marshal document")
+	}
+	spec := map[string]interface{}{}
+	if err = json.Unmarshal(data, &spec); err != nil {
+		t.Fatal("unexpected error on unmarshal document")
+	}
+
+	server := httptest.NewServer(makeOpenAPIRouter(t))
+	defer server.Close()
+
+	paths := spec["paths"].(map[string]interface{})
+	for _, op := range apiOperations {
+		operation := paths[op.Path].(map[string]interface{})[strings.ToLower(op.Method)].(map[string]interface{})
+		name := v.Prefix() + " " + op.Name
+		prefix := v.Prefix()
+		if op.Unversioned {
+			prefix = ""
+		}
+		body, ok := openAPIRequestsV2[op.Name]
+		if !ok || v != V2 {
+			body = openAPIRequests[op.Name]
+		}
+
+		path := strings.Replace(op.Path, "{id}", dummyUUID, 1)
+		path = strings.Replace(path, "{name}", projection.AccountSummaries, 1)
+		path = strings.Replace(path, "{account_id}", dummyUUID, 1)
+		response := doOpenAPIRequest(t, server.URL+prefix+path, op.Method, body)
+		if response.StatusCode >= http.StatusBadRequest {
+			t.Errorf("%s: unexpected status %d", name, response.StatusCode)
+		}
+		validateOpenAPIResponse(t, spec, operation, name, response)
+
+		// invalid identifiers and bodies are responded with problem of default response
+		if strings.Contains(op.Path, "{id}") || op.Request != nil || op.Consumes != "" {
+			path = strings.Replace(op.Path, "{id}", "dummy", 1)
+			response = doOpenAPIRequest(t, server.URL+prefix+path, op.Method, `{"dummy":1}`)
+			validateOpenAPIResponse(t, spec, operation, name, response)
+		}
+	}
+
+	response := doOpenAPIRequest(t, server.URL+v.Prefix()+"/openapi.json", http.MethodGet, "")
+	if response.StatusCode != http.StatusOK {
+		t.Error("expected document served")
+	}
+}
+
+func doOpenAPIRequest(t *testing.T, url, method, body string) *http.Response {
+	request, err := http.NewRequest(method, url, bytes.NewReader([]byte(body)))
+	if err != nil {
+		t.Fatal("unexpected error on make request")
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal("unexpected error on request")
+	}
+	return response
+}
+
+func validateOpenAPIResponse(
+	t *testing.T, spec, operation map[string]interface{}, name string, response *http.Response) {
+
+	defer response.Body.Close()
+	responses := operation["responses"].(map[string]interface{})
+	documented, ok := responses[fmt.Sprint(response.StatusCode)].(map[string]interface{})
+	if !ok {
+		documented = responses["default"].(map[string]interface{})
+	}
+
+	contentType := strings.Split(response.Header.Get("Content-Type"), ";")[0]
+	content, ok := documented["content"].(map[string]interface{})[contentType].(map[string]interface{})
+	if !ok {
+		t.Errorf("%s: undocumented content type %q of status %d", name, contentType, response.StatusCode)
+		return
+	}
+
+	// documents other than JSON are not validated by schema
+	if contentType != "application/json" && contentType != apperror.ProblemContentType {
+		return
+	}
+
+	var body interface{}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		t.Errorf("%s: error on decode response", name)
+		return
+	}
+
+	if err := validateSchema(spec, content["schema"].(map[string]interface{}), body, "$"); err != nil {
+		t.Errorf("%s: response of status %d does not conform: %v", name, response.StatusCode, err)
+	}
+}
+
+// validateSchema check value against subset of OpenAPI schema used by document
+func validateSchema(spec, schema map[string]interface{}, value interface{}, path string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		components := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+		return validateSchema(spec, components[name].(map[string]interface{}), value, path)
+	}
+
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable || schema["type"] == nil {
+			return nil
+		}
+		return fmt.Errorf("%s is null", path)
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s is not object", path)
+		}
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok = object[name.(string)]; !ok {
+				return fmt.Errorf("%s.%s is required", path, name)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, item := range object {
+			property, ok := properties[name].(map[string]interface{})
+			if !ok {
+				if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+					property = additional
+				} else {
+					return fmt.Errorf("%s.%s is not documented", path, name)
+				}
+			}
+			if err := validateSchema(spec, property, item, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s is not array", path)
+		}
+		for i, item := range array {
+			err := validateSchema(spec, schema["items"].(map[string]interface{}), item, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s is not string", path)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s is not number", path)
+		}
+	case "integer":
+		if number, ok := value.(float64); !ok || number != math.Trunc(number) {
+			return fmt.Errorf("%s is not integer", path)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s is not boolean", path)
+		}
+	}
+	return nil
+}
@@ -2,46 +2,77 @@ package endpoints
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi"
 	"github.com/go-kit/kit/endpoint"
 	kitlog "github.com/go-kit/kit/log"
 	kithttp "github.com/go-kit/kit/transport/http"
-	"github.com/pkg/errors"
 
+	"github.com/sbutakov/wallet/pkg/metadata"
 	"github.com/sbutakov/wallet/pkg/payment"
+	"github.com/sbutakov/wallet/pkg/validation"
 )
 
+// IdempotencyKeyHeader header with key of transfer, transfer repeated with the same key is made once
+const IdempotencyKeyHeader = "Idempotency-Key"
+
 type transferMoneyRequest struct {
-	AccountFrom string  `json:"account_from"`
-	AccountTo   string  `json:"account_to"`
-	Amount      float64 `json:"amount"`
+	AccountFrom string            `json:"account_from" validate:"required,uuid"`
+	AccountTo   string            `json:"account_to" validate:"required,max=254"`
+	Amount      float64           `json:"amount" validate:"precision=2"`
+	ExternalID  string            `json:"external_id" validate:"max=64"`
+	Metadata    metadata.Metadata `json:"metadata" validate:"metadata"`
+	Description string            `json:"description" validate:"max=140"`
+	Category    string            `json:"category" validate:"max=20"`
+
+	idempotencyKey string
+}
+
+// idempotencyKeyRequest key of call taken from header, it is stored as external identifier
+type idempotencyKeyRequest struct {
+	Key string `json:"Idempotency-Key" validate:"max=64"`
+}
+
+func (r transferMoneyRequest) details() payment.Details {
+	return payment.Details{
+		ExternalID:     r.ExternalID,
+		Metadata:       r.Metadata,
+		Description:    r.Description,
+		Category:       r.Category,
+		IdempotencyKey: r.idempotencyKey,
+	}
 }
 
 // PaymentService interface for transfer and viewing payments
 type PaymentService interface {
-	PaymentList() ([]*payment.Payment, error)
-	TransferMoney(accountFromID, accountToID string, amount float64) (*payment.Payment, error)
+	PaymentList(ctx context.Context, filter payment.Filter) ([]*payment.Payment, error)
+	TransferMoney(ctx context.Context, accountFromID, accountToID string, amount float64,
+		details payment.Details) (*payment.Payment, error)
 }
 
 // MakePaymentEndpoints init router for handling create and view payments
-func MakePaymentEndpoints(service PaymentService, logger kitlog.Logger) http.Handler {
+func MakePaymentEndpoints(service PaymentService, logger kitlog.Logger, opts ...Option) http.Handler {
+	o := newOptions(opts)
 	router := chi.NewRouter()
 	router.Method(http.MethodPost, "/", kithttp.NewServer(
-		transferMoney(service), decodeTransferMoneyRequest, encodeTransferMoneyResponse,
-		[]kithttp.ServerOption{
+		o.endpoint("transfer_money", transferMoney(service)),
+		decodeTransferMoneyRequest,
+		encodeTransferMoneyResponse,
+		o.server("transfer_money",
 			kithttp.ServerErrorLogger(logger),
-			kithttp.ServerErrorEncoder(encodePaymentError),
-		}...))
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
 
 	router.Method(http.MethodGet, "/", kithttp.NewServer(
-		listPayments(service), decodeListPaymentsRequest, encodeListPaymentsResponse,
-		[]kithttp.ServerOption{
+		o.endpoint("list_payments", listPayments(service)),
+		decodeListPaymentsRequest,
+		encodeListPaymentsResponse,
+		o.server("list_payments",
 			kithttp.ServerErrorLogger(logger),
-			kithttp.ServerErrorEncoder(encodePaymentError),
-		}...))
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
 
 	return router
 }
@@ -49,65 +80,72 @@ func MakePaymentEndpoints(service PaymentService, logger kitlog.Logger) http.Han
 func transferMoney(service PaymentService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 		req := request.(transferMoneyRequest)
-		return service.TransferMoney(req.AccountFrom, req.AccountTo, req.Amount)
+		return service.TransferMoney(ctx, req.AccountFrom, req.AccountTo, req.Amount, req.details())
 	}
 }
 
-func decodeTransferMoneyRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	req := transferMoneyRequest{}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		return nil, errors.Wrap(err, "error on decode request")
+func decodeTransferMoneyRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	key := idempotencyKeyRequest{Key: strings.TrimSpace(r.Header.Get(IdempotencyKeyHeader))}
+	if err := validation.Validate(key); err != nil {
+		return nil, err
+	}
+
+	var req transferMoneyRequest
+	if versionFromContext(ctx) == V2 {
+		reqV2 := transferMoneyRequestV2{}
+		if err := decodeJSONRequest(r, &reqV2); err != nil {
+			return nil, err
+		}
+		req = reqV2.endpointRequest()
+	} else if err := decodeJSONRequest(r, &req); err != nil {
+		return nil, err
 	}
+	req.idempotencyKey = key.Key
 	return req, nil
 }
 
 func encodeTransferMoneyResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	if err, ok := response.(error); ok {
-		encodePaymentError(ctx, err, w)
+		encodeError(ctx, err, w)
 		return nil
 	}
 	resp := response.(*payment.Payment)
-	return json.NewEncoder(w).Encode(schemaResponse{
-		Result: resp,
-	})
+	return encodeResult(ctx, w, resp)
 }
 
 func listPayments(service PaymentService) endpoint.Endpoint {
-	return func(_ context.Context, _ interface{}) (response interface{}, err error) {
-		return service.PaymentList()
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		filter, _ := request.(payment.Filter)
+		return service.PaymentList(ctx, filter)
 	}
 }
 
-func decodeListPaymentsRequest(_ context.Context, _ *http.Request) (interface{}, error) {
-	return nil, nil
+type paymentFilterRequest struct {
+	Query    string `json:"q" validate:"max=140"`
+	Category string `json:"category" validate:"max=20"`
+}
+
+// decodeListPaymentsRequest decode filter of payments from query params of metadata filter,
+// q matching words of description and category
+func decodeListPaymentsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	filter, err := decodeMetadataFilter(r)
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.URL.Query()
+	req := paymentFilterRequest{Query: strings.TrimSpace(query.Get("q")), Category: query.Get("category")}
+	if err = validation.Validate(req); err != nil {
+		return nil, err
+	}
+	return payment.Filter{Filter: filter, Query: req.Query, Category: req.Category}, nil
 }
 
 func encodeListPaymentsResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	if err, ok := response.(error); ok {
-		encodeAccountError(ctx, err, w)
+		encodeError(ctx, err, w)
 		return nil
 	}
 	resp := response.([]*payment.Payment)
-	return json.NewEncoder(w).Encode(schemaResponse{
-		Result: resp,
-	})
-}
-
-func encodePaymentError(_ context.Context, err error, w http.ResponseWriter) {
-	switch err {
-	case payment.ErrorMoneyTransfer:
-	case payment.ErrorTransferYourself:
-	case payment.ErrorIncorrectAmount:
-		w.WriteHeader(http.StatusBadRequest)
-
-	case payment.ErrorDifferentCurrencies:
-	case payment.ErrorNotEnoughMoney:
-		w.WriteHeader(http.StatusOK)
-	default:
-		w.WriteHeader(http.StatusInternalServerError)
-	}
-
-	json.NewEncoder(w).Encode(schemaResponse{ // nolint: errcheck
-		Error: err.Error(),
-	})
+	return encodeResult(ctx, w, resp)
 }
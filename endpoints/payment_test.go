@@ -2,27 +2,55 @@ package endpoints
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/sbutakov/wallet/pkg/payment"
+	"github.com/sbutakov/wallet/pkg/paymentrequest"
 
 	"github.com/go-kit/kit/log"
 )
 
-func (d *dummyStorage) TransferMoney(
-	accountFromID, accountToID string, amount float64) (*payment.Payment, error) {
+func (d *dummyStorage) TransferMoney(_ context.Context, accountFromID, accountToID string, amount float64,
+	details payment.Details) (*payment.Payment, error) {
 
-	return &payment.Payment{}, nil
+	return &payment.Payment{
+		ExternalID:  details.ExternalID,
+		Metadata:    details.Metadata,
+		Description: details.Description,
+		Category:    details.Category,
+	}, nil
 }
 
-func (d *dummyStorage) PaymentList() ([]*payment.Payment, error) {
+func (d *dummyStorage) PaymentList(_ context.Context, filter payment.Filter) ([]*payment.Payment, error) {
+	if filter.Query == "" {
+		return nil, nil
+	}
+	return []*payment.Payment{{Description: filter.Query, Category: filter.Category}}, nil
+}
+
+func (d *dummyStorage) CustomerPaymentList(context.Context, string, payment.Filter) ([]*payment.Payment, error) {
 	return nil, nil
 }
 
+func (d *dummyStorage) StreamPayments(_ context.Context, _ payment.Filter, sink payment.Sink) error {
+	for i, id := range []string{"first", "second", "third"} {
+		if err := sink(&payment.Payment{ID: id, Amount: float64(i + 1)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *dummyStorage) CustomerStreamPayments(context.Context, string, payment.Filter, payment.Sink) error {
+	return nil
+}
+
 func TestMakePaymentEndpoints(t *testing.T) {
 	var logger log.Logger
 	logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
@@ -33,8 +61,8 @@ func TestMakePaymentEndpoints(t *testing.T) {
 	server := httptest.NewServer(MakePaymentEndpoints(service, logger))
 
 	body, err := json.Marshal(transferMoneyRequest{
-		AccountFrom: "dummy_from",
-		AccountTo:   "dummy_to",
+		AccountFrom: "7b6c7d2a-3f0e-4d8e-9a51-1f3c2b4a5d60",
+		AccountTo:   "2c1d9e8f-6a5b-4c3d-8e7f-0a1b2c3d4e5f",
 		Amount:      100.01,
 	})
 	if err != nil {
@@ -58,4 +86,91 @@ func TestMakePaymentEndpoints(t *testing.T) {
 	if resp.Result == nil {
 		t.Error("unexpected nil no result")
 	}
+
+	body, err = json.Marshal(transferMoneyRequest{
+		AccountFrom: "7b6c7d2a-3f0e-4d8e-9a51-1f3c2b4a5d60",
+		AccountTo:   "2c1d9e8f-6a5b-4c3d-8e7f-0a1b2c3d4e5f",
+		Amount:      0.01,
+		ExternalID:  paymentrequest.ExternalID("3f2e1d0c-9b8a-4765-8c4d-3e2f1a0b9c8d"),
+	})
+	if err != nil {
+		t.Fatal("unexpected marshal error")
+	}
+	response, err = http.Post(server.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal("unexpected error on request")
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected reserved external id rejected, got %d", response.StatusCode)
+	}
+}
+
+func TestTransferMoney_IdempotencyKey(t *testing.T) {
+	server := httptest.NewServer(MakePaymentEndpoints(payment.New(&dummyStorage{}), log.NewNopLogger(),
+		WithVersion(V2)))
+	defer server.Close()
+
+	body := `{"account_from":"` + dummyUUID + `","account_to":"2c1d9e8f-6a5b-4c3d-8e7f-0a1b2c3d4e5f","amount":"1.00"}`
+	for _, c := range []struct {
+		key        string
+		status     int
+		externalID string
+	}{
+		{"", http.StatusOK, ""},
+		{"key", http.StatusOK, "key"},
+		{strings.Repeat("k", 65), http.StatusBadRequest, ""},
+	} {
+		request, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(body))
+		if err != nil {
+			t.Fatal("unexpected error on make request")
+		}
+		request.Header.Set(IdempotencyKeyHeader, c.key)
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal("unexpected error on request")
+		}
+
+		resp := struct {
+			Data struct {
+				ExternalID string `json:"external_id"`
+			} `json:"data"`
+		}{}
+		err = json.NewDecoder(response.Body).Decode(&resp)
+		response.Body.Close()
+		if err != nil || response.StatusCode != c.status || resp.Data.ExternalID != c.externalID {
+			t.Errorf("unexpected response of key %q: %d %+v", c.key, response.StatusCode, resp.Data)
+		}
+	}
+}
+
+func TestListPayments_Search(t *testing.T) {
+	server := httptest.NewServer(MakePaymentEndpoints(payment.New(&dummyStorage{}), log.NewNopLogger()))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "?q=+rent+july+&category=bills")
+	if err != nil {
+		t.Fatal("unexpected error on request")
+	}
+	defer response.Body.Close()
+
+	var payments []*payment.Payment
+	resp := schemaResponse{Result: &payments}
+	if err = json.NewDecoder(response.Body).Decode(&resp); err != nil {
+		t.Fatal("error on decode response")
+	}
+	if len(payments) != 1 || payments[0].Description != "rent july" || payments[0].Category != "bills" {
+		t.Errorf("unexpected payments %v", payments)
+	}
+
+	for _, query := range []string{"?category=dummy", "?q=" + strings.Repeat("a", 141)} {
+		response, err = http.Get(server.URL + query)
+		if err != nil {
+			t.Fatal("unexpected error on request")
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected %s rejected, got %d", query, response.StatusCode)
+		}
+	}
 }
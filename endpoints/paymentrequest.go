@@ -0,0 +1,167 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-kit/kit/endpoint"
+	kitlog "github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/sbutakov/wallet/pkg/paymentrequest"
+	"github.com/sbutakov/wallet/pkg/validation"
+)
+
+type paymentRequestCreateRequest struct {
+	PayeeAccountID string    `json:"payee_account_id" validate:"required,uuid"`
+	PayerAccountID string    `json:"payer_account_id" validate:"required,max=254"`
+	Amount         float64   `json:"amount" validate:"precision=2"`
+	Currency       string    `json:"currency" validate:"required,currency"`
+	Memo           string    `json:"memo" validate:"max=140"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+type paymentRequestRequest struct {
+	ID string `json:"id" validate:"uuid"`
+}
+
+type paymentRequestFilterRequest struct {
+	AccountID string `json:"account_id" validate:"uuid"`
+	Status    string `json:"status" validate:"max=10"`
+}
+
+// PaymentRequestService interface for requesting money and accepting, declining
+// and canceling payment requests
+type PaymentRequestService interface {
+	Create(ctx context.Context, payeeAccountID, payerAccountID string, amount float64, currency string,
+		details paymentrequest.Details) (*paymentrequest.Request, error)
+	Get(ctx context.Context, id string) (*paymentrequest.Request, error)
+	List(ctx context.Context, filter paymentrequest.Filter) ([]*paymentrequest.Request, error)
+	Accept(ctx context.Context, id string) (*paymentrequest.Request, error)
+	Decline(ctx context.Context, id string) (*paymentrequest.Request, error)
+	Cancel(ctx context.Context, id string) (*paymentrequest.Request, error)
+}
+
+// MakePaymentRequestEndpoints init router for handling create and view payment requests,
+// accept and decline them by payer and cancel them by payee
+func MakePaymentRequestEndpoints(service PaymentRequestService, logger kitlog.Logger, opts ...Option) http.Handler {
+	o := newOptions(opts)
+	router := chi.NewRouter()
+	router.Method(http.MethodPost, "/", kithttp.NewServer(
+		o.endpoint("create_payment_request", createPaymentRequest(service)),
+		decodePaymentRequestCreateRequest,
+		encodePaymentRequestResponse,
+		o.server("create_payment_request",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodGet, "/", kithttp.NewServer(
+		o.endpoint("list_payment_requests", listPaymentRequests(service)),
+		decodeListPaymentRequestsRequest,
+		encodePaymentRequestResponse,
+		o.server("list_payment_requests",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodGet, "/{id}", kithttp.NewServer(
+		o.endpoint("get_payment_request", resolvePaymentRequest(service.Get)),
+		decodePaymentRequestRequest,
+		encodePaymentRequestResponse,
+		o.server("get_payment_request",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodPost, "/{id}/accept", kithttp.NewServer(
+		o.endpoint("accept_payment_request", resolvePaymentRequest(service.Accept)),
+		decodePaymentRequestRequest,
+		encodePaymentRequestResponse,
+		o.server("accept_payment_request",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodPost, "/{id}/decline", kithttp.NewServer(
+		o.endpoint("decline_payment_request", resolvePaymentRequest(service.Decline)),
+		decodePaymentRequestRequest,
+		encodePaymentRequestResponse,
+		o.server("decline_payment_request",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodPost, "/{id}/cancel", kithttp.NewServer(
+		o.endpoint("cancel_payment_request", resolvePaymentRequest(service.Cancel)),
+		decodePaymentRequestRequest,
+		encodePaymentRequestResponse,
+		o.server("cancel_payment_request",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	return router
+}
+
+func createPaymentRequest(service PaymentRequestService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(paymentRequestCreateRequest)
+		return service.Create(ctx, req.PayeeAccountID, req.PayerAccountID, req.Amount, req.Currency,
+			paymentrequest.Details{Memo: req.Memo, ExpiresAt: req.ExpiresAt})
+	}
+}
+
+func listPaymentRequests(service PaymentRequestService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return service.List(ctx, request.(paymentrequest.Filter))
+	}
+}
+
+// resolvePaymentRequest endpoint calling action on payment request identified by request
+func resolvePaymentRequest(
+	action func(ctx context.Context, id string) (*paymentrequest.Request, error)) endpoint.Endpoint {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return action(ctx, request.(paymentRequestRequest).ID)
+	}
+}
+
+func decodePaymentRequestCreateRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	if versionFromContext(ctx) == V2 {
+		req := paymentRequestCreateRequestV2{}
+		if err := decodeJSONRequest(r, &req); err != nil {
+			return nil, err
+		}
+		return req.endpointRequest(), nil
+	}
+
+	req := paymentRequestCreateRequest{}
+	if err := decodeJSONRequest(r, &req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodePaymentRequestRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := paymentRequestRequest{ID: chi.URLParam(r, "id")}
+	if err := validation.Validate(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func decodeListPaymentRequestsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+	req := paymentRequestFilterRequest{AccountID: query.Get("account_id"), Status: query.Get("status")}
+	if err := validation.Validate(req); err != nil {
+		return nil, err
+	}
+	return paymentrequest.Filter{AccountID: req.AccountID, Status: req.Status}, nil
+}
+
+func encodePaymentRequestResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return encodeResult(ctx, w, response)
+}
@@ -0,0 +1,124 @@
+package endpoints
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/sbutakov/wallet/pkg/payment"
+	"github.com/sbutakov/wallet/pkg/paymentrequest"
+)
+
+func (d *dummyStorage) CreatePaymentRequest(
+	_ context.Context, req *paymentrequest.Request) (*paymentrequest.Request, error) {
+
+	req.ID = dummyUUID
+	req.Status = paymentrequest.StatusPending
+	return req, nil
+}
+
+func (d *dummyStorage) GetPaymentRequest(_ context.Context, id string) (*paymentrequest.Request, error) {
+	return &paymentrequest.Request{ID: id, PayeeAccountID: dummyUUID, PayerAccountID: "2c1d9e8f-6a5b-4c3d-8e7f-0a1b2c3d4e5f",
+		Amount: 10.5, Currency: "usd", Status: paymentrequest.StatusPending, ExpiresAt: time.Now().Add(time.Hour)}, nil
+}
+
+func (d *dummyStorage) ListPaymentRequests(
+	_ context.Context, _ string, filter paymentrequest.Filter) ([]*paymentrequest.Request, error) {
+
+	return []*paymentrequest.Request{{ID: dummyUUID, PayeeAccountID: filter.AccountID, Amount: 10.5,
+		Currency: "usd", Status: paymentrequest.StatusPending}}, nil
+}
+
+func (d *dummyStorage) AcceptPaymentRequest(ctx context.Context, id string,
+	pay func(context.Context, *paymentrequest.Request) error) (*paymentrequest.Request, error) {
+
+	req, err := d.GetPaymentRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = pay(ctx, req); err != nil {
+		return nil, err
+	}
+	return d.ResolvePaymentRequest(ctx, id, paymentrequest.StatusAccepted)
+}
+
+func (d *dummyStorage) ResolvePaymentRequest(
+	ctx context.Context, id, status string) (*paymentrequest.Request, error) {
+
+	req, err := d.GetPaymentRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	req.Status = status
+	return req, nil
+}
+
+func (d *dummyStorage) ExpirePaymentRequests(context.Context) (int64, error) {
+	return 0, nil
+}
+
+func TestMakePaymentRequestEndpoints(t *testing.T) {
+	config := paymentrequest.Config{DefaultExpiry: time.Hour, MaxExpiry: 24 * time.Hour}
+	service := paymentrequest.New(config, &dummyStorage{}, payment.New(&dummyStorage{}))
+	server := httptest.NewServer(MakePaymentRequestEndpoints(service, log.NewNopLogger(), WithVersion(V2)))
+	defer server.Close()
+
+	do := func(method, path, body string, result interface{}) int {
+		request, err := http.NewRequest(method, server.URL+path, bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatal("unexpected error on make request")
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal("unexpected error on request")
+		}
+		defer response.Body.Close()
+		if result != nil && response.StatusCode == http.StatusOK {
+			if err = json.NewDecoder(response.Body).Decode(&schemaResponseV2{Data: result}); err != nil {
+				t.Fatal("error on decode response")
+			}
+		}
+		return response.StatusCode
+	}
+
+	created := map[string]interface{}{}
+	body := `{"payee_account_id":"` + dummyUUID + `","payer_account_id":"2c1d9e8f-6a5b-4c3d-8e7f-0a1b2c3d4e5f",` +
+		`"amount":"10.50","currency":"USD","memo":"dinner"}`
+	if do(http.MethodPost, "/", body, &created) != http.StatusOK || created["amount"] != "10.50" ||
+		created["currency"] != "usd" || created["status"] != paymentrequest.StatusPending {
+		t.Errorf("unexpected created payment request %v", created)
+	}
+
+	body = `{"payee_account_id":"` + dummyUUID + `","payer_account_id":"2c1d9e8f-6a5b-4c3d-8e7f-0a1b2c3d4e5f",` +
+		`"amount":"10.50","currency":"usd","expires_at":"2000-01-01T00:00:00Z"}`
+	if code := do(http.MethodPost, "/", body, nil); code != http.StatusBadRequest {
+		t.Errorf("expected expiry in the past rejected, got %d", code)
+	}
+
+	var requests []map[string]interface{}
+	if do(http.MethodGet, "/?account_id="+dummyUUID, "", &requests) != http.StatusOK || len(requests) != 1 ||
+		requests[0]["payee_account_id"] != dummyUUID {
+		t.Errorf("unexpected payment requests %v", requests)
+	}
+	if code := do(http.MethodGet, "/?status=dummy", "", nil); code != http.StatusBadRequest {
+		t.Errorf("expected unknown status rejected, got %d", code)
+	}
+
+	for action, status := range map[string]string{
+		"accept":  paymentrequest.StatusAccepted,
+		"decline": paymentrequest.StatusDeclined,
+		"cancel":  paymentrequest.StatusCanceled,
+	} {
+		resolved := map[string]interface{}{}
+		if do(http.MethodPost, "/"+dummyUUID+"/"+action, "", &resolved) != http.StatusOK ||
+			resolved["status"] != status {
+			t.Errorf("unexpected payment request after %s %v", action, resolved)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-kit/kit/endpoint"
+	kitlog "github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/closing"
+)
+
+type periodCloseRequest struct {
+	Until string `json:"until" validate:"required,date"`
+}
+
+// PeriodService interface for closing periods
+type PeriodService interface {
+	ClosePeriod(ctx context.Context, until time.Time) (*closing.Closing, error)
+	Closings(ctx context.Context) ([]*closing.Closing, error)
+}
+
+// MakePeriodEndpoints init router for handling close periods and view closings,
+// period is closed until first open day
+func MakePeriodEndpoints(service PeriodService, logger kitlog.Logger, opts ...Option) http.Handler {
+	o := newOptions(opts)
+	router := chi.NewRouter()
+	router.Method(http.MethodPost, "/", kithttp.NewServer(
+		o.endpoint("close_period", closePeriod(service)),
+		decodePeriodCloseRequest,
+		encodePeriodResponse,
+		o.server("close_period",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodGet, "/", kithttp.NewServer(
+		o.endpoint("list_period_closings", listPeriodClosings(service)),
+		kithttp.NopRequestDecoder,
+		encodePeriodResponse,
+		o.server("list_period_closings",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	return router
+}
+
+func closePeriod(service PeriodService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return service.ClosePeriod(ctx, request.(time.Time))
+	}
+}
+
+func listPeriodClosings(service PeriodService) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return service.Closings(ctx)
+	}
+}
+
+func decodePeriodCloseRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := periodCloseRequest{}
+	if err := decodeJSONRequest(r, &req); err != nil {
+		return nil, err
+	}
+	until, err := closing.ParseDay(req.Until)
+	if err != nil {
+		return nil, errors.Wrap(ErrorMalformedRequest, "until must be date")
+	}
+	return until, nil
+}
+
+func encodePeriodResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return encodeResult(ctx, w, response)
+}
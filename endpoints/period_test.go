@@ -0,0 +1,67 @@
+package endpoints
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/sbutakov/wallet/pkg/closing"
+)
+
+func (d *dummyStorage) LastSnapshotDay(context.Context) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func (d *dummyStorage) TakeSnapshots(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (d *dummyStorage) VerifySnapshots(context.Context, time.Time, time.Time) (*closing.VerifyResult, error) {
+	return &closing.VerifyResult{}, nil
+}
+
+func (d *dummyStorage) ClosePeriod(_ context.Context, until time.Time) (*closing.Closing, error) {
+	return &closing.Closing{ClosedUntil: until.Format(closing.DayLayout), ClosedAt: time.Now(), Actor: "dummy"}, nil
+}
+
+func (d *dummyStorage) ListClosings(context.Context) ([]*closing.Closing, error) {
+	return []*closing.Closing{}, nil
+}
+
+func TestMakePeriodEndpoints(t *testing.T) {
+	service := closing.New(closing.Config{}, &dummyStorage{})
+	server := httptest.NewServer(MakePeriodEndpoints(service, log.NewNopLogger(), WithVersion(V2)))
+	defer server.Close()
+
+	post := func(body string) (int, map[string]interface{}) {
+		response, err := http.Post(server.URL, "application/json", bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatal("unexpected error on request")
+		}
+		defer response.Body.Close()
+		result := map[string]interface{}{}
+		if response.StatusCode == http.StatusOK {
+			if err = json.NewDecoder(response.Body).Decode(&schemaResponseV2{Data: &result}); err != nil {
+				t.Fatal("error on decode response")
+			}
+		}
+		return response.StatusCode, result
+	}
+
+	if status, result := post(`{"until":"2026-09-01"}`); status != http.StatusOK || result["closed_until"] != "2026-09-01" {
+		t.Errorf("unexpected closing %d %v", status, result)
+	}
+
+	tomorrow := time.Now().UTC().AddDate(0, 0, 2).Format(closing.DayLayout)
+	for _, body := range []string{`{}`, `{"until":"01.09.2026"}`, `{"until":"2026-13-01"}`, `{"until":"` + tomorrow + `"}`} {
+		if status, _ := post(body); status != http.StatusBadRequest {
+			t.Errorf("expected %s rejected, got %d", body, status)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-kit/kit/endpoint"
+	kitlog "github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/sbutakov/wallet/pkg/metadata"
+	"github.com/sbutakov/wallet/pkg/projection"
+)
+
+// ProjectionService interface for viewing and rebuilding projections
+type ProjectionService interface {
+	Status(ctx context.Context) ([]*projection.Status, error)
+	Rebuild(ctx context.Context, name string) (*projection.Status, error)
+	AccountSummaries(ctx context.Context, filter metadata.Filter) ([]*projection.AccountSummary, error)
+}
+
+// MakeProjectionEndpoints init router for handling view lag of projections, rebuild projection
+// and view summaries of accounts
+func MakeProjectionEndpoints(service ProjectionService, logger kitlog.Logger, opts ...Option) http.Handler {
+	o := newOptions(opts)
+	router := chi.NewRouter()
+	router.Method(http.MethodGet, "/", kithttp.NewServer(
+		o.endpoint("list_projections", listProjections(service)),
+		kithttp.NopRequestDecoder,
+		encodeProjectionResponse,
+		o.server("list_projections",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodPost, "/{name}/rebuild", kithttp.NewServer(
+		o.endpoint("rebuild_projection", rebuildProjection(service)),
+		decodeRebuildProjectionRequest,
+		encodeProjectionResponse,
+		o.server("rebuild_projection",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodGet, "/account_summaries", kithttp.NewServer(
+		o.endpoint("list_account_summaries", listAccountSummaries(service)),
+		decodeListAccountSummariesRequest,
+		encodeProjectionResponse,
+		o.server("list_account_summaries",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	return router
+}
+
+func listProjections(service ProjectionService) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return service.Status(ctx)
+	}
+}
+
+func rebuildProjection(service ProjectionService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return service.Rebuild(ctx, request.(string))
+	}
+}
+
+func listAccountSummaries(service ProjectionService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return service.AccountSummaries(ctx, request.(metadata.Filter))
+	}
+}
+
+func decodeRebuildProjectionRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return chi.URLParam(r, "name"), nil
+}
+
+func decodeListAccountSummariesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return decodeMetadataFilter(r)
+}
+
+func encodeProjectionResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return encodeResult(ctx, w, response)
+}
@@ -0,0 +1,84 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/sbutakov/wallet/pkg/metadata"
+	"github.com/sbutakov/wallet/pkg/projection"
+)
+
+func (d *dummyStorage) ApplyEvents(context.Context, string, int) (int, error) {
+	return 0, nil
+}
+
+func (d *dummyStorage) RebuildProjection(context.Context, string) error {
+	return nil
+}
+
+func (d *dummyStorage) ProjectionStatus(context.Context) ([]*projection.Status, error) {
+	statuses := make([]*projection.Status, len(projection.Names))
+	for i, name := range projection.Names {
+		statuses[i] = &projection.Status{Name: name, Position: 1, LastEventID: 3, Lag: 2, UpdatedAt: time.Now()}
+	}
+	return statuses, nil
+}
+
+func (d *dummyStorage) ListAccountSummaries(
+	_ context.Context, customerID string, filter metadata.Filter) ([]*projection.AccountSummary, error) {
+
+	return []*projection.AccountSummary{{ID: dummyUUID, CustomerID: customerID, ExternalID: filter.ExternalID,
+		Currency: "usd", Balance: 10.5, Incoming: 1, Received: 10.5}}, nil
+}
+
+func TestMakeProjectionEndpoints(t *testing.T) {
+	service := projection.New(projection.Config{}, &dummyStorage{})
+	server := httptest.NewServer(MakeProjectionEndpoints(service, log.NewNopLogger(), WithVersion(V2)))
+	defer server.Close()
+
+	do := func(method, path string, result interface{}) int {
+		request, err := http.NewRequest(method, server.URL+path, nil)
+		if err != nil {
+			t.Fatal("unexpected error on make request")
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal("unexpected error on request")
+		}
+		defer response.Body.Close()
+		if result != nil && response.StatusCode == http.StatusOK {
+			if err = json.NewDecoder(response.Body).Decode(&schemaResponseV2{Data: result}); err != nil {
+				t.Fatal("error on decode response")
+			}
+		}
+		return response.StatusCode
+	}
+
+	var statuses []*projection.Status
+	if do(http.MethodGet, "/", &statuses) != http.StatusOK || len(statuses) != len(projection.Names) ||
+		statuses[0].Lag != 2 {
+		t.Errorf("unexpected statuses %v", statuses)
+	}
+
+	status := &projection.Status{}
+	if do(http.MethodPost, "/account_counters/rebuild", status) != http.StatusOK ||
+		status.Name != projection.AccountCounters {
+		t.Errorf("unexpected status of rebuilt projection %v", status)
+	}
+	if code := do(http.MethodPost, "/dummy/rebuild", nil); code != http.StatusNotFound {
+		t.Errorf("expected unknown projection not found, got %d", code)
+	}
+
+	var summaries []map[string]interface{}
+	if do(http.MethodGet, "/account_summaries?external_id=c-1", &summaries) != http.StatusOK ||
+		len(summaries) != 1 || summaries[0]["external_id"] != "c-1" || summaries[0]["received"] != "10.50" ||
+		summaries[0]["last_activity_at"] != nil {
+		t.Errorf("unexpected summaries %v", summaries)
+	}
+}
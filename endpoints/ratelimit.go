@@ -0,0 +1,56 @@
+package endpoints
+
+import (
+	"context"
+	"net"
+
+	"github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"google.golang.org/grpc/peer"
+
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/ratelimit"
+)
+
+// WithRateLimit reject calls of clients exceeding rate limits, clients are identified by principal
+// or by remote address when authentication is disabled, transfers from accounts are limited
+// by payment.NewRateLimitingService
+func WithRateLimit(limiter *ratelimit.Limiter) Option {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, func(string) endpoint.Middleware {
+			return rateLimitMiddleware(limiter)
+		})
+		o.serverOptions = append(o.serverOptions, func(string) kithttp.ServerOption {
+			return kithttp.ServerBefore(kithttp.PopulateRequestContext)
+		})
+	}
+}
+
+func rateLimitMiddleware(limiter *ratelimit.Limiter) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if client := rateLimitClient(ctx); client != "" {
+				if err := limiter.AllowClient(ctx, client); err != nil {
+					return nil, err
+				}
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// rateLimitClient identify client by principal or by remote address of request or gRPC call
+func rateLimitClient(ctx context.Context) string {
+	if principal := auth.PrincipalFromContext(ctx); principal != nil {
+		return principal.Method + ":" + principal.ID
+	}
+
+	addr, _ := ctx.Value(kithttp.ContextKeyRequestRemoteAddr).(string)
+	if p, ok := peer.FromContext(ctx); ok {
+		addr = p.Addr.String()
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return "addr:" + host
+	}
+	return ""
+}
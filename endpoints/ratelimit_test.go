@@ -0,0 +1,61 @@
+package endpoints
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/payment"
+	"github.com/sbutakov/wallet/pkg/ratelimit"
+)
+
+func TestWithRateLimit(t *testing.T) {
+	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	limiter := ratelimit.New(ratelimit.Config{
+		ClientRate:   100,
+		ClientBurst:  100,
+		AccountRate:  0.01,
+		AccountBurst: 1,
+	}, ratelimit.NewMemory())
+	server := httptest.NewServer(MakePaymentEndpoints(
+		payment.NewRateLimitingService(limiter, payment.New(&dummyStorage{})), logger, WithRateLimit(limiter)))
+	defer server.Close()
+
+	transfer := func(from string) *http.Response {
+		body, _ := json.Marshal(transferMoneyRequest{ // nolint: errcheck
+			AccountFrom: from,
+			AccountTo:   "2c1d9e8f-6a5b-4c3d-8e7f-0a1b2c3d4e5f",
+			Amount:      1,
+		})
+		response, err := http.Post(server.URL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal("unexpected error on request")
+		}
+		return response
+	}
+
+	if response := transfer(dummyUUID); response.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status %d of first transfer", response.StatusCode)
+	}
+
+	response := transfer(dummyUUID)
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusTooManyRequests || response.Header.Get("Retry-After") != "100" {
+		t.Errorf("expected transfer rate limited, got status %d retry after %q",
+			response.StatusCode, response.Header.Get("Retry-After"))
+	}
+	problem := apperror.Problem{}
+	if err := json.NewDecoder(response.Body).Decode(&problem); err != nil || problem.Code != "rate_limited" {
+		t.Errorf("unexpected problem %v", problem)
+	}
+
+	if response = transfer("3e2d1c0b-7a6f-4e5d-9c8b-1a2b3c4d5e6f"); response.StatusCode != http.StatusOK {
+		t.Errorf("expected transfer from other account allowed, got status %d", response.StatusCode)
+	}
+}
@@ -0,0 +1,125 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/go-kit/kit/endpoint"
+	kitlog "github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/reporting"
+)
+
+// ReportService interface for viewing reports
+type ReportService interface {
+	Balances(ctx context.Context) ([]*reporting.CurrencyBalance, error)
+	DailyVolume(ctx context.Context, period reporting.Period) ([]*reporting.DailyVolume, error)
+	TopAccounts(ctx context.Context, query reporting.TopQuery) ([]*reporting.TopAccount, error)
+}
+
+// MakeReportEndpoints init router for handling view reports, periods of reports are
+// set by from and to days
+func MakeReportEndpoints(service ReportService, logger kitlog.Logger, opts ...Option) http.Handler {
+	o := newOptions(opts)
+	router := chi.NewRouter()
+	router.Method(http.MethodGet, "/balances", kithttp.NewServer(
+		o.endpoint("report_balances", reportBalances(service)),
+		kithttp.NopRequestDecoder,
+		encodeReportResponse,
+		o.server("report_balances",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodGet, "/daily_volume", kithttp.NewServer(
+		o.endpoint("report_daily_volume", reportDailyVolume(service)),
+		decodeReportPeriodRequest,
+		encodeReportResponse,
+		o.server("report_daily_volume",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	router.Method(http.MethodGet, "/top_accounts", kithttp.NewServer(
+		o.endpoint("report_top_accounts", reportTopAccounts(service)),
+		decodeReportTopAccountsRequest,
+		encodeReportResponse,
+		o.server("report_top_accounts",
+			kithttp.ServerErrorLogger(logger),
+			kithttp.ServerErrorEncoder(encodeError),
+		)...))
+
+	return router
+}
+
+func reportBalances(service ReportService) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return service.Balances(ctx)
+	}
+}
+
+func reportDailyVolume(service ReportService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return service.DailyVolume(ctx, request.(reporting.Period))
+	}
+}
+
+func reportTopAccounts(service ReportService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		return service.TopAccounts(ctx, request.(reporting.TopQuery))
+	}
+}
+
+func decodeReportPeriodRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return decodeReportPeriod(r)
+}
+
+// decodeReportPeriod decode period of report from query params from and to, last day is exclusive
+func decodeReportPeriod(r *http.Request) (reporting.Period, error) {
+	period := reporting.Period{}
+	query := r.URL.Query()
+	var err error
+	if from := query.Get("from"); from != "" {
+		if period.From, err = reporting.ParseDay(from); err != nil {
+			return period, errors.Wrap(ErrorBadQuery, "from must be day")
+		}
+	}
+	if to := query.Get("to"); to != "" {
+		if period.To, err = reporting.ParseDay(to); err != nil {
+			return period, errors.Wrap(ErrorBadQuery, "to must be day")
+		}
+	}
+	return period, nil
+}
+
+func decodeReportTopAccountsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	period, err := decodeReportPeriod(r)
+	if err != nil {
+		return nil, err
+	}
+
+	params := r.URL.Query()
+	query := reporting.TopQuery{
+		Period:    period,
+		Direction: params.Get("direction"),
+		Currency:  strings.ToLower(params.Get("currency")),
+	}
+	if query.Direction == "" {
+		query.Direction = reporting.DirectionSenders
+	}
+	if limit := params.Get("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			return nil, ErrorBadQuery
+		}
+	}
+	return query, nil
+}
+
+func encodeReportResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return encodeResult(ctx, w, response)
+}
@@ -0,0 +1,87 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/sbutakov/wallet/pkg/reporting"
+)
+
+func (d *dummyStorage) BalancesByCurrency(context.Context, bool) ([]*reporting.CurrencyBalance, error) {
+	return []*reporting.CurrencyBalance{{Currency: "usd", Accounts: 2, Total: 100.5}}, nil
+}
+
+func (d *dummyStorage) DailyVolume(
+	_ context.Context, period reporting.Period, _ bool) ([]*reporting.DailyVolume, error) {
+
+	day := period.From.Format(reporting.DayLayout)
+	return []*reporting.DailyVolume{{Day: day, Currency: "usd", Transfers: 3, Volume: 10.25}}, nil
+}
+
+func (d *dummyStorage) TopAccounts(
+	_ context.Context, query reporting.TopQuery, _ bool) ([]*reporting.TopAccount, error) {
+
+	return []*reporting.TopAccount{
+		{AccountID: dummyUUID, Name: query.Direction, Currency: query.Currency, Transfers: int64(query.Limit)},
+	}, nil
+}
+
+func (d *dummyStorage) RefreshReports(context.Context) error {
+	return nil
+}
+
+func TestMakeReportEndpoints(t *testing.T) {
+	service := reporting.New(reporting.Config{}, &dummyStorage{})
+	server := httptest.NewServer(MakeReportEndpoints(service, log.NewNopLogger(), WithVersion(V2)))
+	defer server.Close()
+
+	get := func(path string, result interface{}) int {
+		response, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal("unexpected error on request")
+		}
+		defer response.Body.Close()
+		if result != nil && response.StatusCode == http.StatusOK {
+			if err = json.NewDecoder(response.Body).Decode(&schemaResponseV2{Data: result}); err != nil {
+				t.Fatal("error on decode response")
+			}
+		}
+		return response.StatusCode
+	}
+
+	var balances []map[string]interface{}
+	if get("/balances", &balances) != http.StatusOK || len(balances) != 1 || balances[0]["total"] != "100.50" {
+		t.Errorf("unexpected balances %v", balances)
+	}
+
+	var volumes []map[string]interface{}
+	status := get("/daily_volume?from=2026-09-01&to=2026-10-01", &volumes)
+	if status != http.StatusOK || len(volumes) != 1 || volumes[0]["day"] != "2026-09-01" ||
+		volumes[0]["volume"] != "10.25" {
+		t.Errorf("unexpected daily volume %v", volumes)
+	}
+
+	var top []map[string]interface{}
+	if get("/top_accounts?direction=receivers&currency=USD&limit=5", &top) != http.StatusOK || len(top) != 1 ||
+		top[0]["name"] != reporting.DirectionReceivers || top[0]["currency"] != "usd" || top[0]["transfers"] != 5.0 {
+		t.Errorf("unexpected top accounts %v", top)
+	}
+
+	for _, path := range []string{
+		"/daily_volume?from=2026-09",
+		"/daily_volume?from=2026-10-01&to=2026-09-01",
+		"/daily_volume?from=2025-01-01&to=" + time.Now().Format(reporting.DayLayout),
+		"/top_accounts?direction=dummy",
+		"/top_accounts?limit=abc",
+	} {
+		if status = get(path, nil); status != http.StatusBadRequest {
+			t.Errorf("expected %s rejected, got %d", path, status)
+		}
+	}
+}
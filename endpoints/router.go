@@ -0,0 +1,69 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+// MountRoutes mount probes of health service and routes of API mounted by api for every version
+// under version prefix, routes without version prefix are kept as deprecated aliases of V1,
+// middlewares (e.g. authentication) are applied to routes of API only and probes are served
+// without them, middlewares of router must be set before mounting routes
+func MountRoutes(router chi.Router, config Config, health HealthService, api func(chi.Router, Version),
+	logger kitlog.Logger, middlewares ...func(http.Handler) http.Handler) {
+
+	router.Mount("/healthz", MakeLivenessEndpoints(health, logger))
+	router.Mount("/readyz", MakeReadinessEndpoints(health, logger))
+
+	v1Deprecated := Deprecated(V2, config.V1Deprecated, config.V1Sunset)
+	router.Group(func(router chi.Router) {
+		router.Use(middlewares...)
+		router.Group(func(router chi.Router) {
+			router.Use(v1Deprecated)
+			api(router, V1)
+			router.Method(http.MethodGet, "/openapi.json", MakeOpenAPIEndpoint(V1))
+		})
+		for _, version := range Versions {
+			versioned := chi.NewRouter()
+			if version == V1 {
+				versioned.Use(v1Deprecated)
+			}
+			api(versioned, version)
+			versioned.Method(http.MethodGet, "/openapi.json", MakeOpenAPIEndpoint(version))
+			router.Mount(version.Prefix(), versioned)
+		}
+	})
+}
+
+// Services services of API routes
+type Services struct {
+	Accounts        AccountService
+	Statements      StatementService
+	Imports         ImportService
+	Customers       CustomerService
+	Payments        PaymentService
+	APIKeys         APIKeyService
+	Audit           AuditService
+	Reports         ReportService
+	Periods         PeriodService
+	Projections     ProjectionService
+	Aliases         AliasService
+	PaymentRequests PaymentRequestService
+}
+
+// MountAPI mount routes of every service of API, routes must be described by OpenAPI document
+func MountAPI(router chi.Router, services Services, logger kitlog.Logger, opts ...Option) {
+	router.Mount("/accounts", MakeAccountEndpoints(
+		services.Accounts, services.Statements, services.Imports, logger, opts...))
+	router.Mount("/customers", MakeCustomerEndpoints(services.Customers, services.Accounts, logger, opts...))
+	router.Mount("/payments", MakePaymentEndpoints(services.Payments, logger, opts...))
+	router.Mount("/apikeys", MakeAPIKeyEndpoints(services.APIKeys, logger, opts...))
+	router.Mount("/audit", MakeAuditEndpoints(services.Audit, logger, opts...))
+	router.Mount("/reports", MakeReportEndpoints(services.Reports, logger, opts...))
+	router.Mount("/periods", MakePeriodEndpoints(services.Periods, logger, opts...))
+	router.Mount("/projections", MakeProjectionEndpoints(services.Projections, logger, opts...))
+	router.Mount("/aliases", MakeAliasEndpoints(services.Aliases, logger, opts...))
+	router.Mount("/payment_requests", MakePaymentRequestEndpoints(services.PaymentRequests, logger, opts...))
+}
@@ -0,0 +1,71 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/go-kit/kit/log"
+
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/health"
+)
+
+type dummyAuthenticator struct {
+}
+
+func (d *dummyAuthenticator) Authenticate(_ context.Context, r *http.Request) (*auth.Principal, error) {
+	switch r.Header.Get(auth.APIKeyHeader) {
+	case "":
+		return nil, nil
+	case "dummy":
+		return &auth.Principal{ID: "dummy", Scopes: []string{auth.ScopeAccountsRead}}, nil
+	}
+	return nil, auth.ErrorInvalidCredentials
+}
+
+func TestMountRoutes(t *testing.T) {
+	services := makeOpenAPIServices(t)
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler { return next })
+	MountRoutes(router, Config{}, health.New(health.Config{}), func(router chi.Router, v Version) {
+		MountAPI(router, services, log.NewNopLogger(), WithVersion(v), WithAuthorization())
+	}, log.NewNopLogger(), auth.Middleware(&dummyAuthenticator{}))
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	for _, c := range []struct {
+		path, key string
+		status    int
+	}{
+		{"/healthz", "", http.StatusOK},
+		{"/readyz", "invalid", http.StatusOK},
+		{"/v2/openapi.json", "", http.StatusOK},
+		{"/v2/accounts", "", http.StatusUnauthorized},
+		{"/v1/accounts", "", http.StatusUnauthorized},
+		{"/accounts", "", http.StatusUnauthorized},
+		{"/v2/accounts", "invalid", http.StatusUnauthorized},
+		{"/v2/accounts", "dummy", http.StatusOK},
+		{"/accounts", "dummy", http.StatusOK},
+		{"/v2/payments", "dummy", http.StatusForbidden},
+	} {
+		request, err := http.NewRequest(http.MethodGet, server.URL+c.path, nil)
+		if err != nil {
+			t.Fatal("unexpected error on make request")
+		}
+		if c.key != "" {
+			request.Header.Set(auth.APIKeyHeader, c.key)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal("unexpected error on request")
+		}
+		response.Body.Close()
+		if response.StatusCode != c.status {
+			t.Errorf("expected status %d of %s with key %q, got %d", c.status, c.path, c.key, response.StatusCode)
+		}
+	}
+}
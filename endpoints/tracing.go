@@ -0,0 +1,49 @@
+package endpoints
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/sbutakov/wallet/pkg/tracing"
+)
+
+// WithTracing record span of every endpoint call, continues trace of traceparent request header
+// or metadata of gRPC call
+func WithTracing(tracer *tracing.Tracer) Option {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, func(name string) endpoint.Middleware {
+			return tracingMiddleware(tracer, name)
+		})
+		o.serverOptions = append(o.serverOptions, func(string) kithttp.ServerOption {
+			return kithttp.ServerBefore(traceParentToContext)
+		})
+		o.grpcServerOptions = append(o.grpcServerOptions, func(string) kitgrpc.ServerOption {
+			return kitgrpc.ServerBefore(grpcTraceParentToContext)
+		})
+	}
+}
+
+func tracingMiddleware(tracer *tracing.Tracer, name string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			span, ctx := tracer.StartSpan(ctx, "endpoint."+name)
+			defer func() {
+				span.SetError(err)
+				span.Finish()
+			}()
+			return next(ctx, request)
+		}
+	}
+}
+
+func traceParentToContext(ctx context.Context, r *http.Request) context.Context {
+	sc, err := tracing.ParseTraceParent(r.Header.Get(tracing.TraceParentHeader))
+	if err != nil {
+		return ctx
+	}
+	return tracing.ContextWithRemoteParent(ctx, sc)
+}
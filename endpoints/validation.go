@@ -0,0 +1,38 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/validation"
+)
+
+// maxRequestBodySize limit of request body size in bytes
+const maxRequestBodySize = 1 << 20
+
+// ErrorRequestTooLarge request body exceeds limit
+var ErrorRequestTooLarge = apperror.New(apperror.KindTooLarge, "request_too_large", "request body is too large")
+
+// decodeJSONRequest decode request body into req rejecting oversized bodies, unknown fields
+// and trailing data, then validate req by rules of its tags
+func decodeJSONRequest(r *http.Request, req interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxRequestBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(req); err != nil {
+		if _, ok := err.(*http.MaxBytesError); ok {
+			return ErrorRequestTooLarge
+		}
+		return malformedRequest(err)
+	}
+	if err := decoder.Decode(&struct{}{}); err != io.EOF {
+		if _, ok := err.(*http.MaxBytesError); ok {
+			return ErrorRequestTooLarge
+		}
+		return malformedRequest(errors.New("unexpected data after json object"))
+	}
+	return validation.Validate(req)
+}
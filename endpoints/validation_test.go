@@ -0,0 +1,68 @@
+package endpoints
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/payment"
+	"github.com/sbutakov/wallet/pkg/validation"
+)
+
+func TestDecodeJSONRequest(t *testing.T) {
+	var logger log.Logger
+	logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	logger = log.With(logger, "wallet", log.DefaultTimestampUTC)
+
+	server := httptest.NewServer(MakePaymentEndpoints(payment.New(&dummyStorage{}), logger))
+	cases := []struct {
+		body   string
+		status int
+		code   string
+		fields []string
+	}{
+		{`{"account_from":"dummy","account_to":"` + dummyUUID + `","amount":1.005}`,
+			http.StatusBadRequest, validation.CodeValidationFailed, []string{"account_from", "amount"}},
+		{`{"account_to":"` + dummyUUID + `","amount":1}`,
+			http.StatusBadRequest, validation.CodeValidationFailed, []string{"account_from"}},
+		{`{"account_from":"` + dummyUUID + `","account_to":"` + dummyUUID + `","amount":1,"fee":1}`,
+			http.StatusBadRequest, "malformed_request", nil},
+		{`{"account_from":"` + dummyUUID + `"} {}`, http.StatusBadRequest, "malformed_request", nil},
+		{`{"account_from":"` + dummyUUID + `"}}`, http.StatusBadRequest, "malformed_request", nil},
+		{`{"account_from":"` + dummyUUID + `"} x`, http.StatusBadRequest, "malformed_request", nil},
+		{`{"account_from":"` + strings.Repeat("a", maxRequestBodySize) + `"}`,
+			http.StatusRequestEntityTooLarge, "request_too_large", nil},
+	}
+
+	for _, c := range cases {
+		response, err := http.Post(server.URL, "application/json", bytes.NewReader([]byte(c.body)))
+		if err != nil {
+			t.Fatal("unexpected error on request")
+		}
+
+		problem := apperror.Problem{}
+		if err = json.NewDecoder(response.Body).Decode(&problem); err != nil {
+			t.Fatal("error on decode problem")
+		}
+
+		if response.StatusCode != c.status || problem.Code != c.code {
+			t.Errorf("unexpected problem %+v", problem)
+		}
+
+		if len(problem.InvalidParams) != len(c.fields) {
+			t.Fatalf("unexpected invalid params %+v", problem.InvalidParams)
+		}
+		for i, field := range c.fields {
+			if problem.InvalidParams[i].Field != field {
+				t.Errorf("expected invalid param %s", field)
+			}
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/metadata"
+	"github.com/sbutakov/wallet/pkg/payment"
+	"github.com/sbutakov/wallet/pkg/paymentrequest"
+	"github.com/sbutakov/wallet/pkg/projection"
+	"github.com/sbutakov/wallet/pkg/reporting"
+	"github.com/sbutakov/wallet/pkg/statement"
+)
+
+// Version version of HTTP API, versions share services and differ only by
+// encoding of requests and results
+type Version int
+
+const (
+	// V1 results are wrapped in {"result", "error"} envelope, money amounts are numbers
+	V1 Version = 1
+	// V2 results are wrapped in {"data"} envelope, money amounts are decimal strings
+	V2 Version = 2
+)
+
+// Versions supported versions of API, latest is last
+var Versions = []Version{V1, V2}
+
+// Prefix path prefix of routes of version
+func (v Version) Prefix() string {
+	return "/v" + strconv.Itoa(int(v))
+}
+
+// Config versions of API configuration
+type Config struct {
+	// V1Deprecated date since V1 is deprecated in favour of V2
+	V1Deprecated time.Time `default:"2026-10-19T00:00:00Z"`
+	// V1Sunset date after which V1 may be removed, not announced when empty
+	V1Sunset time.Time
+}
+
+// WithVersion encode requests and results of endpoints by rules of version, V1 is default
+func WithVersion(v Version) Option {
+	return func(o *options) {
+		o.serverOptions = append(o.serverOptions, func(string) kithttp.ServerOption {
+			return kithttp.ServerBefore(func(ctx context.Context, _ *http.Request) context.Context {
+				return context.WithValue(ctx, contextKeyVersion, v)
+			})
+		})
+	}
+}
+
+// Deprecated middleware announce deprecation of version by Deprecation and Sunset headers,
+// same route of successor version is linked
+func Deprecated(successor Version, since, sunset time.Time) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := r.URL.Path
+			for _, v := range Versions {
+				if strings.HasPrefix(path, v.Prefix()+"/") {
+					path = strings.TrimPrefix(path, v.Prefix())
+					break
+				}
+			}
+
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(since.Unix(), 10))
+			if !sunset.IsZero() {
+				w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			w.Header().Add("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, successor.Prefix(), path))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func versionFromContext(ctx context.Context) Version {
+	if v, ok := ctx.Value(contextKeyVersion).(Version); ok {
+		return v
+	}
+	return V1
+}
+
+type schemaResponseV2 struct {
+	Data interface{} `json:"data"`
+}
+
+// encodeResult write result in envelope of version of request
+func encodeResult(ctx context.Context, w http.ResponseWriter, result interface{}) error {
+	w.Header().Set("Content-Type", contentTypeJSON)
+	return json.NewEncoder(w).Encode(versionFromContext(ctx).envelope(result))
+}
+
+func (v Version) envelope(result interface{}) interface{} {
+	if v == V2 {
+		return schemaResponseV2{Data: v.result(result)}
+	}
+	return schemaResponse{Result: result}
+}
+
+// result convert result of endpoint into its representation in version
+func (v Version) result(result interface{}) interface{} {
+	if v != V2 {
+		return result
+	}
+
+	switch r := result.(type) {
+	case *account.Account:
+		return newAccountV2(r)
+	case []*account.Account:
+		accounts := make([]*accountV2, len(r))
+		for i, acc := range r {
+			accounts[i] = newAccountV2(acc)
+		}
+		return accounts
+	case *payment.Payment:
+		return newPaymentV2(r)
+	case []*payment.Payment:
+		payments := make([]*paymentV2, len(r))
+		for i, pay := range r {
+			payments[i] = newPaymentV2(pay)
+		}
+		return payments
+	case *statement.Balance:
+		return newBalanceV2(r)
+	case []*statement.Balance:
+		balances := make([]*balanceV2, len(r))
+		for i, balance := range r {
+			balances[i] = newBalanceV2(balance)
+		}
+		return balances
+	case []*reporting.CurrencyBalance:
+		balances := make([]*currencyBalanceV2, len(r))
+		for i, balance := range r {
+			balances[i] = &currencyBalanceV2{
+				Currency: balance.Currency, Accounts: balance.Accounts, Total: decimal(balance.Total)}
+		}
+		return balances
+	case []*reporting.DailyVolume:
+		volumes := make([]*dailyVolumeV2, len(r))
+		for i, volume := range r {
+			volumes[i] = &dailyVolumeV2{Day: volume.Day, Currency: volume.Currency,
+				Transfers: volume.Transfers, Volume: decimal(volume.Volume)}
+		}
+		return volumes
+	case []*reporting.TopAccount:
+		accounts := make([]*topAccountV2, len(r))
+		for i, acc := range r {
+			accounts[i] = &topAccountV2{AccountID: acc.AccountID, Name: acc.Name, Currency: acc.Currency,
+				Transfers: acc.Transfers, Volume: decimal(acc.Volume)}
+		}
+		return accounts
+	case []*projection.AccountSummary:
+		summaries := make([]*accountSummaryV2, len(r))
+		for i, summary := range r {
+			summaries[i] = newAccountSummaryV2(summary)
+		}
+		return summaries
+	case *paymentrequest.Request:
+		return newPaymentRequestV2(r)
+	case []*paymentrequest.Request:
+		requests := make([]*paymentRequestV2, len(r))
+		for i, req := range r {
+			requests[i] = newPaymentRequestV2(req)
+		}
+		return requests
+	}
+	return result
+}
+
+// request return zero request of version decoded instead of request of endpoint
+func (v Version) request(request interface{}) interface{} {
+	if v != V2 {
+		return request
+	}
+
+	switch request.(type) {
+	case accountCreateRequest:
+		return accountCreateRequestV2{}
+	case transferMoneyRequest:
+		return transferMoneyRequestV2{}
+	case paymentRequestCreateRequest:
+		return paymentRequestCreateRequestV2{}
+	}
+	return request
+}
+
+// decimalPattern decimal string of money amount
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// decimal money amount encoded as decimal string with two fraction digits
+type decimal float64
+
+func (d decimal) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(strconv.FormatFloat(float64(d), 'f', 2, 64))), nil
+}
+
+func (d *decimal) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil || !decimalPattern.MatchString(value) {
+		return errors.Errorf("amount %s is not decimal string", data)
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return errors.Errorf("amount %s is out of range", data)
+	}
+	*d = decimal(f)
+	return nil
+}
+
+type accountV2 struct {
+	ID         string            `json:"id"`
+	CustomerID string            `json:"customer_id,omitempty"`
+	Name       string            `json:"name"`
+	Currency   string            `json:"currency"`
+	Balance    decimal           `json:"balance"`
+	ExternalID string            `json:"external_id,omitempty"`
+	Metadata   metadata.Metadata `json:"metadata,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+func newAccountV2(acc *account.Account) *accountV2 {
+	return &accountV2{
+		ID:         acc.ID,
+		CustomerID: acc.CustomerID,
+		Name:       acc.Name,
+		Currency:   acc.Currency,
+		Balance:    decimal(acc.Balance),
+		ExternalID: acc.ExternalID,
+		Metadata:   acc.Metadata,
+		CreatedAt:  acc.CreatedAt,
+	}
+}
+
+type paymentV2 struct {
+	ID          string            `json:"id"`
+	Amount      decimal           `json:"amount"`
+	Currency    string            `json:"currency"`
+	AccountTo   string            `json:"account_to"`
+	AccountFrom string            `json:"account_from"`
+	Direction   string            `json:"direction"`
+	ExternalID  string            `json:"external_id,omitempty"`
+	Metadata    metadata.Metadata `json:"metadata,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Category    string            `json:"category,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+func newPaymentV2(pay *payment.Payment) *paymentV2 {
+	return &paymentV2{
+		ID:          pay.ID,
+		Amount:      decimal(pay.Amount),
+		Currency:    pay.Currency,
+		AccountTo:   pay.AccountTo,
+		AccountFrom: pay.AccountFrom,
+		Direction:   pay.Direction,
+		ExternalID:  pay.ExternalID,
+		Metadata:    pay.Metadata,
+		Description: pay.Description,
+		Category:    pay.Category,
+		CreatedAt:   pay.CreatedAt,
+	}
+}
+
+type balanceV2 struct {
+	AccountID string    `json:"account_id"`
+	Currency  string    `json:"currency"`
+	Balance   decimal   `json:"balance"`
+	AsOf      time.Time `json:"as_of"`
+}
+
+func newBalanceV2(balance *statement.Balance) *balanceV2 {
+	return &balanceV2{
+		AccountID: balance.AccountID,
+		Currency:  balance.Currency,
+		Balance:   decimal(balance.Balance),
+		AsOf:      balance.AsOf,
+	}
+}
+
+type currencyBalanceV2 struct {
+	Currency string  `json:"currency"`
+	Accounts int64   `json:"accounts"`
+	Total    decimal `json:"total"`
+}
+
+type dailyVolumeV2 struct {
+	Day       string  `json:"day"`
+	Currency  string  `json:"currency"`
+	Transfers int64   `json:"transfers"`
+	Volume    decimal `json:"volume"`
+}
+
+type topAccountV2 struct {
+	AccountID string  `json:"account_id"`
+	Name      string  `json:"name"`
+	Currency  string  `json:"currency"`
+	Transfers int64   `json:"transfers"`
+	Volume    decimal `json:"volume"`
+}
+
+type accountSummaryV2 struct {
+	ID             string            `json:"id"`
+	CustomerID     string            `json:"customer_id,omitempty"`
+	Name           string            `json:"name"`
+	Currency       string            `json:"currency"`
+	Balance        decimal           `json:"balance"`
+	ExternalID     string            `json:"external_id,omitempty"`
+	Metadata       metadata.Metadata `json:"metadata,omitempty"`
+	Incoming       int64             `json:"incoming"`
+	Outgoing       int64             `json:"outgoing"`
+	Received       decimal           `json:"received"`
+	Sent           decimal           `json:"sent"`
+	LastActivityAt *time.Time        `json:"last_activity_at"`
+	CreatedAt      time.Time         `json:"created_at"`
+}
+
+func newAccountSummaryV2(summary *projection.AccountSummary) *accountSummaryV2 {
+	return &accountSummaryV2{
+		ID:             summary.ID,
+		CustomerID:     summary.CustomerID,
+		Name:           summary.Name,
+		Currency:       summary.Currency,
+		Balance:        decimal(summary.Balance),
+		ExternalID:     summary.ExternalID,
+		Metadata:       summary.Metadata,
+		Incoming:       summary.Incoming,
+		Outgoing:       summary.Outgoing,
+		Received:       decimal(summary.Received),
+		Sent:           decimal(summary.Sent),
+		LastActivityAt: summary.LastActivityAt,
+		CreatedAt:      summary.CreatedAt,
+	}
+}
+
+type paymentRequestV2 struct {
+	ID             string    `json:"id"`
+	PayeeAccountID string    `json:"payee_account_id"`
+	PayerAccountID string    `json:"payer_account_id"`
+	Amount         decimal   `json:"amount"`
+	Currency       string    `json:"currency"`
+	Memo           string    `json:"memo,omitempty"`
+	Status         string    `json:"status"`
+	PaymentID      string    `json:"payment_id,omitempty"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func newPaymentRequestV2(req *paymentrequest.Request) *paymentRequestV2 {
+	return &paymentRequestV2{
+		ID:             req.ID,
+		PayeeAccountID: req.PayeeAccountID,
+		PayerAccountID: req.PayerAccountID,
+		Amount:         decimal(req.Amount),
+		Currency:       req.Currency,
+		Memo:           req.Memo,
+		Status:         req.Status,
+		PaymentID:      req.PaymentID,
+		ExpiresAt:      req.ExpiresAt,
+		CreatedAt:      req.CreatedAt,
+		UpdatedAt:      req.UpdatedAt,
+	}
+}
+
+type accountCreateRequestV2 struct {
+	CustomerID string            `json:"customer_id" validate:"uuid"`
+	Name       string            `json:"name" validate:"required,max=50,name"`
+	Balance    decimal           `json:"balance" validate:"precision=2"`
+	Currency   string            `json:"currency" validate:"required,currency"`
+	ExternalID string            `json:"external_id" validate:"max=64"`
+	Metadata   metadata.Metadata `json:"metadata" validate:"metadata"`
+}
+
+type transferMoneyRequestV2 struct {
+	AccountFrom string            `json:"account_from" validate:"required,uuid"`
+	AccountTo   string            `json:"account_to" validate:"required,max=254"`
+	Amount      decimal           `json:"amount" validate:"precision=2"`
+	ExternalID  string            `json:"external_id" validate:"max=64"`
+	Metadata    metadata.Metadata `json:"metadata" validate:"metadata"`
+	Description string            `json:"description" validate:"max=140"`
+	Category    string            `json:"category" validate:"max=20"`
+}
+
+type paymentRequestCreateRequestV2 struct {
+	PayeeAccountID string    `json:"payee_account_id" validate:"required,uuid"`
+	PayerAccountID string    `json:"payer_account_id" validate:"required,max=254"`
+	Amount         decimal   `json:"amount" validate:"precision=2"`
+	Currency       string    `json:"currency" validate:"required,currency"`
+	Memo           string    `json:"memo" validate:"max=140"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+func (r accountCreateRequestV2) endpointRequest() accountCreateRequest {
+	return accountCreateRequest{
+		CustomerID: r.CustomerID,
+		Name:       r.Name,
+		Balance:    float64(r.Balance),
+		Currency:   r.Currency,
+		ExternalID: r.ExternalID,
+		Metadata:   r.Metadata,
+	}
+}
+
+func (r transferMoneyRequestV2) endpointRequest() transferMoneyRequest {
+	return transferMoneyRequest{
+		AccountFrom: r.AccountFrom,
+		AccountTo:   r.AccountTo,
+		Amount:      float64(r.Amount),
+		ExternalID:  r.ExternalID,
+		Metadata:    r.Metadata,
+		Description: r.Description,
+		Category:    r.Category,
+	}
+}
+
+func (r paymentRequestCreateRequestV2) endpointRequest() paymentRequestCreateRequest {
+	return paymentRequestCreateRequest{
+		PayeeAccountID: r.PayeeAccountID,
+		PayerAccountID: r.PayerAccountID,
+		Amount:         float64(r.Amount),
+		Currency:       r.Currency,
+		Memo:           r.Memo,
+		ExpiresAt:      r.ExpiresAt,
+	}
+}
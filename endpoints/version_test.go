@@ -0,0 +1,79 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/sbutakov/wallet/pkg/account"
+)
+
+func TestWithVersion(t *testing.T) {
+	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	service, err := account.New(account.Config{AllowedCurrency: []string{"usd"}}, &dummyStorage{})
+	if err != nil {
+		t.Fatal("unexpected error on init account service")
+	}
+
+	server := httptest.NewServer(MakeAccountEndpoints(service, nil, nil, logger, WithVersion(V2)))
+	defer server.Close()
+
+	response, err := http.Post(server.URL, "application/json",
+		strings.NewReader(`{"name":"dummy","currency":"usd","balance":"100.50"}`))
+	if err != nil {
+		t.Fatal("unexpected error on request")
+	}
+	defer response.Body.Close()
+
+	resp := map[string]map[string]interface{}{}
+	if err = json.NewDecoder(response.Body).Decode(&resp); err != nil {
+		t.Fatal("error on decode response")
+	}
+	if _, ok := resp["data"]["balance"].(string); !ok || response.StatusCode != http.StatusOK {
+		t.Errorf("expected balance as decimal string in data, got %v", resp)
+	}
+
+	for _, body := range []string{
+		`{"name":"dummy","currency":"usd","balance":100.5}`,
+		`{"name":"dummy","currency":"usd","balance":"1e3"}`,
+	} {
+		response, err = http.Post(server.URL, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal("unexpected error on request")
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected amount %s rejected, got status %d", body, response.StatusCode)
+		}
+	}
+}
+
+func TestDeprecated(t *testing.T) {
+	since := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	sunset := since.AddDate(1, 0, 0)
+	handler := Deprecated(V2, since, sunset)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	for path, successor := range map[string]string{
+		"/v1/accounts/": "</v2/accounts/>; rel=\"successor-version\"",
+		"/payments/":    "</v2/payments/>; rel=\"successor-version\"",
+	} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if recorder.Header().Get("Deprecation") != "@1792368000" {
+			t.Errorf("unexpected deprecation header %q", recorder.Header().Get("Deprecation"))
+		}
+		if recorder.Header().Get("Sunset") != "Tue, 19 Oct 2027 00:00:00 GMT" {
+			t.Errorf("unexpected sunset header %q", recorder.Header().Get("Sunset"))
+		}
+		if recorder.Header().Get("Link") != successor {
+			t.Errorf("unexpected link header %q of %s", recorder.Header().Get("Link"), path)
+		}
+	}
+}
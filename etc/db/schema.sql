@@ -5,14 +5,46 @@ BEGIN
     END IF;
 END $$;
 
+CREATE TABLE IF NOT EXISTS customers (
+    id         UUID         NOT NULL PRIMARY KEY,
+    name       VARCHAR(50)  NOT NULL,
+    email      VARCHAR(254) UNIQUE,
+    created_at TIMESTAMP    WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
 CREATE TABLE IF NOT EXISTS accounts (
-    id         UUID        NOT NULL PRIMARY KEY,
-    name       VARCHAR(50) NOT NULL,
-    currency   VARCHAR(3)  NOT NULL,
-    balance    NUMERIC     NOT NULL,
-    created_at TIMESTAMP   WITH TIME ZONE NOT NULL DEFAULT NOW()
+    id          UUID        NOT NULL PRIMARY KEY,
+    customer_id UUID        REFERENCES customers(id),
+    name        VARCHAR(50) NOT NULL,
+    currency    VARCHAR(3)  NOT NULL,
+    balance     NUMERIC     NOT NULL,
+    created_at  TIMESTAMP   WITH TIME ZONE NOT NULL DEFAULT NOW()
 );
 
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+                   WHERE table_name = 'accounts' AND column_name = 'customer_id') THEN
+        ALTER TABLE accounts ADD COLUMN customer_id UUID REFERENCES customers(id);
+    END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS accounts_customer_id_idx ON accounts(customer_id);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+                   WHERE table_name = 'accounts' AND column_name = 'external_id') THEN
+        ALTER TABLE accounts ADD COLUMN external_id VARCHAR(64) UNIQUE;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+                   WHERE table_name = 'accounts' AND column_name = 'metadata') THEN
+        ALTER TABLE accounts ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}';
+    END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS accounts_metadata_idx ON accounts USING GIN (metadata jsonb_path_ops);
+
 CREATE TABLE IF NOT EXISTS payments (
     id         UUID              NOT NULL PRIMARY KEY,
     account    UUID              REFERENCES accounts(id),
@@ -21,3 +53,274 @@ CREATE TABLE IF NOT EXISTS payments (
     direction  payment_direction NOT NULL,
     created_at TIMESTAMP        WITH TIME ZONE NOT NULL DEFAULT NOW()
 );
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+                   WHERE table_name = 'payments' AND column_name = 'external_id') THEN
+        ALTER TABLE payments ADD COLUMN external_id VARCHAR(64);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+                   WHERE table_name = 'payments' AND column_name = 'metadata') THEN
+        ALTER TABLE payments ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}';
+    END IF;
+END $$;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+                   WHERE table_name = 'payments' AND column_name = 'description') THEN
+        ALTER TABLE payments ADD COLUMN description VARCHAR(140);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+                   WHERE table_name = 'payments' AND column_name = 'category') THEN
+        ALTER TABLE payments ADD COLUMN category VARCHAR(20);
+    END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS payments_description_idx ON payments USING GIN (to_tsvector('simple', description));
+CREATE INDEX IF NOT EXISTS payments_category_idx ON payments(category);
+
+CREATE UNIQUE INDEX IF NOT EXISTS payments_external_id_idx ON payments(external_id) WHERE direction = 'outgoing';
+CREATE INDEX IF NOT EXISTS payments_metadata_idx ON payments USING GIN (metadata jsonb_path_ops);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id          UUID        NOT NULL PRIMARY KEY,
+    customer_id UUID        REFERENCES customers(id),
+    name        VARCHAR(50) NOT NULL,
+    key_hash    CHAR(64)    NOT NULL UNIQUE,
+    scopes      TEXT[]      NOT NULL,
+    created_at  TIMESTAMP   WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    revoked_at  TIMESTAMP   WITH TIME ZONE
+);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+                   WHERE table_name = 'api_keys' AND column_name = 'customer_id') THEN
+        ALTER TABLE api_keys ADD COLUMN customer_id UUID REFERENCES customers(id);
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id          BIGSERIAL   NOT NULL PRIMARY KEY,
+    occurred_at TIMESTAMP   WITH TIME ZONE NOT NULL,
+    actor       TEXT        NOT NULL,
+    request_id  TEXT,
+    action      VARCHAR(50) NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id   TEXT        NOT NULL,
+    before      JSON,
+    after       JSON,
+    prev_hash   TEXT        NOT NULL,
+    hash        TEXT        NOT NULL UNIQUE
+);
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_name = 'audit_log' AND column_name = 'prev_hash' AND data_type = 'character') THEN
+        ALTER TABLE audit_log ALTER COLUMN prev_hash TYPE TEXT, ALTER COLUMN hash TYPE TEXT;
+    END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS audit_log_entity_idx ON audit_log(entity_type, entity_id);
+
+CREATE OR REPLACE FUNCTION audit_log_immutable() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_immutable ON audit_log;
+CREATE TRIGGER audit_log_immutable BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE PROCEDURE audit_log_immutable();
+
+CREATE TABLE IF NOT EXISTS rate_limits (
+    key        TEXT             NOT NULL PRIMARY KEY,
+    tokens     DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP        WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS payments_account_created_at_idx ON payments(account, created_at);
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS report_currency_balances AS
+    SELECT currency, COUNT(*) AS accounts, COALESCE(SUM(balance), 0) AS total FROM accounts GROUP BY currency;
+
+CREATE UNIQUE INDEX IF NOT EXISTS report_currency_balances_idx ON report_currency_balances(currency);
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS report_daily_account_volume AS
+    SELECT (created_at AT TIME ZONE 'UTC')::date AS day, account, direction,
+           COUNT(*) AS transfers, SUM(amount) AS volume
+    FROM payments GROUP BY day, account, direction;
+
+CREATE UNIQUE INDEX IF NOT EXISTS report_daily_account_volume_idx
+    ON report_daily_account_volume(day, account, direction);
+
+CREATE INDEX IF NOT EXISTS payments_created_at_idx ON payments(created_at);
+
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+    account_id UUID      NOT NULL REFERENCES accounts(id),
+    day        DATE      NOT NULL,
+    as_of      TIMESTAMP WITH TIME ZONE NOT NULL,
+    balance    NUMERIC   NOT NULL,
+    PRIMARY KEY (account_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS balance_snapshots_day_idx ON balance_snapshots(day);
+
+CREATE TABLE IF NOT EXISTS period_closings (
+    closed_until DATE      NOT NULL PRIMARY KEY,
+    closed_at    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    actor        TEXT      NOT NULL
+);
+
+CREATE OR REPLACE FUNCTION closed_until() RETURNS TIMESTAMP WITH TIME ZONE AS $$
+    SELECT MAX(closed_until)::timestamp AT TIME ZONE 'UTC' FROM period_closings;
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION payments_period_open() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        IF OLD.created_at < closed_until() THEN
+            RAISE EXCEPTION 'payment falls into closed period' USING ERRCODE = 'WL001';
+        END IF;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        IF NEW.created_at < closed_until() THEN
+            RAISE EXCEPTION 'payment falls into closed period' USING ERRCODE = 'WL001';
+        END IF;
+        RETURN NEW;
+    END IF;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS payments_period_open ON payments;
+CREATE TRIGGER payments_period_open BEFORE INSERT OR UPDATE OR DELETE ON payments
+    FOR EACH ROW EXECUTE PROCEDURE payments_period_open();
+
+CREATE OR REPLACE FUNCTION balance_snapshots_period_open() RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.as_of <= closed_until() THEN
+        RAISE EXCEPTION 'snapshot of closed period is immutable' USING ERRCODE = 'WL001';
+    END IF;
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS balance_snapshots_period_open ON balance_snapshots;
+CREATE TRIGGER balance_snapshots_period_open BEFORE UPDATE OR DELETE ON balance_snapshots
+    FOR EACH ROW EXECUTE PROCEDURE balance_snapshots_period_open();
+
+CREATE TABLE IF NOT EXISTS events (
+    id          BIGSERIAL   NOT NULL PRIMARY KEY,
+    type        VARCHAR(50) NOT NULL,
+    entity_id   TEXT        NOT NULL,
+    payload     JSONB       NOT NULL,
+    occurred_at TIMESTAMP   WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- events of accounts and payments stored before events were recorded, account is created
+-- with balance preceding all its payments
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM events) THEN
+        INSERT INTO events(type, entity_id, payload, occurred_at)
+        SELECT type, entity_id, payload, occurred_at FROM (
+            SELECT 'account.created' AS type, a.id::text AS entity_id, json_build_object(
+                       'id', a.id, 'customer_id', a.customer_id, 'name', a.name, 'currency', a.currency,
+                       'balance', a.balance - COALESCE((SELECT SUM(CASE WHEN p.direction = 'outgoing'
+                                                                   THEN -p.amount ELSE p.amount END)
+                                                        FROM payments p WHERE p.account = a.id), 0),
+                       'external_id', a.external_id, 'metadata', a.metadata, 'created_at', a.created_at
+                   )::jsonb AS payload, a.created_at AS occurred_at, 0 AS priority
+            FROM accounts a
+            UNION ALL
+            SELECT 'payment.transferred', p.id::text, json_build_object(
+                       'id', p.id, 'account_from', p.account, 'account_to', p.account_to, 'amount', p.amount,
+                       'currency', a.currency, 'direction', p.direction, 'created_at', p.created_at
+                   )::jsonb, p.created_at, 1
+            FROM payments p JOIN accounts a ON a.id = p.account WHERE p.direction = 'outgoing'
+        ) e ORDER BY occurred_at, priority;
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS projections (
+    name       VARCHAR(50) NOT NULL PRIMARY KEY,
+    position   BIGINT      NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP   WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO projections(name) VALUES ('account_summaries'), ('account_counters'), ('account_activity')
+    ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS account_summaries (
+    id          UUID        NOT NULL PRIMARY KEY,
+    customer_id UUID,
+    name        VARCHAR(50) NOT NULL,
+    currency    VARCHAR(3)  NOT NULL,
+    balance     NUMERIC     NOT NULL,
+    external_id VARCHAR(64),
+    metadata    JSONB       NOT NULL DEFAULT '{}',
+    created_at  TIMESTAMP   WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS account_summaries_customer_id_idx ON account_summaries(customer_id);
+CREATE INDEX IF NOT EXISTS account_summaries_metadata_idx ON account_summaries USING GIN (metadata jsonb_path_ops);
+
+CREATE TABLE IF NOT EXISTS account_counters (
+    account_id UUID    NOT NULL PRIMARY KEY,
+    incoming   BIGINT  NOT NULL,
+    outgoing   BIGINT  NOT NULL,
+    received   NUMERIC NOT NULL,
+    sent       NUMERIC NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS account_activity (
+    account_id       UUID NOT NULL PRIMARY KEY,
+    last_payment_id  UUID NOT NULL,
+    last_activity_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS aliases (
+    id          UUID         NOT NULL PRIMARY KEY,
+    kind        VARCHAR(10)  NOT NULL,
+    value       VARCHAR(254) NOT NULL UNIQUE,
+    customer_id UUID         REFERENCES customers(id),
+    verified_at TIMESTAMP    WITH TIME ZONE,
+    created_at  TIMESTAMP    WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS aliases_customer_id_idx ON aliases(customer_id);
+
+CREATE TABLE IF NOT EXISTS alias_accounts (
+    alias_id   UUID       NOT NULL REFERENCES aliases(id) ON DELETE CASCADE,
+    account_id UUID       NOT NULL REFERENCES accounts(id),
+    currency   VARCHAR(3) NOT NULL,
+    is_default BOOLEAN    NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (alias_id, account_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS alias_accounts_default_idx ON alias_accounts(alias_id, currency) WHERE is_default;
+
+CREATE TABLE IF NOT EXISTS payment_requests (
+    id            UUID         NOT NULL PRIMARY KEY,
+    payee_account UUID         NOT NULL REFERENCES accounts(id),
+    payer_account UUID         NOT NULL REFERENCES accounts(id),
+    amount        NUMERIC      NOT NULL CHECK (amount > 0),
+    currency      VARCHAR(3)   NOT NULL,
+    memo          VARCHAR(140),
+    status        VARCHAR(10)  NOT NULL DEFAULT 'pending',
+    payment_id    UUID         REFERENCES payments(id),
+    expires_at    TIMESTAMP    WITH TIME ZONE NOT NULL,
+    created_at    TIMESTAMP    WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMP    WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS payment_requests_payee_account_idx ON payment_requests(payee_account);
+CREATE INDEX IF NOT EXISTS payment_requests_payer_account_idx ON payment_requests(payer_account);
+CREATE INDEX IF NOT EXISTS payment_requests_pending_idx ON payment_requests(expires_at) WHERE status = 'pending';
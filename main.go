@@ -1,20 +1,38 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/go-chi/chi"
-	klog "github.com/go-kit/kit/log"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
 
 	"github.com/sbutakov/wallet/config"
 	"github.com/sbutakov/wallet/endpoints"
 	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/alias"
+	"github.com/sbutakov/wallet/pkg/audit"
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/closing"
+	"github.com/sbutakov/wallet/pkg/customer"
+	"github.com/sbutakov/wallet/pkg/health"
+	"github.com/sbutakov/wallet/pkg/importer"
+	"github.com/sbutakov/wallet/pkg/logging"
+	"github.com/sbutakov/wallet/pkg/metrics"
 	"github.com/sbutakov/wallet/pkg/payment"
+	"github.com/sbutakov/wallet/pkg/paymentrequest"
 	"github.com/sbutakov/wallet/pkg/postgres"
+	"github.com/sbutakov/wallet/pkg/projection"
+	"github.com/sbutakov/wallet/pkg/ratelimit"
+	"github.com/sbutakov/wallet/pkg/reporting"
+	"github.com/sbutakov/wallet/pkg/statement"
+	"github.com/sbutakov/wallet/pkg/tracing"
 )
 
 var (
@@ -34,9 +52,7 @@ func main() {
 	zerolog.MessageFieldName = "message"
 	zerolog.LevelFieldName = "level"
 	zerolog.TimestampFieldName = "@timestamp"
-	zerolog.SetGlobalLevel(zerolog.ErrorLevel)
 
-	kitlog := klog.NewLogfmtLogger(log.Logger)
 	cfg, err := config.LoadConfigFromEnv()
 	if err != nil {
 		log.Panic().
@@ -44,6 +60,23 @@ func main() {
 			Msg("error on load config from env")
 	}
 
+	level, err := cfg.Log.ParseLevel()
+	if err != nil {
+		log.Panic().
+			Err(err).
+			Msg("error on init logging")
+	}
+	zerolog.SetGlobalLevel(level)
+	kitlog := logging.NewKitLogger(&log.Logger)
+
+	exporter, err := tracing.NewExporter(cfg.Tracing)
+	if err != nil {
+		log.Panic().
+			Err(err).
+			Msg("error on init tracing exporter")
+	}
+	tracer := tracing.New(exporter)
+
 	db, err := postgres.New(cfg.Postgres)
 	if err != nil {
 		log.Panic().
@@ -51,16 +84,183 @@ func main() {
 			Msg("error on connect to database server")
 	}
 
-	accountsService, err := account.New(cfg.Account, db)
+	db.SetTracer(tracer)
+	authService := auth.New(db)
+	auditService := audit.New(db)
+	statementService := statement.New(db)
+	closingService := closing.New(cfg.Closing, db)
+	projectionService := projection.New(cfg.Projection, db)
+	if len(os.Args) > 1 {
+		err := runCommand(os.Args[1:], authService, auditService, statementService, closingService,
+			projectionService, os.Stdout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var accountStorage account.Storage = db
+	if cfg.Projection.ServeAccounts {
+		accountStorage = db.AccountSummaries()
+	}
+	accountsService, err := account.New(cfg.Account, accountStorage)
 	if err != nil {
 		log.Panic().
 			Err(err).
 			Msg("error on init account service")
 	}
-	paymentService := payment.New(db)
+	registry := metrics.NewRegistry()
+	db.RegisterMetrics(registry)
+	instrumentation := endpoints.NewInstrumentation(registry)
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		var backend ratelimit.Backend
+		switch cfg.RateLimit.Backend {
+		case ratelimit.BackendMemory:
+			backend = ratelimit.NewMemory()
+		case ratelimit.BackendPostgres:
+			backend = db
+		default:
+			log.Panic().
+				Str("backend", cfg.RateLimit.Backend).
+				Msg("unknown rate limit backend")
+		}
+		limiter = ratelimit.New(cfg.RateLimit, backend)
+	}
+	var paymentService payment.Interface = payment.NewTracingService(tracer, payment.New(db))
+	if limiter != nil {
+		paymentService = payment.NewRateLimitingService(limiter, paymentService)
+	}
+	paymentService = payment.NewLoggingService(&log.Logger,
+		payment.NewInstrumentingService(registry, paymentService))
+	healthService := health.New(cfg.Health)
+	healthService.Register("postgres", health.CheckerFunc(db.Ping))
+	healthService.Register("schema", health.CheckerFunc(db.AssertSchema))
+	aliasService := alias.New(db)
+	paymentRequestService := paymentrequest.New(cfg.PaymentRequest, db, paymentService)
+	reportService := reporting.New(cfg.Reporting, db)
+	if cfg.Reporting.Cache {
+		heartbeat := health.NewHeartbeat(cfg.Reporting.RefreshInterval)
+		healthService.Register("reports", heartbeat)
+		go reportService.Run(context.Background(), heartbeat, func(err error) {
+			log.Error().
+				Err(err).
+				Msg("error on refresh reports")
+		})
+	}
+	if cfg.Closing.Snapshots {
+		heartbeat := health.NewHeartbeat(cfg.Closing.SnapshotInterval)
+		healthService.Register("snapshots", heartbeat)
+		go closingService.Run(context.Background(), heartbeat, func(err error) {
+			log.Error().
+				Err(err).
+				Msg("error on take balance snapshots")
+		})
+	}
+	if cfg.Projection.Enabled {
+		heartbeat := health.NewHeartbeat(cfg.Projection.Interval)
+		healthService.Register("projections", heartbeat)
+		go projectionService.Run(context.Background(), heartbeat, func(err error) {
+			log.Error().
+				Err(err).
+				Msg("error on apply events to projections")
+		})
+	}
+	if cfg.PaymentRequest.Sweeper {
+		heartbeat := health.NewHeartbeat(cfg.PaymentRequest.SweepInterval)
+		healthService.Register("payment_requests", heartbeat)
+		go paymentRequestService.Run(context.Background(), heartbeat, func(err error) {
+			log.Error().
+				Err(err).
+				Msg("error on expire payment requests")
+		})
+	}
+
+	options := []endpoints.Option{
+		endpoints.WithInstrumentation(instrumentation),
+		endpoints.WithTracing(tracer),
+	}
+	var apiMiddlewares []func(http.Handler) http.Handler
+	grpcInterceptors := []endpoints.GRPCInterceptor{endpoints.GRPCRequestID(&log.Logger)}
+	if cfg.Auth.Enabled {
+		authenticators := []auth.Authenticator{authService}
+		if cfg.Auth.JWT.Configured() {
+			keys, err := auth.NewKeySource(cfg.Auth.JWT)
+			if err != nil {
+				log.Panic().
+					Err(err).
+					Msg("error on load jwt keys")
+			}
+			authenticators = append(authenticators, auth.NewJWTAuthenticator(cfg.Auth.JWT, keys))
+		}
+		apiMiddlewares = append(apiMiddlewares, auth.Middleware(authenticators...))
+		grpcInterceptors = append(grpcInterceptors, endpoints.GRPCAuthentication(authenticators...))
+		options = append(options, endpoints.WithAuthorization())
+	}
+	if limiter != nil {
+		options = append(options, endpoints.WithRateLimit(limiter))
+	}
+
+	tracedAccountsService := account.NewTracingService(tracer, accountsService)
+	customerService := customer.New(db)
+	importService := importer.New(accountsService, db)
+	services := endpoints.Services{
+		Accounts:        tracedAccountsService,
+		Statements:      statementService,
+		Imports:         importService,
+		Customers:       customerService,
+		Payments:        paymentService,
+		APIKeys:         authService,
+		Audit:           auditService,
+		Reports:         reportService,
+		Periods:         closingService,
+		Projections:     projectionService,
+		Aliases:         aliasService,
+		PaymentRequests: paymentRequestService,
+	}
+	mountAPI := func(router chi.Router, version endpoints.Version) {
+		options := append(options[:len(options):len(options)], endpoints.WithVersion(version))
+		endpoints.MountAPI(router, services, kitlog, options...)
+	}
+
 	router := chi.NewRouter()
-	router.Mount("/accounts", endpoints.MakeAccountEndpoints(accountsService, kitlog))
-	router.Mount("/payments", endpoints.MakePaymentEndpoints(paymentService, kitlog))
+	router.Use(logging.RequestID(&log.Logger), logging.AccessLog(&log.Logger))
+	endpoints.MountRoutes(router, cfg.API, healthService, mountAPI, kitlog, apiMiddlewares...)
+
+	// metrics and traces are served only to operators on separate listener
+	if cfg.Service.AdminListenAddress != "" {
+		admin := chi.NewRouter()
+		admin.Method(http.MethodGet, "/metrics", registry.Handler())
+		if memory, ok := exporter.(*tracing.MemoryExporter); ok {
+			admin.Method(http.MethodGet, "/debug/traces", memory)
+		}
+		go func() {
+			if err := http.ListenAndServe(cfg.Service.AdminListenAddress, admin); err != nil {
+				log.Panic().
+					Err(err).
+					Msg("error on listen and serve admin")
+			}
+		}()
+	}
+	if cfg.Service.GRPCListenAddress != "" {
+		listener, err := net.Listen("tcp", cfg.Service.GRPCListenAddress)
+		if err != nil {
+			log.Panic().
+				Err(err).
+				Msg("error on listen grpc")
+		}
+		grpcServer := grpc.NewServer(endpoints.GRPCServerOptions(grpcInterceptors...)...)
+		endpoints.RegisterGRPCServices(grpcServer, tracedAccountsService, paymentService, kitlog, options...)
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Panic().
+					Err(err).
+					Msg("error on serve grpc")
+			}
+		}()
+	}
+
 	if err := http.ListenAndServe(cfg.Service.ListenAddress, router); err != nil {
 		log.Panic().
 			Err(err).
@@ -2,34 +2,54 @@
 package account
 
 import (
+	"context"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/customer"
+	"github.com/sbutakov/wallet/pkg/metadata"
 )
 
 var (
 	// ErrorNotFound account not found
-	ErrorNotFound = errors.New("account not found")
+	ErrorNotFound = apperror.New(apperror.KindNotFound, "account_not_found", "account not found")
 	// ErrorBalanceValue balance must be greater than zero
-	ErrorBalanceValue = errors.New("balance must be greater than zero")
+	ErrorBalanceValue = apperror.New(
+		apperror.KindInvalidArgument, "invalid_balance", "balance must be greater than zero")
 	// ErrorUnsupportedCurrency unsupported currency type
-	ErrorUnsupportedCurrency = errors.New("unsupported currency type")
+	ErrorUnsupportedCurrency = apperror.New(
+		apperror.KindInvalidArgument, "unsupported_currency", "unsupported currency type")
 )
 
 // Storage interface for creating and viewing account in database
 type Storage interface {
-	CreateAccount(name, currency string, balance float64) (*Account, error)
-	ListAccount() ([]*Account, error)
+	CreateAccount(ctx context.Context, customerID, name, currency string, balance float64,
+		externalID string, meta metadata.Metadata) (*Account, error)
+	ListAccount(ctx context.Context, filter metadata.Filter) ([]*Account, error)
+	ListCustomerAccounts(ctx context.Context, customerID string, filter metadata.Filter) ([]*Account, error)
 }
 
 // Account base type of package
 type Account struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Currency  string    `json:"currency"`
-	Balance   float64   `json:"balance"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         string            `json:"id"`
+	CustomerID string            `json:"customer_id,omitempty"`
+	Name       string            `json:"name"`
+	Currency   string            `json:"currency"`
+	Balance    float64           `json:"balance"`
+	ExternalID string            `json:"external_id,omitempty"`
+	Metadata   metadata.Metadata `json:"metadata,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// Interface methods of account service, implemented by Service and its decorators
+type Interface interface {
+	List(ctx context.Context, filter metadata.Filter) ([]*Account, error)
+	ListByCustomer(ctx context.Context, customerID string) ([]*Account, error)
+	Create(ctx context.Context, customerID, name, currency string, balance float64,
+		externalID string, meta metadata.Metadata) (*Account, error)
 }
 
 // Config configuration params of account service
@@ -60,25 +80,67 @@ func New(config Config, storage Storage) (*Service, error) {
 	}, nil
 }
 
-// Create create account and store in database
-func (s *Service) Create(name, currency string, balance float64) (*Account, error) {
-	if !contains(currency, s.currency) {
-		return nil, ErrorUnsupportedCurrency
-	}
-	if balance <= 0 {
-		return nil, ErrorBalanceValue
+// Create create account and store in database, account of customer principal is owned by it,
+// external identifier is optional and unique
+func (s *Service) Create(ctx context.Context, customerID, name, currency string, balance float64,
+	externalID string, meta metadata.Metadata) (*Account, error) {
+
+	customerID, err := s.Check(ctx, customerID, currency, balance)
+	if err != nil {
+		return nil, err
 	}
 
-	account, err := s.storage.CreateAccount(name, currency, balance)
+	account, err := s.storage.CreateAccount(ctx, customerID, name, currency, balance, externalID, meta)
 	if err != nil {
 		return nil, errors.Wrap(err, "error on create account")
 	}
 	return account, nil
 }
 
-// List view accounts stored in database
-func (s *Service) List() ([]*Account, error) {
-	return s.storage.ListAccount()
+// Check check account with currency and balance is allowed to be created by principal,
+// returns owner of account, customer principal owns accounts created without owner
+func (s *Service) Check(ctx context.Context, customerID, currency string, balance float64) (string, error) {
+	if principalCustomerID := customer.PrincipalCustomerID(ctx); customerID == "" {
+		customerID = principalCustomerID
+	}
+	if err := customer.Authorize(ctx, customerID); err != nil {
+		return "", err
+	}
+
+	if !contains(currency, s.currency) {
+		return "", ErrorUnsupportedCurrency
+	}
+	if balance <= 0 {
+		return "", ErrorBalanceValue
+	}
+	return customerID, nil
+}
+
+// List view accounts stored in database matching filter, customer principal views only owned accounts
+func (s *Service) List(ctx context.Context, filter metadata.Filter) ([]*Account, error) {
+	if customerID := customer.PrincipalCustomerID(ctx); customerID != "" {
+		return s.storage.ListCustomerAccounts(ctx, customerID, filter)
+	}
+	return s.storage.ListAccount(ctx, filter)
+}
+
+// ListByCustomer view accounts owned by customer
+func (s *Service) ListByCustomer(ctx context.Context, customerID string) ([]*Account, error) {
+	if err := customer.Authorize(ctx, customerID); err != nil {
+		return nil, err
+	}
+	return s.storage.ListCustomerAccounts(ctx, customerID, metadata.Filter{})
+}
+
+// Authorize check authenticated principal is allowed to act on behalf of account owner
+func Authorize(ctx context.Context, account *Account) error {
+	if customer.PrincipalCustomerID(ctx) == "" {
+		return nil
+	}
+	if account.CustomerID == "" {
+		return customer.ErrorNotOwner
+	}
+	return customer.Authorize(ctx, account.CustomerID)
 }
 
 func contains(str string, arr []string) bool {
@@ -1,15 +1,26 @@
 package account
 
-import "testing"
+import (
+	"context"
+	"testing"
+
+	"github.com/sbutakov/wallet/pkg/metadata"
+)
 
 type dummyStorage struct {
 }
 
-func (d *dummyStorage) CreateAccount(name, currency string, balance float64) (*Account, error) {
-	return &Account{}, nil
+func (d *dummyStorage) CreateAccount(_ context.Context, _, name, currency string, balance float64,
+	externalID string, meta metadata.Metadata) (*Account, error) {
+
+	return &Account{ExternalID: externalID, Metadata: meta}, nil
+}
+
+func (d *dummyStorage) ListAccount(context.Context, metadata.Filter) ([]*Account, error) {
+	return nil, nil
 }
 
-func (d *dummyStorage) ListAccount() ([]*Account, error) {
+func (d *dummyStorage) ListCustomerAccounts(context.Context, string, metadata.Filter) ([]*Account, error) {
 	return nil, nil
 }
 
@@ -31,17 +42,17 @@ func TestService_Create(t *testing.T) {
 		t.Fatal("unexpected nil pointer instance")
 	}
 
-	_, err = instance.Create("dummy", "usd", 1)
-	if err != nil {
+	acc, err := instance.Create(context.Background(), "", "dummy", "usd", 1, "order-1", metadata.Metadata{"a": "b"})
+	if err != nil || acc.ExternalID != "order-1" || acc.Metadata["a"] != "b" {
 		t.Error("unexpected error on create account")
 	}
 
-	_, err = instance.Create("dummy", "rub", 1)
+	_, err = instance.Create(context.Background(), "", "dummy", "rub", 1, "", nil)
 	if err != ErrorUnsupportedCurrency {
 		t.Error("error on check currency")
 	}
 
-	_, err = instance.Create("dummy", "eur", 0)
+	_, err = instance.Create(context.Background(), "", "dummy", "eur", 0, "", nil)
 	if err != ErrorBalanceValue {
 		t.Error("error on check balance")
 	}
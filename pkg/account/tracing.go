@@ -0,0 +1,54 @@
+package account
+
+import (
+	"context"
+
+	"github.com/sbutakov/wallet/pkg/metadata"
+	"github.com/sbutakov/wallet/pkg/tracing"
+)
+
+type tracingService struct {
+	tracer *tracing.Tracer
+	next   Interface
+}
+
+// NewTracingService decorate service with recording span of every method call
+func NewTracingService(tracer *tracing.Tracer, next Interface) Interface {
+	return &tracingService{
+		tracer: tracer,
+		next:   next,
+	}
+}
+
+func (s *tracingService) Create(ctx context.Context, customerID, name, currency string, balance float64,
+	externalID string, meta metadata.Metadata) (result *Account, err error) {
+
+	span, ctx := s.tracer.StartSpan(ctx, "account.Create")
+	span.SetAttribute("currency", currency)
+	defer func() {
+		span.SetError(err)
+		span.Finish()
+	}()
+	return s.next.Create(ctx, customerID, name, currency, balance, externalID, meta)
+}
+
+func (s *tracingService) List(ctx context.Context, filter metadata.Filter) (result []*Account, err error) {
+	span, ctx := s.tracer.StartSpan(ctx, "account.List")
+	defer func() {
+		span.SetError(err)
+		span.Finish()
+	}()
+	return s.next.List(ctx, filter)
+}
+
+func (s *tracingService) ListByCustomer(
+	ctx context.Context, customerID string) (result []*Account, err error) {
+
+	span, ctx := s.tracer.StartSpan(ctx, "account.ListByCustomer")
+	span.SetAttribute("customer_id", customerID)
+	defer func() {
+		span.SetError(err)
+		span.Finish()
+	}()
+	return s.next.ListByCustomer(ctx, customerID)
+}
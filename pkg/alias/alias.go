@@ -0,0 +1,255 @@
+// Package alias provides directory of aliases of accounts, alias is unique handle, phone number or email
+// resolved to default account of its owner in currency of transfer
+package alias
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/customer"
+)
+
+// kinds of aliases
+const (
+	KindHandle = "handle"
+	KindPhone  = "phone"
+	KindEmail  = "email"
+)
+
+var (
+	handlePattern = regexp.MustCompile(`^[a-z][a-z0-9_.]{2,29}$`)
+	phonePattern  = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+	emailPattern  = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	uuidPattern   = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+	// phoneSeparators characters of written phone numbers dropped by normalization
+	phoneSeparators = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "")
+)
+
+// MaxLength limit of alias, longest is email
+const MaxLength = 254
+
+var (
+	// ErrorInvalid alias is neither handle, phone number nor email
+	ErrorInvalid = apperror.New(apperror.KindInvalidArgument, "invalid_alias",
+		"alias must be handle of 3 to 30 letters, digits, _ and ., phone number in international format or email")
+	// ErrorNotFound alias not found
+	ErrorNotFound = apperror.New(apperror.KindNotFound, "alias_not_found", "alias not found")
+	// ErrorTaken alias belongs to another owner
+	ErrorTaken = apperror.New(apperror.KindConflict, "alias_taken", "alias is already taken")
+	// ErrorNotVerified phone number or email is not verified yet
+	ErrorNotVerified = apperror.New(apperror.KindFailedPrecondition, "alias_not_verified", "alias is not verified")
+	// ErrorNoAccount alias has no account in currency of transfer
+	ErrorNoAccount = apperror.New(
+		apperror.KindFailedPrecondition, "alias_no_account", "alias has no account in currency")
+	// ErrorForeignAccount account is owned by customer other than owner of alias
+	ErrorForeignAccount = apperror.New(
+		apperror.KindInvalidArgument, "alias_foreign_account", "account is not owned by owner of alias")
+)
+
+// Alias alias of accounts of customer, handles are verified on creation, phone numbers and emails
+// are verified by back office
+type Alias struct {
+	ID         string           `json:"id"`
+	Kind       string           `json:"kind"`
+	Value      string           `json:"value"`
+	CustomerID string           `json:"customer_id,omitempty"`
+	Verified   bool             `json:"verified"`
+	VerifiedAt *time.Time       `json:"verified_at"`
+	Accounts   []*LinkedAccount `json:"accounts"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
+
+// LinkedAccount account linked to alias, alias has at most one default account per currency
+type LinkedAccount struct {
+	AccountID string `json:"account_id"`
+	Currency  string `json:"currency"`
+	Default   bool   `json:"default"`
+}
+
+// Resolver interface for finding aliases by normalized value
+type Resolver interface {
+	FindAlias(ctx context.Context, value string) (*Alias, error)
+}
+
+// Storage interface for managing aliases, first linked account of currency becomes its default
+type Storage interface {
+	Resolver
+	AssertAccount(ctx context.Context, id string) (*account.Account, error)
+	CreateAlias(ctx context.Context, alias *Alias, acc *account.Account) (*Alias, error)
+	GetAlias(ctx context.Context, id string) (*Alias, error)
+	ListAliases(ctx context.Context, customerID string) ([]*Alias, error)
+	DeleteAlias(ctx context.Context, id string) error
+	LinkAliasAccount(ctx context.Context, id string, acc *account.Account, isDefault bool) (*Alias, error)
+	UnlinkAliasAccount(ctx context.Context, id, accountID string) (*Alias, error)
+	VerifyAlias(ctx context.Context, id string) (*Alias, error)
+}
+
+// Service manages aliases
+type Service struct {
+	storage Storage
+}
+
+// New is constructor
+func New(storage Storage) *Service {
+	return &Service{
+		storage: storage,
+	}
+}
+
+// Create create alias of account, alias is owned by customer of account
+func (s *Service) Create(ctx context.Context, value, accountID string) (*Alias, error) {
+	kind, value, err := Normalize(value)
+	if err != nil {
+		return nil, err
+	}
+
+	acc, err := s.authorizeAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	alias := &Alias{Kind: kind, Value: value, CustomerID: acc.CustomerID, Verified: kind == KindHandle}
+	return s.storage.CreateAlias(ctx, alias, acc)
+}
+
+// Get view alias, customer principal views only owned aliases
+func (s *Service) Get(ctx context.Context, id string) (*Alias, error) {
+	alias, err := s.storage.GetAlias(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = authorize(ctx, alias); err != nil {
+		return nil, err
+	}
+	return alias, nil
+}
+
+// List view aliases, customer principal views only owned aliases
+func (s *Service) List(ctx context.Context) ([]*Alias, error) {
+	return s.storage.ListAliases(ctx, customer.PrincipalCustomerID(ctx))
+}
+
+// Delete delete alias, value of deleted alias can be taken by anyone
+func (s *Service) Delete(ctx context.Context, id string) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+	return s.storage.DeleteAlias(ctx, id)
+}
+
+// Link link account of owner of alias, account becomes default of its currency when it is set
+// or currency has no default account
+func (s *Service) Link(ctx context.Context, id, accountID string, isDefault bool) (*Alias, error) {
+	alias, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	acc, err := s.authorizeAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if acc.CustomerID != alias.CustomerID {
+		return nil, ErrorForeignAccount
+	}
+	return s.storage.LinkAliasAccount(ctx, alias.ID, acc, isDefault)
+}
+
+// Unlink unlink account from alias, another account of currency becomes default
+// when default account is unlinked
+func (s *Service) Unlink(ctx context.Context, id, accountID string) (*Alias, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.storage.UnlinkAliasAccount(ctx, id, accountID)
+}
+
+// Verify mark phone number or email verified, customers cannot verify their own aliases
+func (s *Service) Verify(ctx context.Context, id string) (*Alias, error) {
+	if customer.PrincipalCustomerID(ctx) != "" {
+		return nil, auth.ErrorForbidden
+	}
+	return s.storage.VerifyAlias(ctx, id)
+}
+
+func (s *Service) authorizeAccount(ctx context.Context, accountID string) (*account.Account, error) {
+	acc, err := s.storage.AssertAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err = account.Authorize(ctx, acc); err != nil {
+		return nil, err
+	}
+	return acc, nil
+}
+
+// Resolve return default account of alias in currency, only verified aliases are resolved
+func Resolve(ctx context.Context, resolver Resolver, value, currency string) (string, error) {
+	_, value, err := Normalize(value)
+	if err != nil {
+		return "", err
+	}
+
+	alias, err := resolver.FindAlias(ctx, value)
+	if err != nil {
+		return "", err
+	}
+	if !alias.Verified {
+		return "", ErrorNotVerified
+	}
+	for _, acc := range alias.Accounts {
+		if acc.Default && acc.Currency == currency {
+			return acc.AccountID, nil
+		}
+	}
+	return "", ErrorNoAccount
+}
+
+// Normalize return kind and normalized value of alias, handles are lowercase and may be prefixed by @,
+// phone numbers are international numbers without separators, emails are lowercase
+func Normalize(value string) (string, string, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case strings.Contains(strings.TrimPrefix(value, "@"), "@"):
+		value = strings.ToLower(value)
+		if len(value) <= MaxLength && emailPattern.MatchString(value) {
+			return KindEmail, value, nil
+		}
+	case strings.HasPrefix(value, "+") || strings.HasPrefix(value, "00"):
+		value = phoneSeparators.Replace(value)
+		if strings.HasPrefix(value, "00") {
+			value = "+" + value[2:]
+		}
+		if phonePattern.MatchString(value) {
+			return KindPhone, value, nil
+		}
+	default:
+		value = strings.ToLower(strings.TrimPrefix(value, "@"))
+		if handlePattern.MatchString(value) {
+			return KindHandle, value, nil
+		}
+	}
+	return "", "", ErrorInvalid
+}
+
+// IsAccountID check value is identifier of account, other values are resolved as aliases
+func IsAccountID(value string) bool {
+	return uuidPattern.MatchString(value)
+}
+
+// authorize check principal is allowed to act on behalf of owner of alias, aliases
+// without owner are managed only by back office
+func authorize(ctx context.Context, alias *Alias) error {
+	if customer.PrincipalCustomerID(ctx) == "" {
+		return nil
+	}
+	if alias.CustomerID == "" {
+		return customer.ErrorNotOwner
+	}
+	return customer.Authorize(ctx, alias.CustomerID)
+}
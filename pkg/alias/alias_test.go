@@ -0,0 +1,173 @@
+package alias
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/customer"
+)
+
+type dummyStorage struct {
+	accounts map[string]account.Account
+	aliases  map[string]Alias
+	verified string
+}
+
+func (d *dummyStorage) FindAlias(_ context.Context, value string) (*Alias, error) {
+	for _, alias := range d.aliases {
+		if alias.Value == value {
+			return &alias, nil
+		}
+	}
+	return nil, ErrorNotFound
+}
+
+func (d *dummyStorage) AssertAccount(_ context.Context, id string) (*account.Account, error) {
+	if res, ok := d.accounts[id]; ok {
+		return &res, nil
+	}
+	return nil, account.ErrorNotFound
+}
+
+func (d *dummyStorage) CreateAlias(_ context.Context, alias *Alias, acc *account.Account) (*Alias, error) {
+	alias.Accounts = []*LinkedAccount{{AccountID: acc.ID, Currency: acc.Currency, Default: true}}
+	return alias, nil
+}
+
+func (d *dummyStorage) GetAlias(_ context.Context, id string) (*Alias, error) {
+	if res, ok := d.aliases[id]; ok {
+		return &res, nil
+	}
+	return nil, ErrorNotFound
+}
+
+func (d *dummyStorage) ListAliases(context.Context, string) ([]*Alias, error) {
+	return nil, nil
+}
+
+func (d *dummyStorage) DeleteAlias(context.Context, string) error {
+	return nil
+}
+
+func (d *dummyStorage) LinkAliasAccount(
+	_ context.Context, id string, acc *account.Account, isDefault bool) (*Alias, error) {
+
+	res := d.aliases[id]
+	res.Accounts = append(res.Accounts, &LinkedAccount{AccountID: acc.ID, Currency: acc.Currency, Default: isDefault})
+	return &res, nil
+}
+
+func (d *dummyStorage) UnlinkAliasAccount(_ context.Context, id, _ string) (*Alias, error) {
+	res := d.aliases[id]
+	return &res, nil
+}
+
+func (d *dummyStorage) VerifyAlias(_ context.Context, id string) (*Alias, error) {
+	d.verified = id
+	res := d.aliases[id]
+	res.Verified = true
+	return &res, nil
+}
+
+func TestNormalize(t *testing.T) {
+	for _, c := range []struct{ value, kind, normalized string }{
+		{"@Alice", KindHandle, "alice"},
+		{"bob_1.x", KindHandle, "bob_1.x"},
+		{"+1 (202) 555-0123", KindPhone, "+12025550123"},
+		{"0044 20 7946 0958", KindPhone, "+442079460958"},
+		{" Alice@Example.COM ", KindEmail, "alice@example.com"},
+	} {
+		kind, normalized, err := Normalize(c.value)
+		if err != nil || kind != c.kind || normalized != c.normalized {
+			t.Errorf("unexpected normalized %q: %s %q %v", c.value, kind, normalized, err)
+		}
+	}
+
+	for _, value := range []string{"", "ab", "1alice", "+123", "+0123456789", "alice@", "a@b", "@@alice"} {
+		if _, _, err := Normalize(value); err != ErrorInvalid {
+			t.Errorf("expected %q rejected", value)
+		}
+	}
+
+	if IsAccountID("alice") || !IsAccountID("9e8d7c6b-5a4f-4e3d-8c2b-1a0f9e8d7c6b") {
+		t.Error("error on check account identifier")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	storage := &dummyStorage{aliases: map[string]Alias{
+		"1": {Value: "alice", Verified: true, Accounts: []*LinkedAccount{
+			{AccountID: "alice_usd_1", Currency: "usd"},
+			{AccountID: "alice_usd_2", Currency: "usd", Default: true},
+		}},
+		"2": {Value: "bob@example.com", Accounts: []*LinkedAccount{{AccountID: "bob", Currency: "usd", Default: true}}},
+	}}
+
+	ctx := context.Background()
+	if id, err := Resolve(ctx, storage, "@ALICE", "usd"); err != nil || id != "alice_usd_2" {
+		t.Errorf("expected default account resolved, got %s %v", id, err)
+	}
+	if _, err := Resolve(ctx, storage, "alice", "eur"); err != ErrorNoAccount {
+		t.Error("expected error on alias without account in currency")
+	}
+	if _, err := Resolve(ctx, storage, "Bob@Example.com", "usd"); err != ErrorNotVerified {
+		t.Error("expected error on not verified alias")
+	}
+	if _, err := Resolve(ctx, storage, "carol", "usd"); err != ErrorNotFound {
+		t.Error("expected error on unknown alias")
+	}
+	if _, err := Resolve(ctx, storage, "?", "usd"); err != ErrorInvalid {
+		t.Error("expected error on invalid alias")
+	}
+}
+
+func TestService_Authorization(t *testing.T) {
+	storage := &dummyStorage{
+		accounts: map[string]account.Account{
+			"alice_usd": {ID: "alice_usd", CustomerID: "customer_alice", Currency: "usd"},
+			"alice_eur": {ID: "alice_eur", CustomerID: "customer_alice", Currency: "eur"},
+			"bob_usd":   {ID: "bob_usd", CustomerID: "customer_bob", Currency: "usd"},
+		},
+		aliases: map[string]Alias{
+			"alice": {ID: "alice", Value: "alice", CustomerID: "customer_alice"},
+		},
+	}
+
+	service := New(storage)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{CustomerID: "customer_alice"})
+	created, err := service.Create(ctx, "+1 202 555 0123", "alice_usd")
+	if err != nil || created.Kind != KindPhone || created.Verified || created.CustomerID != "customer_alice" {
+		t.Errorf("unexpected created alias %v %v", created, err)
+	}
+	if created, err = service.Create(ctx, "@alice", "alice_usd"); err != nil || !created.Verified {
+		t.Error("expected handle verified on creation")
+	}
+	if _, err = service.Create(ctx, "carol", "bob_usd"); err != customer.ErrorNotOwner {
+		t.Error("expected error on create alias of account of another customer")
+	}
+
+	linked, err := service.Link(ctx, "alice", "alice_eur", false)
+	if err != nil || len(linked.Accounts) != 1 {
+		t.Errorf("unexpected linked alias %v %v", linked, err)
+	}
+	ctx = auth.WithPrincipal(context.Background(), &auth.Principal{})
+	if _, err = service.Link(ctx, "alice", "bob_usd", true); err != ErrorForeignAccount {
+		t.Error("expected error on link account of another customer")
+	}
+
+	ctx = auth.WithPrincipal(context.Background(), &auth.Principal{CustomerID: "customer_bob"})
+	if _, err = service.Get(ctx, "alice"); err != customer.ErrorNotOwner {
+		t.Error("expected error on view alias of another customer")
+	}
+	if err = service.Delete(ctx, "alice"); err != customer.ErrorNotOwner {
+		t.Error("expected error on delete alias of another customer")
+	}
+	if _, err = service.Verify(ctx, "alice"); err != auth.ErrorForbidden || storage.verified != "" {
+		t.Error("expected customer forbidden to verify alias")
+	}
+	if _, err = service.Verify(context.Background(), "alice"); err != nil || storage.verified != "alice" {
+		t.Error("unexpected error on verify alias")
+	}
+}
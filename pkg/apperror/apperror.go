@@ -0,0 +1,75 @@
+// Package apperror provides typed errors with stable machine-readable codes
+// and their representation as RFC 7807 problem details
+package apperror
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Kind class of error, transports map it to their status codes
+type Kind int
+
+const (
+	// KindInternal unexpected failure, details are not exposed to clients
+	KindInternal Kind = iota
+	// KindInvalidArgument request is malformed or has invalid values
+	KindInvalidArgument
+	// KindNotFound requested entity not found
+	KindNotFound
+	// KindFailedPrecondition request is valid but cannot be applied to current state
+	KindFailedPrecondition
+	// KindUnauthenticated client is not authenticated
+	KindUnauthenticated
+	// KindPermissionDenied client has no permission
+	KindPermissionDenied
+	// KindConflict entity already exists or was changed concurrently
+	KindConflict
+	// KindTooLarge request exceeds size limit
+	KindTooLarge
+	// KindRateLimited client exceeded rate limit
+	KindRateLimited
+)
+
+// CodeInternal code of errors not known to application
+const CodeInternal = "internal"
+
+// FieldError reason of rejecting field of request
+type FieldError struct {
+	Field  string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Error error with stable code, message is shown to clients,
+// fields are set when particular fields of request are invalid
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+}
+
+// New is constructor
+func New(kind Kind, code, message string) *Error {
+	return &Error{
+		Kind:    kind,
+		Code:    code,
+		Message: message,
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// From return typed error caused err, unknown errors are internal
+func From(err error) *Error {
+	if e, ok := errors.Cause(err).(*Error); ok {
+		return e
+	}
+	return &Error{Kind: KindInternal, Code: CodeInternal, Message: "internal error"}
+}
+
+// Code return stable code of error caused err
+func Code(err error) string {
+	return From(err).Code
+}
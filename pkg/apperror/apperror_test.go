@@ -0,0 +1,34 @@
+package apperror
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+var errorDummy = New(KindFailedPrecondition, "dummy", "dummy failed")
+
+func TestFrom(t *testing.T) {
+	if From(errors.Wrap(errorDummy, "error on dummy")) != errorDummy {
+		t.Error("expected typed error caused wrapped one")
+	}
+
+	if e := From(errors.New("connection refused")); e.Kind != KindInternal || e.Code != CodeInternal {
+		t.Error("expected internal error on unknown one")
+	}
+}
+
+func TestNewProblem(t *testing.T) {
+	problem := NewProblem(errors.Wrap(errorDummy, "error on dummy"))
+	if problem.Status != http.StatusUnprocessableEntity || problem.Code != "dummy" ||
+		problem.Type != problemTypePrefix+"dummy" || problem.Detail != "error on dummy: dummy failed" {
+
+		t.Error("unexpected problem of typed error")
+	}
+
+	problem = NewProblem(errors.New("password authentication failed"))
+	if problem.Status != http.StatusInternalServerError || problem.Detail != "internal error" {
+		t.Error("expected details of internal error are hidden")
+	}
+}
@@ -0,0 +1,77 @@
+package apperror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	// ProblemContentType media type of problem details
+	ProblemContentType = "application/problem+json"
+
+	problemTypePrefix = "urn:wallet:problem:"
+)
+
+// Problem problem details of RFC 7807 extended by error code and request identifier
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+
+	InvalidParams []FieldError `json:"invalid_params,omitempty"`
+}
+
+// HTTPStatus http status code of error kind
+func HTTPStatus(kind Kind) int {
+	switch kind {
+	case KindInvalidArgument:
+		return http.StatusBadRequest
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindFailedPrecondition:
+		return http.StatusUnprocessableEntity
+	case KindUnauthenticated:
+		return http.StatusUnauthorized
+	case KindPermissionDenied:
+		return http.StatusForbidden
+	case KindConflict:
+		return http.StatusConflict
+	case KindTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case KindRateLimited:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+// NewProblem make problem details of error, detail contains whole chain of error messages
+// for client errors and only generic message for internal ones
+func NewProblem(err error) *Problem {
+	e := From(err)
+	status := HTTPStatus(e.Kind)
+	detail := e.Message
+	if e.Kind != KindInternal {
+		detail = err.Error()
+	}
+
+	return &Problem{
+		Type:   problemTypePrefix + e.Code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   e.Code,
+
+		InvalidParams: e.Fields,
+	}
+}
+
+// WriteProblem write problem details of error as response
+func WriteProblem(w http.ResponseWriter, problem *Problem) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem) // nolint: errcheck
+}
@@ -0,0 +1,234 @@
+// Package audit provides tamper-evident log of state-changing operations
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/logging"
+)
+
+const (
+	// ActionCreateCustomer customer created
+	ActionCreateCustomer = "customer.create"
+	// ActionCreateAccount account created
+	ActionCreateAccount = "account.create"
+	// ActionTransferMoney money transferred between accounts
+	ActionTransferMoney = "payment.transfer"
+	// ActionCreateAPIKey api key created
+	ActionCreateAPIKey = "api_key.create"
+	// ActionRevokeAPIKey api key revoked
+	ActionRevokeAPIKey = "api_key.revoke"
+	// ActionClosePeriod period closed
+	ActionClosePeriod = "period.close"
+	// ActionCreateAlias alias created
+	ActionCreateAlias = "alias.create"
+	// ActionUpdateAlias accounts of alias linked or unlinked
+	ActionUpdateAlias = "alias.update"
+	// ActionVerifyAlias alias verified
+	ActionVerifyAlias = "alias.verify"
+	// ActionDeleteAlias alias deleted
+	ActionDeleteAlias = "alias.delete"
+	// ActionCreatePaymentRequest payment request created
+	ActionCreatePaymentRequest = "payment_request.create"
+	// ActionAcceptPaymentRequest payment request accepted by payment
+	ActionAcceptPaymentRequest = "payment_request.accept"
+	// ActionDeclinePaymentRequest payment request declined by payer
+	ActionDeclinePaymentRequest = "payment_request.decline"
+	// ActionCancelPaymentRequest payment request canceled by payee
+	ActionCancelPaymentRequest = "payment_request.cancel"
+	// ActionExpirePaymentRequest payment request expired by sweeper
+	ActionExpirePaymentRequest = "payment_request.expire"
+
+	// EntityCustomer customer entity
+	EntityCustomer = "customer"
+	// EntityAccount account entity
+	EntityAccount = "account"
+	// EntityPayment payment entity
+	EntityPayment = "payment"
+	// EntityAPIKey api key entity
+	EntityAPIKey = "api_key"
+	// EntityPeriod period identified by its first open day
+	EntityPeriod = "period"
+	// EntityAlias alias entity
+	EntityAlias = "alias"
+	// EntityPaymentRequest payment request entity
+	EntityPaymentRequest = "payment_request"
+
+	// ActorSystem operation is not initiated by authenticated client
+	ActorSystem = "system"
+
+	defaultLimit  = 100
+	maxLimit      = 1000
+	verifyBatch   = 1000
+	hashSeparator = "\x1f"
+)
+
+var (
+	// ErrorChainBroken entry hash or link to previous entry does not match
+	ErrorChainBroken = apperror.New(
+		apperror.KindInternal, "audit_chain_broken", "audit chain is broken")
+	// ErrorIncorrectLimit limit must be positive
+	ErrorIncorrectLimit = apperror.New(
+		apperror.KindInvalidArgument, "invalid_limit", "limit must be greater than zero")
+)
+
+// Entry record of state-changing operation, linked to previous record by hash
+type Entry struct {
+	ID         int64           `json:"id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Actor      string          `json:"actor"`
+	RequestID  string          `json:"request_id,omitempty"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+}
+
+// NewEntry make entry of operation, actor and request identifier are taken from context,
+// occurred time is truncated to precision of database
+func NewEntry(ctx context.Context, action, entityType, entityID string, before, after interface{}) (*Entry, error) {
+	entry := &Entry{
+		OccurredAt: time.Now().UTC().Truncate(time.Microsecond),
+		Actor:      ActorFromContext(ctx),
+		RequestID:  logging.RequestIDFromContext(ctx),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+	}
+
+	var err error
+	if entry.Before, err = snapshot(before); err != nil {
+		return nil, err
+	}
+	if entry.After, err = snapshot(after); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// ComputeHash hash of entry content linked with hash of previous entry
+func (e *Entry) ComputeHash() string {
+	fields := []string{
+		e.PrevHash,
+		strconv.FormatInt(e.ID, 10),
+		e.OccurredAt.UTC().Format(time.RFC3339Nano),
+		e.Actor,
+		e.RequestID,
+		e.Action,
+		e.EntityType,
+		e.EntityID,
+		string(e.Before),
+		string(e.After),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, hashSeparator)))
+	return hex.EncodeToString(sum[:])
+}
+
+// ActorFromContext describe authenticated principal stored in context
+func ActorFromContext(ctx context.Context) string {
+	principal := auth.PrincipalFromContext(ctx)
+	if principal == nil {
+		return ActorSystem
+	}
+	return principal.Method + ":" + principal.ID
+}
+
+func snapshot(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on marshal audit snapshot")
+	}
+	return data, nil
+}
+
+// Filter params of audit log query
+type Filter struct {
+	EntityType string
+	EntityID   string
+	AfterID    int64
+	Limit      int
+}
+
+// Storage interface for viewing audit log, entries are appended by storage
+// within transactions of operations
+type Storage interface {
+	ListAuditEntries(ctx context.Context, filter Filter) ([]*Entry, error)
+}
+
+// VerifyResult result of audit chain verification
+type VerifyResult struct {
+	Entries  int64  `json:"entries"`
+	LastID   int64  `json:"last_id"`
+	LastHash string `json:"last_hash"`
+}
+
+// Service handles with audit log
+type Service struct {
+	storage Storage
+}
+
+// New is constructor
+func New(storage Storage) *Service {
+	return &Service{
+		storage: storage,
+	}
+}
+
+// List view audit entries ordered by identifier, log is not available to customers
+// because it contains operations of all of them
+func (s *Service) List(ctx context.Context, filter Filter) ([]*Entry, error) {
+	if principal := auth.PrincipalFromContext(ctx); principal != nil && principal.CustomerID != "" {
+		return nil, auth.ErrorForbidden
+	}
+	if filter.Limit < 0 {
+		return nil, ErrorIncorrectLimit
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultLimit
+	}
+	if filter.Limit > maxLimit {
+		filter.Limit = maxLimit
+	}
+	return s.storage.ListAuditEntries(ctx, filter)
+}
+
+// Verify walk whole audit log and check every entry hash and its link to previous entry
+func (s *Service) Verify(ctx context.Context) (*VerifyResult, error) {
+	result := &VerifyResult{}
+	for {
+		entries, err := s.storage.ListAuditEntries(ctx, Filter{AfterID: result.LastID, Limit: verifyBatch})
+		if err != nil {
+			return result, err
+		}
+
+		for _, entry := range entries {
+			if entry.PrevHash != result.LastHash || entry.ComputeHash() != entry.Hash {
+				return result, errors.Wrap(ErrorChainBroken, fmt.Sprintf("entry %d", entry.ID))
+			}
+			result.Entries++
+			result.LastID = entry.ID
+			result.LastHash = entry.Hash
+		}
+
+		if len(entries) < verifyBatch {
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/logging"
+)
+
+type dummyStorage struct {
+	entries []*Entry
+}
+
+func (d *dummyStorage) append(ctx context.Context, action string, after interface{}) error {
+	entry, err := NewEntry(ctx, action, EntityAccount, "dummy", nil, after)
+	if err != nil {
+		return err
+	}
+	entry.ID = int64(len(d.entries) + 1)
+	if len(d.entries) > 0 {
+		entry.PrevHash = d.entries[len(d.entries)-1].Hash
+	}
+	entry.Hash = entry.ComputeHash()
+	d.entries = append(d.entries, entry)
+	return nil
+}
+
+func (d *dummyStorage) ListAuditEntries(_ context.Context, filter Filter) ([]*Entry, error) {
+	var entries []*Entry
+	for _, entry := range d.entries {
+		if entry.ID > filter.AfterID && len(entries) < filter.Limit {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func TestNewEntry(t *testing.T) {
+	ctx := logging.WithRequestID(context.Background(), "request")
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{ID: "admin", Method: auth.MethodAPIKey})
+	entry, err := NewEntry(ctx, ActionCreateAccount, EntityAccount, "dummy", nil, map[string]int{"balance": 1})
+	if err != nil {
+		t.Fatal("unexpected error on make entry")
+	}
+
+	if entry.Actor != "api_key:admin" || entry.RequestID != "request" {
+		t.Error("expected actor and request identifier taken from context")
+	}
+
+	if entry.Before != nil || string(entry.After) != `{"balance":1}` {
+		t.Error("unexpected snapshots of entry")
+	}
+
+	entry, err = NewEntry(context.Background(), ActionCreateAccount, EntityAccount, "dummy", nil, nil)
+	if err != nil || entry.Actor != ActorSystem {
+		t.Error("expected system actor without principal")
+	}
+}
+
+func TestService_Verify(t *testing.T) {
+	storage := &dummyStorage{}
+	for i := 0; i < verifyBatch+10; i++ {
+		if err := storage.append(context.Background(), ActionCreateAccount, i); err != nil {
+			t.Fatal("unexpected error on append entry")
+		}
+	}
+
+	instance := New(storage)
+	result, err := instance.Verify(context.Background())
+	if err != nil || result.Entries != int64(len(storage.entries)) {
+		t.Fatal("expected valid chain")
+	}
+
+	storage.entries[verifyBatch+5].After = []byte("100500")
+	result, err = instance.Verify(context.Background())
+	if errors.Cause(err) != ErrorChainBroken || result.LastID != verifyBatch+5 {
+		t.Error("expected broken chain on tampered entry")
+	}
+
+	storage.entries[verifyBatch+5].After = []byte("1005")
+	storage.entries = append(storage.entries[:3], storage.entries[4:]...)
+	if _, err = instance.Verify(context.Background()); errors.Cause(err) != ErrorChainBroken {
+		t.Error("expected broken chain on removed entry")
+	}
+}
+
+func TestService_List(t *testing.T) {
+	storage := &dummyStorage{}
+	for i := 0; i < 3; i++ {
+		if err := storage.append(context.Background(), ActionCreateAccount, i); err != nil {
+			t.Fatal("unexpected error on append entry")
+		}
+	}
+
+	instance := New(storage)
+	if _, err := instance.List(context.Background(), Filter{Limit: -1}); err != ErrorIncorrectLimit {
+		t.Error("expected error on negative limit")
+	}
+
+	entries, err := instance.List(context.Background(), Filter{AfterID: 1})
+	if err != nil || len(entries) != 2 {
+		t.Error("expected entries after identifier")
+	}
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{CustomerID: "alice"})
+	if _, err = instance.List(ctx, Filter{}); err != auth.ErrorForbidden {
+		t.Error("expected forbidden for customer principal")
+	}
+}
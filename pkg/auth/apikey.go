@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// APIKeyHeader header carries api key
+	APIKeyHeader = "X-API-Key"
+	// MethodAPIKey principal authenticated by api key
+	MethodAPIKey = "api_key"
+
+	apiKeyPrefix       = "wk_"
+	apiKeySecretLength = 32
+)
+
+// APIKey api key of client, only hash of secret is stored,
+// key issued for customer allows acting only on behalf of that customer
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	CustomerID string     `json:"customer_id,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Storage interface for storing api keys in database
+type Storage interface {
+	CreateAPIKey(
+		ctx context.Context, customerID, name string, scopes []string, hash string) (*APIKey, error)
+	FindAPIKey(ctx context.Context, hash string) (*APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]*APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+}
+
+// Config configuration params of authentication, JWT bearer tokens are accepted
+// besides api keys when any JWT key source is configured
+type Config struct {
+	Enabled bool `default:"true"`
+	JWT     JWTConfig
+}
+
+// Service handles with api keys
+type Service struct {
+	storage Storage
+}
+
+// New is constructor
+func New(storage Storage) *Service {
+	return &Service{
+		storage: storage,
+	}
+}
+
+// CreateKey create api key with scopes, secret is returned only once,
+// customer is optional and restricts key to acting on behalf of that customer
+func (s *Service) CreateKey(
+	ctx context.Context, customerID, name string, scopes []string) (*APIKey, string, error) {
+
+	if err := ValidateScopes(scopes); err != nil {
+		return nil, "", err
+	}
+
+	buf := make([]byte, apiKeySecretLength)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", errors.Wrap(err, "error on generate api key")
+	}
+
+	secret := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	key, err := s.storage.CreateAPIKey(ctx, customerID, name, scopes, hashSecret(secret))
+	if err != nil {
+		return nil, "", errors.Wrap(err, "error on create api key")
+	}
+	return key, secret, nil
+}
+
+// ListKeys view api keys stored in database
+func (s *Service) ListKeys(ctx context.Context) ([]*APIKey, error) {
+	return s.storage.ListAPIKeys(ctx)
+}
+
+// RevokeKey revoke api key, it cannot be used anymore
+func (s *Service) RevokeKey(ctx context.Context, id string) error {
+	return s.storage.RevokeAPIKey(ctx, id)
+}
+
+// Authenticate resolve principal by api key passed in request header,
+// returns nil principal when request has no api key
+func (s *Service) Authenticate(ctx context.Context, r *http.Request) (*Principal, error) {
+	secret := strings.TrimSpace(r.Header.Get(APIKeyHeader))
+	if secret == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return nil, ErrorInvalidCredentials
+	}
+
+	key, err := s.storage.FindAPIKey(ctx, hashSecret(secret))
+	if err != nil {
+		if errors.Cause(err) == ErrorNotFound {
+			return nil, ErrorInvalidCredentials
+		}
+		return nil, errors.Wrap(err, "error on find api key")
+	}
+
+	if key.RevokedAt != nil {
+		return nil, ErrorInvalidCredentials
+	}
+
+	return &Principal{
+		ID:         key.ID,
+		Name:       key.Name,
+		Method:     MethodAPIKey,
+		CustomerID: key.CustomerID,
+		Scopes:     key.Scopes,
+	}, nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,145 @@
+// Package auth provides methods for authenticating clients and checking their permissions
+package auth
+
+import (
+	"context"
+
+	"github.com/sbutakov/wallet/pkg/apperror"
+)
+
+const (
+	// ScopeAccountsRead view accounts
+	ScopeAccountsRead = "accounts:read"
+	// ScopeAccountsWrite create accounts
+	ScopeAccountsWrite = "accounts:write"
+	// ScopePaymentsRead view payments
+	ScopePaymentsRead = "payments:read"
+	// ScopePaymentsWrite transfer money
+	ScopePaymentsWrite = "payments:write"
+	// ScopeCustomersRead view customers
+	ScopeCustomersRead = "customers:read"
+	// ScopeCustomersWrite create customers
+	ScopeCustomersWrite = "customers:write"
+	// ScopeKeysAdmin manage api keys
+	ScopeKeysAdmin = "keys:admin"
+	// ScopeAuditRead view audit log
+	ScopeAuditRead = "audit:read"
+	// ScopeReportsRead view reports
+	ScopeReportsRead = "reports:read"
+	// ScopePeriodsAdmin close periods
+	ScopePeriodsAdmin = "periods:admin"
+	// ScopeProjectionsAdmin rebuild projections
+	ScopeProjectionsAdmin = "projections:admin"
+	// ScopeAliasesRead view aliases
+	ScopeAliasesRead = "aliases:read"
+	// ScopeAliasesWrite create and delete aliases and link accounts to them
+	ScopeAliasesWrite = "aliases:write"
+	// ScopeAliasesAdmin verify aliases
+	ScopeAliasesAdmin = "aliases:admin"
+	// ScopePaymentRequestsRead view payment requests
+	ScopePaymentRequestsRead = "payment_requests:read"
+	// ScopePaymentRequestsWrite create, decline and cancel payment requests
+	ScopePaymentRequestsWrite = "payment_requests:write"
+)
+
+// Scopes all known scopes
+var Scopes = []string{
+	ScopeAccountsRead,
+	ScopeAccountsWrite,
+	ScopePaymentsRead,
+	ScopePaymentsWrite,
+	ScopeCustomersRead,
+	ScopeCustomersWrite,
+	ScopeKeysAdmin,
+	ScopeAuditRead,
+	ScopeReportsRead,
+	ScopePeriodsAdmin,
+	ScopeProjectionsAdmin,
+	ScopeAliasesRead,
+	ScopeAliasesWrite,
+	ScopeAliasesAdmin,
+	ScopePaymentRequestsRead,
+	ScopePaymentRequestsWrite,
+}
+
+var (
+	// ErrorUnauthorized client is not authenticated
+	ErrorUnauthorized = apperror.New(apperror.KindUnauthenticated, "unauthorized", "unauthorized")
+	// ErrorForbidden client has no permission
+	ErrorForbidden = apperror.New(apperror.KindPermissionDenied, "forbidden", "forbidden")
+	// ErrorInvalidCredentials credentials are malformed, unknown or revoked
+	ErrorInvalidCredentials = apperror.New(
+		apperror.KindUnauthenticated, "invalid_credentials", "invalid credentials")
+	// ErrorUnknownScope unknown scope
+	ErrorUnknownScope = apperror.New(apperror.KindInvalidArgument, "unknown_scope", "unknown scope")
+	// ErrorNotFound api key not found
+	ErrorNotFound = apperror.New(apperror.KindNotFound, "api_key_not_found", "api key not found")
+)
+
+// Principal authenticated client, customer identifier is set when client acts as customer
+type Principal struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Method     string   `json:"method"`
+	CustomerID string   `json:"customer_id,omitempty"`
+	Scopes     []string `json:"scopes"`
+}
+
+// HasScope principal is granted scope
+func (p *Principal) HasScope(scope string) bool {
+	if p == nil {
+		return false
+	}
+	return contains(scope, p.Scopes)
+}
+
+type contextKey int
+
+const (
+	contextKeyPrincipal contextKey = iota
+)
+
+// WithPrincipal store authenticated principal in context
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, contextKeyPrincipal, principal)
+}
+
+// PrincipalFromContext return authenticated principal, nil when client is anonymous
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(contextKeyPrincipal).(*Principal)
+	return principal
+}
+
+// Authorize check principal stored in context is granted scope
+func Authorize(ctx context.Context, scope string) error {
+	principal := PrincipalFromContext(ctx)
+	if principal == nil {
+		return ErrorUnauthorized
+	}
+	if !principal.HasScope(scope) {
+		return ErrorForbidden
+	}
+	return nil
+}
+
+// ValidateScopes check all scopes are known
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return ErrorUnknownScope
+	}
+	for _, scope := range scopes {
+		if !contains(scope, Scopes) {
+			return ErrorUnknownScope
+		}
+	}
+	return nil
+}
+
+func contains(str string, arr []string) bool {
+	for _, item := range arr {
+		if str == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type dummyStorage struct {
+	keys map[string]*APIKey
+}
+
+func (d *dummyStorage) CreateAPIKey(
+	_ context.Context, customerID, name string, scopes []string, hash string) (*APIKey, error) {
+
+	key := &APIKey{ID: hash[:8], CustomerID: customerID, Name: name, Scopes: scopes}
+	d.keys[hash] = key
+	return key, nil
+}
+
+func (d *dummyStorage) FindAPIKey(_ context.Context, hash string) (*APIKey, error) {
+	if key, ok := d.keys[hash]; ok {
+		return key, nil
+	}
+	return nil, ErrorNotFound
+}
+
+func (d *dummyStorage) ListAPIKeys(context.Context) ([]*APIKey, error) {
+	return nil, nil
+}
+
+func (d *dummyStorage) RevokeAPIKey(_ context.Context, id string) error {
+	for _, key := range d.keys {
+		if key.ID == id {
+			now := time.Now()
+			key.RevokedAt = &now
+			return nil
+		}
+	}
+	return ErrorNotFound
+}
+
+func TestService_Authenticate(t *testing.T) {
+	instance := New(&dummyStorage{keys: map[string]*APIKey{}})
+	ctx := context.Background()
+
+	if _, _, err := instance.CreateKey(ctx, "", "dummy", []string{"dummy:scope"}); err != ErrorUnknownScope {
+		t.Error("expected error on unknown scope")
+	}
+
+	key, secret, err := instance.CreateKey(ctx, "", "dummy", []string{ScopeAccountsRead})
+	if err != nil {
+		t.Fatal("unexpected error on create api key")
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	principal, err := instance.Authenticate(ctx, r)
+	if err != nil || principal != nil {
+		t.Error("expected anonymous request without api key")
+	}
+
+	r.Header.Set(APIKeyHeader, secret)
+	principal, err = instance.Authenticate(ctx, r)
+	if err != nil {
+		t.Fatal("unexpected error on authenticate")
+	}
+
+	if !principal.HasScope(ScopeAccountsRead) || principal.HasScope(ScopePaymentsWrite) {
+		t.Error("unexpected scopes of principal")
+	}
+
+	if err = instance.RevokeKey(ctx, key.ID); err != nil {
+		t.Fatal("unexpected error on revoke api key")
+	}
+
+	if _, err = instance.Authenticate(ctx, r); err != ErrorInvalidCredentials {
+		t.Error("expected error on revoked api key")
+	}
+
+	r.Header.Set(APIKeyHeader, "wk_unknown")
+	if _, err = instance.Authenticate(ctx, r); err != ErrorInvalidCredentials {
+		t.Error("expected error on unknown api key")
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	ctx := context.Background()
+	if err := Authorize(ctx, ScopeAccountsRead); err != ErrorUnauthorized {
+		t.Error("expected error on anonymous principal")
+	}
+
+	ctx = WithPrincipal(ctx, &Principal{Scopes: []string{ScopeAccountsRead}})
+	if err := Authorize(ctx, ScopeAccountsRead); err != nil {
+		t.Error("unexpected error on granted scope")
+	}
+
+	if err := Authorize(ctx, ScopePaymentsWrite); err != ErrorForbidden {
+		t.Error("expected error on not granted scope")
+	}
+}
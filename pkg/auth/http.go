@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/logging"
+)
+
+// Authenticator interface for resolving principal by request credentials,
+// returns nil principal when request has no credentials supported by authenticator
+type Authenticator interface {
+	Authenticate(ctx context.Context, r *http.Request) (*Principal, error)
+}
+
+// Middleware authenticate requests by first authenticator recognized credentials and
+// store principal in request context, rejects requests with invalid credentials,
+// anonymous requests are passed to handler which is responsible for authorization
+func Middleware(authenticators ...Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			for _, authenticator := range authenticators {
+				principal, err := authenticator.Authenticate(ctx, r)
+				if err != nil {
+					writeAuthError(w, r, err)
+					return
+				}
+				if principal != nil {
+					ctx = WithPrincipal(ctx, principal)
+					break
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ErrorInvalidToken bearer token cannot be accepted, detail contains reason
+var ErrorInvalidToken = apperror.New(apperror.KindUnauthenticated, "invalid_token", "invalid token")
+
+func writeAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	if e, ok := errors.Cause(err).(*TokenError); ok {
+		w.Header().Set("WWW-Authenticate",
+			fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, e.Error()))
+		err = errors.Wrap(ErrorInvalidToken, e.Error())
+	}
+	problem := apperror.NewProblem(err)
+	problem.RequestID = logging.RequestIDFromContext(r.Context())
+	apperror.WriteProblem(w, problem)
+}
@@ -0,0 +1,453 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MethodJWT principal authenticated by JWT bearer token
+	MethodJWT = "jwt"
+
+	algRS256 = "RS256"
+	algES256 = "ES256"
+	algHS256 = "HS256"
+
+	defaultCustomerClaim = "customer_id"
+	bearerPrefix         = "bearer "
+)
+
+// TokenError JWT is malformed, has invalid signature or claims
+type TokenError struct {
+	msg string
+}
+
+func (e *TokenError) Error() string {
+	return e.msg
+}
+
+var (
+	// ErrorTokenMalformed token cannot be parsed
+	ErrorTokenMalformed = &TokenError{msg: "token is malformed"}
+	// ErrorTokenAlgorithm token is signed by unsupported algorithm
+	ErrorTokenAlgorithm = &TokenError{msg: "token signing algorithm is not supported"}
+	// ErrorTokenKey key of token is unknown
+	ErrorTokenKey = &TokenError{msg: "token signing key is unknown"}
+	// ErrorTokenSignature token signature is invalid
+	ErrorTokenSignature = &TokenError{msg: "token signature is invalid"}
+	// ErrorTokenNoExpiry token has no exp claim
+	ErrorTokenNoExpiry = &TokenError{msg: "token has no expiry"}
+	// ErrorTokenExpired token is expired
+	ErrorTokenExpired = &TokenError{msg: "token is expired"}
+	// ErrorTokenNotYetValid token is not valid yet
+	ErrorTokenNotYetValid = &TokenError{msg: "token is not valid yet"}
+	// ErrorTokenIssuer token is issued by unexpected issuer
+	ErrorTokenIssuer = &TokenError{msg: "token issuer is invalid"}
+	// ErrorTokenAudience token is issued for another audience
+	ErrorTokenAudience = &TokenError{msg: "token audience is invalid"}
+	// ErrorTokenSubject token has no subject
+	ErrorTokenSubject = &TokenError{msg: "token subject is empty"}
+)
+
+// JWTConfig configuration params of JWT bearer token authentication,
+// keys are loaded from JWKS file, PEM public key file or HMAC secret,
+// tokens without exp claim are rejected unless AllowNoExpiry is set
+type JWTConfig struct {
+	JWKSFile      string
+	PublicKeyFile string
+	HMACSecret    string
+	Issuer        string
+	Audience      string
+	Leeway        time.Duration `default:"30s"`
+	CustomerClaim string        `default:"customer_id"`
+	AllowNoExpiry bool
+}
+
+// Configured any key source is set
+func (c JWTConfig) Configured() bool {
+	return c.JWKSFile != "" || c.PublicKeyFile != "" || c.HMACSecret != ""
+}
+
+// Validate check PEM public key and HMAC secret are not both set, both are used
+// for tokens without kid header
+func (c JWTConfig) Validate() error {
+	if c.PublicKeyFile != "" && c.HMACSecret != "" {
+		return errors.New("jwt public key file and hmac secret cannot be both set")
+	}
+	return nil
+}
+
+// KeySource interface for resolving verification key by key identifier and algorithm of token
+type KeySource interface {
+	Key(kid, alg string) (interface{}, error)
+}
+
+// KeySet verification keys by key identifier, key with empty identifier is used
+// for tokens without kid header or when there is no key with their kid
+type KeySet map[string]interface{}
+
+// Key return key suitable for algorithm
+func (s KeySet) Key(kid, alg string) (interface{}, error) {
+	key, ok := s[kid]
+	if !ok {
+		if key, ok = s[""]; !ok {
+			return nil, ErrorTokenKey
+		}
+	}
+	if !keyMatchesAlgorithm(key, alg) {
+		return nil, ErrorTokenKey
+	}
+	return key, nil
+}
+
+// NewKeySource make key source by config, key without identifier is set at most once
+func NewKeySource(config JWTConfig) (KeySource, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	keys := KeySet{}
+	if config.JWKSFile != "" {
+		data, err := ioutil.ReadFile(config.JWKSFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "error on read jwks file")
+		}
+		if keys, err = ParseJWKS(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.PublicKeyFile != "" {
+		data, err := ioutil.ReadFile(config.PublicKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "error on read public key file")
+		}
+		if _, ok := keys[""]; ok {
+			return nil, errors.New("jwks already has key without kid")
+		}
+		if keys[""], err = ParsePublicKeyPEM(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.HMACSecret != "" {
+		if _, ok := keys[""]; ok {
+			return nil, errors.New("jwks already has key without kid")
+		}
+		keys[""] = []byte(config.HMACSecret)
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("jwt keys are not configured")
+	}
+	return keys, nil
+}
+
+// ParsePublicKeyPEM parse RSA or ECDSA public key in PEM format
+func ParsePublicKeyPEM(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("error on decode pem block")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		cert, certErr := x509.ParseCertificate(block.Bytes)
+		if certErr != nil {
+			return nil, errors.Wrap(err, "error on parse public key")
+		}
+		key = cert.PublicKey
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	}
+	return nil, errors.New("unsupported public key type")
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// ParseJWKS parse JSON web key set with RSA, EC P-256 and symmetric keys
+func ParseJWKS(data []byte) (KeySet, error) {
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, errors.Wrap(err, "error on decode jwks")
+	}
+
+	keys := KeySet{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, errors.Wrapf(err, "error on parse key %q", k.Kid)
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, errors.New("unsupported curve")
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(k.K)
+	}
+	return nil, errors.New("unsupported key type")
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "error on decode key param")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// JWTAuthenticator authenticate requests by JWT passed in Authorization bearer header
+type JWTAuthenticator struct {
+	keys   KeySource
+	config JWTConfig
+	now    func() time.Time
+}
+
+// NewJWTAuthenticator is constructor
+func NewJWTAuthenticator(config JWTConfig, keys KeySource) *JWTAuthenticator {
+	if config.CustomerClaim == "" {
+		config.CustomerClaim = defaultCustomerClaim
+	}
+
+	return &JWTAuthenticator{
+		keys:   keys,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Authenticate resolve principal by claims of bearer token,
+// returns nil principal when request has no bearer token
+func (a *JWTAuthenticator) Authenticate(_ context.Context, r *http.Request) (*Principal, error) {
+	header := r.Header.Get("Authorization")
+	if len(header) < len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return nil, nil
+	}
+	return a.Verify(strings.TrimSpace(header[len(bearerPrefix):]))
+}
+
+// Verify check token signature and claims, map claims to principal
+func (a *JWTAuthenticator) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrorTokenMalformed
+	}
+
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	if header.Alg != algRS256 && header.Alg != algES256 && header.Alg != algHS256 {
+		return nil, ErrorTokenAlgorithm
+	}
+
+	key, err := a.keys.Key(header.Kid, header.Alg)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrorTokenMalformed
+	}
+	if !verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrorTokenSignature
+	}
+
+	claims := map[string]interface{}{}
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err = a.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	subject, _ := claims["sub"].(string)
+	name, _ := claims["name"].(string)
+	if name == "" {
+		name = subject
+	}
+	customerID, _ := claims[a.config.CustomerClaim].(string)
+
+	return &Principal{
+		ID:         subject,
+		Name:       name,
+		Method:     MethodJWT,
+		CustomerID: customerID,
+		Scopes:     claimScopes(claims),
+	}, nil
+}
+
+func (a *JWTAuthenticator) validateClaims(claims map[string]interface{}) error {
+	now := a.now()
+	exp, ok := numericClaim(claims, "exp")
+	if !ok && !a.config.AllowNoExpiry {
+		return ErrorTokenNoExpiry
+	}
+	if ok && !now.Before(exp.Add(a.config.Leeway)) {
+		return ErrorTokenExpired
+	}
+	if nbf, ok := numericClaim(claims, "nbf"); ok && now.Add(a.config.Leeway).Before(nbf) {
+		return ErrorTokenNotYetValid
+	}
+
+	if subject, _ := claims["sub"].(string); subject == "" {
+		return ErrorTokenSubject
+	}
+
+	if a.config.Issuer != "" {
+		if issuer, _ := claims["iss"].(string); issuer != a.config.Issuer {
+			return ErrorTokenIssuer
+		}
+	}
+
+	if a.config.Audience != "" && !contains(a.config.Audience, stringsClaim(claims, "aud")) {
+		return ErrorTokenAudience
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrorTokenMalformed
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return ErrorTokenMalformed
+	}
+	return nil
+}
+
+func verifySignature(alg string, key interface{}, signed, signature []byte) bool {
+	sum := sha256.Sum256(signed)
+	switch alg {
+	case algHS256:
+		mac := hmac.New(sha256.New, key.([]byte))
+		mac.Write(signed) // nolint: errcheck
+		return hmac.Equal(mac.Sum(nil), signature)
+
+	case algRS256:
+		return rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), crypto.SHA256, sum[:], signature) == nil
+
+	case algES256:
+		if len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(key.(*ecdsa.PublicKey), sum[:], r, s)
+	}
+	return false
+}
+
+func keyMatchesAlgorithm(key interface{}, alg string) bool {
+	switch k := key.(type) {
+	case []byte:
+		return alg == algHS256 && len(k) > 0
+	case *rsa.PublicKey:
+		return alg == algRS256
+	case *ecdsa.PublicKey:
+		return alg == algES256 && k.Curve == elliptic.P256()
+	}
+	return false
+}
+
+func numericClaim(claims map[string]interface{}, name string) (time.Time, bool) {
+	value, ok := claims[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(value), 0), true
+}
+
+// stringsClaim return claim which value is string or array of strings
+func stringsClaim(claims map[string]interface{}, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// claimScopes return scopes of space separated "scope" claim or "scp" array claim
+func claimScopes(claims map[string]interface{}) []string {
+	if scope, ok := claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+	return stringsClaim(claims, "scp")
+}
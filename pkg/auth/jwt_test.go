@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func signToken(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed)) // nolint: errcheck
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, sum[:]); err != nil {
+			t.Fatal("unexpected error on sign token")
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, sum[:])
+		if err != nil {
+			t.Fatal("unexpected error on sign token")
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWTAuthenticator_Verify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("unexpected error on generate rsa key")
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("unexpected error on generate ecdsa key")
+	}
+	secret := []byte("dummy-secret")
+
+	keys := KeySet{
+		"rsa": &rsaKey.PublicKey,
+		"ec":  &ecKey.PublicKey,
+		"":    secret,
+	}
+	authenticator := NewJWTAuthenticator(JWTConfig{Issuer: "gateway", Audience: "wallet"}, keys)
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"sub":         "alice",
+		"iss":         "gateway",
+		"aud":         []string{"wallet"},
+		"exp":         now.Add(time.Hour).Unix(),
+		"nbf":         now.Add(-time.Minute).Unix(),
+		"scope":       "accounts:read payments:write",
+		"customer_id": "customer_alice",
+	}
+
+	for _, token := range []string{
+		signToken(t, algRS256, "rsa", rsaKey, claims),
+		signToken(t, algES256, "ec", ecKey, claims),
+		signToken(t, algHS256, "", secret, claims),
+	} {
+		principal, err := authenticator.Verify(token)
+		if err != nil {
+			t.Fatalf("unexpected error on verify token: %v", err)
+		}
+		if principal.ID != "alice" || principal.CustomerID != "customer_alice" ||
+			!principal.HasScope(ScopePaymentsWrite) || principal.Method != MethodJWT {
+			t.Error("unexpected principal of token")
+		}
+	}
+
+	cases := []struct {
+		name   string
+		token  string
+		expect error
+	}{
+		{"no expiry", signToken(t, algHS256, "", secret, with(claims, "exp", nil)), ErrorTokenNoExpiry},
+		{"expired", signToken(t, algHS256, "", secret, with(claims, "exp", now.Add(-time.Hour).Unix())),
+			ErrorTokenExpired},
+		{"not yet valid", signToken(t, algHS256, "", secret, with(claims, "nbf", now.Add(time.Hour).Unix())),
+			ErrorTokenNotYetValid},
+		{"issuer", signToken(t, algHS256, "", secret, with(claims, "iss", "dummy")), ErrorTokenIssuer},
+		{"audience", signToken(t, algHS256, "", secret, with(claims, "aud", "dummy")), ErrorTokenAudience},
+		{"signature", signToken(t, algHS256, "", []byte("another"), claims), ErrorTokenSignature},
+		{"algorithm confusion", signToken(t, algHS256, "rsa", secret, claims), ErrorTokenKey},
+		{"malformed", "dummy", ErrorTokenMalformed},
+	}
+	for _, c := range cases {
+		if _, err := authenticator.Verify(c.token); err != c.expect {
+			t.Errorf("%s: expected error %v, got %v", c.name, c.expect, err)
+		}
+	}
+
+	token := signToken(t, algHS256, "", secret, with(claims, "exp", nil))
+	config := JWTConfig{Issuer: "gateway", Audience: "wallet", AllowNoExpiry: true}
+	if _, err = NewJWTAuthenticator(config, keys).Verify(token); err != nil {
+		t.Error("expected token without expiry accepted when allowed")
+	}
+}
+
+func TestNewKeySource(t *testing.T) {
+	file, err := ioutil.TempFile("", "jwks")
+	if err != nil {
+		t.Fatal("unexpected error on create jwks file")
+	}
+	defer os.Remove(file.Name())
+	if _, err = file.WriteString(`{"keys":[{"kty":"oct","k":"ZHVtbXk"}]}`); err != nil {
+		t.Fatal("unexpected error on write jwks file")
+	}
+	file.Close()
+
+	if _, err = NewKeySource(JWTConfig{PublicKeyFile: "dummy.pem", HMACSecret: "dummy"}); err == nil {
+		t.Error("expected error on public key file and hmac secret both set")
+	}
+	if _, err = NewKeySource(JWTConfig{JWKSFile: file.Name(), HMACSecret: "dummy"}); err == nil {
+		t.Error("expected error on hmac secret overwriting jwks key without kid")
+	}
+	if _, err = NewKeySource(JWTConfig{JWKSFile: file.Name()}); err != nil {
+		t.Error("unexpected error on load jwks file")
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("unexpected error on generate ecdsa key")
+	}
+
+	encode := func(i *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(i.Bytes())
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{"kid": "ec", "kty": "EC", "crv": "P-256", "x": encode(ecKey.X), "y": encode(ecKey.Y)},
+			{"kid": "enc", "kty": "RSA", "use": "enc"},
+		},
+	})
+
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		t.Fatal("unexpected error on parse jwks")
+	}
+
+	if len(keys) != 1 {
+		t.Fatal("expected only signing keys")
+	}
+
+	token := signToken(t, algES256, "ec", ecKey, map[string]interface{}{
+		"sub": "alice",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if _, err = NewJWTAuthenticator(JWTConfig{}, keys).Verify(token); err != nil {
+		t.Error("unexpected error on verify token by jwks key")
+	}
+}
+
+func TestMiddleware_ExpiredToken(t *testing.T) {
+	secret := []byte("dummy-secret")
+	authenticator := NewJWTAuthenticator(JWTConfig{}, KeySet{"": secret})
+	token := signToken(t, algHS256, "", secret, map[string]interface{}{
+		"sub": "alice",
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	})
+
+	handler := Middleware(authenticator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler must not be called with expired token")
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Error("expected unauthorized status with challenge")
+	}
+}
+
+func with(claims map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(claims))
+	for k, v := range claims {
+		result[k] = v
+	}
+	result[key] = value
+	return result
+}
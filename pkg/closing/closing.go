@@ -0,0 +1,198 @@
+// Package closing provides end of day snapshots of balances of accounts and closing of periods,
+// payments of closed period cannot be created, changed or removed, so closing balances of its days
+// are final, snapshots are verified against balances derived from payments
+package closing
+
+import (
+	"context"
+	"time"
+
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/health"
+)
+
+const (
+	// DayLayout layout of days of snapshots and closings
+	DayLayout = "2006-01-02"
+
+	day = 24 * time.Hour
+
+	// settle delay after end of day, transfers started before midnight are committed by then
+	settle = time.Minute
+)
+
+var (
+	// ErrorPeriodClosed operation falls into closed period
+	ErrorPeriodClosed = apperror.New(
+		apperror.KindFailedPrecondition, "period_closed", "operation falls into closed period")
+	// ErrorPeriodNotEnded period is closed before its last day ends
+	ErrorPeriodNotEnded = apperror.New(
+		apperror.KindInvalidArgument, "period_not_ended", "period can be closed only after its last day ends")
+	// ErrorAlreadyClosed period is already closed, closed period is never reopened
+	ErrorAlreadyClosed = apperror.New(
+		apperror.KindConflict, "period_already_closed", "period is already closed")
+	// ErrorDayNotEnded snapshot of day is taken before day ends
+	ErrorDayNotEnded = apperror.New(
+		apperror.KindInvalidArgument, "day_not_ended", "snapshot can be taken only after day ends")
+)
+
+// Closing closing of period, all days before closed until are closed
+type Closing struct {
+	ClosedUntil string    `json:"closed_until"`
+	ClosedAt    time.Time `json:"closed_at"`
+	Actor       string    `json:"actor"`
+}
+
+// Mismatch snapshot differs from balance derived from payments
+type Mismatch struct {
+	AccountID string  `json:"account_id"`
+	Day       string  `json:"day"`
+	Balance   float64 `json:"balance"`
+	Derived   float64 `json:"derived"`
+}
+
+// VerifyResult result of verification of snapshots of days
+type VerifyResult struct {
+	Snapshots  int64       `json:"snapshots"`
+	Mismatches []*Mismatch `json:"mismatches"`
+}
+
+// Config configuration of snapshots, snapshots of ended days are taken every interval
+// by every replica, snapshot of account and day is taken once
+type Config struct {
+	Snapshots        bool          `default:"true"`
+	SnapshotInterval time.Duration `default:"1h"`
+}
+
+// Storage interface for taking snapshots and closing periods, balance at end of day
+// is recorded for every account opened before end of day
+type Storage interface {
+	LastSnapshotDay(ctx context.Context) (time.Time, error)
+	TakeSnapshots(ctx context.Context, day time.Time) (int64, error)
+	VerifySnapshots(ctx context.Context, from, to time.Time) (*VerifyResult, error)
+	ClosePeriod(ctx context.Context, until time.Time) (*Closing, error)
+	ListClosings(ctx context.Context) ([]*Closing, error)
+}
+
+// Service takes snapshots and closes periods
+type Service struct {
+	config  Config
+	storage Storage
+	now     func() time.Time
+}
+
+// New is constructor
+func New(config Config, storage Storage) *Service {
+	return &Service{
+		config:  config,
+		storage: storage,
+		now:     time.Now,
+	}
+}
+
+// Snapshot take snapshots of balances of accounts at end of day, returns number of taken snapshots,
+// snapshots taken before are kept
+func (s *Service) Snapshot(ctx context.Context, day time.Time) (int64, error) {
+	day = startOfDay(day)
+	if day.After(s.lastEnded()) {
+		return 0, ErrorDayNotEnded
+	}
+	return s.storage.TakeSnapshots(ctx, day)
+}
+
+// CatchUp take snapshots of days ended since last snapshot, only snapshot of last ended day
+// is taken first time, so history is not scanned on start
+func (s *Service) CatchUp(ctx context.Context) error {
+	last, err := s.storage.LastSnapshotDay(ctx)
+	if err != nil {
+		return err
+	}
+
+	lastEnded := s.lastEnded()
+	next := lastEnded
+	if !last.IsZero() {
+		next = startOfDay(last).Add(day)
+	}
+	for ; !next.After(lastEnded); next = next.Add(day) {
+		if _, err = s.storage.TakeSnapshots(ctx, next); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run take snapshots of ended days every interval until context is done, heartbeat beats
+// after every successful catch up, snapshots are not taken when they are disabled
+func (s *Service) Run(ctx context.Context, heartbeat *health.Heartbeat, onError func(error)) {
+	if !s.config.Snapshots {
+		return
+	}
+
+	ticker := time.NewTicker(s.config.SnapshotInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.CatchUp(ctx); err != nil {
+			onError(err)
+		} else {
+			heartbeat.Beat()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ClosePeriod close all days before until, snapshots of last closed day are taken
+// when they are missing, periods are closed only by finance, not by customers
+func (s *Service) ClosePeriod(ctx context.Context, until time.Time) (*Closing, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	until = startOfDay(until)
+	if until.After(s.lastEnded().Add(day)) {
+		return nil, ErrorPeriodNotEnded
+	}
+	return s.storage.ClosePeriod(ctx, until)
+}
+
+// Closings view closings of periods, last closing is first
+func (s *Service) Closings(ctx context.Context) ([]*Closing, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	return s.storage.ListClosings(ctx)
+}
+
+// Verify compare snapshots of days from first until last exclusive with balances derived from payments
+func (s *Service) Verify(ctx context.Context, from, to time.Time) (*VerifyResult, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	return s.storage.VerifySnapshots(ctx, startOfDay(from), startOfDay(to))
+}
+
+// ParseDay parse day in DayLayout
+func ParseDay(value string) (time.Time, error) {
+	return time.Parse(DayLayout, value)
+}
+
+// lastEnded last day ended and settled
+func (s *Service) lastEnded() time.Time {
+	return startOfDay(s.now().Add(-settle)).Add(-day)
+}
+
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(day)
+}
+
+// authorize reject customers, closing affects accounts of all of them
+func authorize(ctx context.Context) error {
+	if principal := auth.PrincipalFromContext(ctx); principal != nil && principal.CustomerID != "" {
+		return auth.ErrorForbidden
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package closing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/health"
+)
+
+type dummyStorage struct {
+	last   time.Time
+	taken  []time.Time
+	until  time.Time
+	fail   bool
+	cancel context.CancelFunc
+}
+
+func (d *dummyStorage) LastSnapshotDay(context.Context) (time.Time, error) {
+	if d.fail {
+		d.fail = false
+		return time.Time{}, errors.New("dummy")
+	}
+	return d.last, nil
+}
+
+func (d *dummyStorage) TakeSnapshots(_ context.Context, day time.Time) (int64, error) {
+	d.taken = append(d.taken, day)
+	d.last = day
+	if d.cancel != nil {
+		d.cancel()
+	}
+	return 1, nil
+}
+
+func (d *dummyStorage) VerifySnapshots(context.Context, time.Time, time.Time) (*VerifyResult, error) {
+	return &VerifyResult{}, nil
+}
+
+func (d *dummyStorage) ClosePeriod(_ context.Context, until time.Time) (*Closing, error) {
+	d.until = until
+	return &Closing{ClosedUntil: until.Format(DayLayout)}, nil
+}
+
+func (d *dummyStorage) ListClosings(context.Context) ([]*Closing, error) {
+	return nil, nil
+}
+
+func newDummyService(storage Storage, now time.Time) *Service {
+	service := New(Config{Snapshots: true, SnapshotInterval: time.Millisecond}, storage)
+	service.now = func() time.Time { return now }
+	return service
+}
+
+func TestService_CatchUp(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	storage := &dummyStorage{}
+	service := newDummyService(storage, now)
+
+	if err := service.CatchUp(context.Background()); err != nil {
+		t.Fatal("unexpected error on catch up")
+	}
+	if len(storage.taken) != 1 || !storage.taken[0].Equal(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected only last ended day taken first time, got %v", storage.taken)
+	}
+
+	storage = &dummyStorage{last: time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)}
+	service = newDummyService(storage, now)
+	if err := service.CatchUp(context.Background()); err != nil || len(storage.taken) != 3 {
+		t.Errorf("expected days since last snapshot taken, got %v", storage.taken)
+	}
+
+	storage = &dummyStorage{}
+	service = newDummyService(storage, time.Date(2026, 10, 19, 0, 0, 30, 0, time.UTC))
+	if err := service.CatchUp(context.Background()); err != nil || !storage.last.Equal(now.AddDate(0, 0, -2).Truncate(day)) {
+		t.Errorf("expected day not taken until settled, got %v", storage.taken)
+	}
+}
+
+func TestService_Snapshot(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	storage := &dummyStorage{}
+	service := newDummyService(storage, now)
+
+	if _, err := service.Snapshot(context.Background(), now); err != ErrorDayNotEnded {
+		t.Error("expected snapshot of current day rejected")
+	}
+	if _, err := service.Snapshot(context.Background(), now.AddDate(0, 0, -1)); err != nil ||
+		!storage.last.Equal(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected snapshot of yesterday taken, got %v", storage.last)
+	}
+}
+
+func TestService_ClosePeriod(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	storage := &dummyStorage{}
+	service := newDummyService(storage, now)
+
+	if _, err := service.ClosePeriod(context.Background(), now); err != nil || !storage.until.Equal(now.Truncate(day)) {
+		t.Errorf("expected period closed until today, got %v, %v", storage.until, err)
+	}
+	if _, err := service.ClosePeriod(context.Background(), now.AddDate(0, 0, 1)); err != ErrorPeriodNotEnded {
+		t.Error("expected period not ended rejected")
+	}
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{ID: "key", CustomerID: "dummy"})
+	if _, err := service.ClosePeriod(ctx, now); err != auth.ErrorForbidden {
+		t.Error("expected closing forbidden to customers")
+	}
+	if _, err := service.Closings(ctx); err != auth.ErrorForbidden {
+		t.Error("expected closings forbidden to customers")
+	}
+}
+
+func TestService_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	storage := &dummyStorage{fail: true, cancel: cancel}
+	heartbeat := health.NewHeartbeat(time.Minute)
+
+	var failures int
+	newDummyService(storage, time.Now()).Run(ctx, heartbeat, func(error) {
+		failures++
+	})
+	if len(storage.taken) != 1 || failures != 1 || heartbeat.Check(context.Background()) != nil {
+		t.Errorf("expected snapshots taken until cancel, taken %d, failed %d", len(storage.taken), failures)
+	}
+
+	storage = &dummyStorage{}
+	New(Config{}, storage).Run(context.Background(), heartbeat, nil)
+	if len(storage.taken) != 0 {
+		t.Error("expected snapshots not taken when disabled")
+	}
+}
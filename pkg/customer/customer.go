@@ -0,0 +1,104 @@
+// Package customer provides methods for creating and viewing customers owning accounts
+package customer
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/auth"
+)
+
+var (
+	// ErrorNotFound customer not found
+	ErrorNotFound = apperror.New(apperror.KindNotFound, "customer_not_found", "customer not found")
+	// ErrorNameEmpty name of customer cannot be empty
+	ErrorNameEmpty = apperror.New(
+		apperror.KindInvalidArgument, "name_empty", "name cannot be empty")
+	// ErrorNotOwner principal is not allowed to act on behalf of customer
+	ErrorNotOwner = apperror.New(
+		apperror.KindPermissionDenied, "not_owner", "principal is not allowed to act on behalf of customer")
+)
+
+// Storage interface for creating and viewing customers in database
+type Storage interface {
+	CreateCustomer(ctx context.Context, name, email string) (*Customer, error)
+	ListCustomers(ctx context.Context) ([]*Customer, error)
+	AssertCustomer(ctx context.Context, id string) (*Customer, error)
+}
+
+// Customer owner of accounts
+type Customer struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Service handles with customers
+type Service struct {
+	storage Storage
+}
+
+// New is constructor
+func New(storage Storage) *Service {
+	return &Service{
+		storage: storage,
+	}
+}
+
+// Create create customer and store in database
+func (s *Service) Create(ctx context.Context, name, email string) (*Customer, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrorNameEmpty
+	}
+
+	customer, err := s.storage.CreateCustomer(ctx, name, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return nil, errors.Wrap(err, "error on create customer")
+	}
+	return customer, nil
+}
+
+// List view customers stored in database, customer principal views only itself
+func (s *Service) List(ctx context.Context) ([]*Customer, error) {
+	if customerID := PrincipalCustomerID(ctx); customerID != "" {
+		customer, err := s.storage.AssertCustomer(ctx, customerID)
+		if err != nil {
+			return nil, err
+		}
+		return []*Customer{customer}, nil
+	}
+	return s.storage.ListCustomers(ctx)
+}
+
+// Get view customer, customer principal views only itself
+func (s *Service) Get(ctx context.Context, id string) (*Customer, error) {
+	if err := Authorize(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.storage.AssertCustomer(ctx, id)
+}
+
+// PrincipalCustomerID return customer of authenticated principal,
+// empty string when principal does not represent customer
+func PrincipalCustomerID(ctx context.Context) string {
+	if principal := auth.PrincipalFromContext(ctx); principal != nil {
+		return principal.CustomerID
+	}
+	return ""
+}
+
+// Authorize check authenticated principal is allowed to act on behalf of customer,
+// principals not representing customer, e.g. back office keys, are allowed to act on behalf of anyone
+func Authorize(ctx context.Context, customerID string) error {
+	if principalCustomerID := PrincipalCustomerID(ctx); principalCustomerID != "" &&
+		principalCustomerID != customerID {
+		return ErrorNotOwner
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package customer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sbutakov/wallet/pkg/auth"
+)
+
+type dummyStorage struct {
+}
+
+func (d *dummyStorage) CreateCustomer(_ context.Context, name, email string) (*Customer, error) {
+	return &Customer{Name: name, Email: email}, nil
+}
+
+func (d *dummyStorage) ListCustomers(context.Context) ([]*Customer, error) {
+	return []*Customer{{ID: "alice"}, {ID: "bob"}}, nil
+}
+
+func (d *dummyStorage) AssertCustomer(_ context.Context, id string) (*Customer, error) {
+	return &Customer{ID: id}, nil
+}
+
+func TestService_Create(t *testing.T) {
+	instance := New(&dummyStorage{})
+	if _, err := instance.Create(context.Background(), " ", ""); err != ErrorNameEmpty {
+		t.Error("expected error on empty name")
+	}
+
+	customer, err := instance.Create(context.Background(), "Alice", " Alice@Example.com")
+	if err != nil {
+		t.Fatal("unexpected error on create customer")
+	}
+
+	if customer.Email != "alice@example.com" {
+		t.Error("expected normalized email")
+	}
+}
+
+func TestService_Get(t *testing.T) {
+	instance := New(&dummyStorage{})
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{CustomerID: "alice"})
+	if _, err := instance.Get(ctx, "alice"); err != nil {
+		t.Error("unexpected error on get itself")
+	}
+
+	if _, err := instance.Get(ctx, "bob"); err != ErrorNotOwner {
+		t.Error("expected error on get another customer")
+	}
+
+	customers, err := instance.List(ctx)
+	if err != nil || len(customers) != 1 || customers[0].ID != "alice" {
+		t.Error("customer principal must view only itself")
+	}
+}
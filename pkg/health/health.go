@@ -0,0 +1,168 @@
+// Package health provides methods for checking state of service components
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// StatusUp component works properly
+	StatusUp = "up"
+	// StatusDown component is unavailable
+	StatusDown = "down"
+
+	defaultTimeout = 5 * time.Second
+)
+
+var (
+	// ErrorWorkerNotStarted background worker has not been started
+	ErrorWorkerNotStarted = errors.New("worker has not been started")
+	// ErrorWorkerStalled background worker does not beat
+	ErrorWorkerStalled = errors.New("worker stalled")
+)
+
+// Checker interface for checking state of component
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapter allows use ordinary function as Checker
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx)
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Component state of single service component
+type Component struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report state of service and its components
+type Report struct {
+	Status     string       `json:"status"`
+	Components []*Component `json:"components,omitempty"`
+}
+
+// Healthy report service works properly
+func (r *Report) Healthy() bool {
+	return r.Status == StatusUp
+}
+
+// Config configuration params of health service
+type Config struct {
+	Timeout time.Duration
+}
+
+type namedChecker struct {
+	name    string
+	checker Checker
+}
+
+// Service handles with health checks
+type Service struct {
+	mu       sync.RWMutex
+	checkers []namedChecker
+	timeout  time.Duration
+}
+
+// New is constructor
+func New(config Config) *Service {
+	if config.Timeout == 0 {
+		config.Timeout = defaultTimeout
+	}
+
+	return &Service{
+		timeout: config.Timeout,
+	}
+}
+
+// Register add component checker used by readiness probe
+func (s *Service) Register(name string, checker Checker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkers = append(s.checkers, namedChecker{name: name, checker: checker})
+}
+
+// Liveness report process is alive
+func (s *Service) Liveness() *Report {
+	return &Report{Status: StatusUp}
+}
+
+// Readiness check all registered components, service is ready when all of them are up
+func (s *Service) Readiness(ctx context.Context) *Report {
+	s.mu.RLock()
+	checkers := make([]namedChecker, len(s.checkers))
+	copy(checkers, s.checkers)
+	s.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	report := &Report{
+		Status:     StatusUp,
+		Components: make([]*Component, len(checkers)),
+	}
+
+	var wg sync.WaitGroup
+	for i, item := range checkers {
+		wg.Add(1)
+		go func(i int, item namedChecker) {
+			defer wg.Done()
+			component := &Component{Name: item.name, Status: StatusUp}
+			if err := item.checker.Check(ctx); err != nil {
+				component.Status = StatusDown
+				component.Error = err.Error()
+			}
+			report.Components[i] = component
+		}(i, item)
+	}
+	wg.Wait()
+
+	for _, component := range report.Components {
+		if component.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// Heartbeat checker of background worker, worker is running while it beats regularly
+type Heartbeat struct {
+	mu       sync.RWMutex
+	last     time.Time
+	interval time.Duration
+}
+
+// NewHeartbeat is constructor, interval is expected period between beats
+func NewHeartbeat(interval time.Duration) *Heartbeat {
+	return &Heartbeat{
+		interval: interval,
+	}
+}
+
+// Beat register worker is alive
+func (h *Heartbeat) Beat() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.last = time.Now()
+}
+
+// Check worker has beaten within last two intervals
+func (h *Heartbeat) Check(_ context.Context) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.last.IsZero() {
+		return ErrorWorkerNotStarted
+	}
+	if time.Since(h.last) > 2*h.interval {
+		return ErrorWorkerStalled
+	}
+	return nil
+}
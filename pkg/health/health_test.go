@@ -0,0 +1,58 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestService_Readiness(t *testing.T) {
+	instance := New(Config{})
+	instance.Register("dummy_up", CheckerFunc(func(context.Context) error {
+		return nil
+	}))
+
+	report := instance.Readiness(context.Background())
+	if !report.Healthy() {
+		t.Error("unexpected unhealthy report")
+	}
+
+	instance.Register("dummy_down", CheckerFunc(func(context.Context) error {
+		return errors.New("dummy error")
+	}))
+
+	report = instance.Readiness(context.Background())
+	if report.Healthy() {
+		t.Error("expected unhealthy report")
+	}
+
+	if len(report.Components) != 2 {
+		t.Fatal("unexpected count of components")
+	}
+
+	if report.Components[0].Status != StatusUp || report.Components[1].Status != StatusDown {
+		t.Error("unexpected components status")
+	}
+
+	if !instance.Liveness().Healthy() {
+		t.Error("unexpected unhealthy liveness report")
+	}
+}
+
+func TestHeartbeat_Check(t *testing.T) {
+	heartbeat := NewHeartbeat(time.Minute)
+	if err := heartbeat.Check(context.Background()); err != ErrorWorkerNotStarted {
+		t.Error("expected error on not started worker")
+	}
+
+	heartbeat.Beat()
+	if err := heartbeat.Check(context.Background()); err != nil {
+		t.Error("unexpected error on running worker")
+	}
+
+	heartbeat.last = time.Now().Add(-3 * time.Minute)
+	if err := heartbeat.Check(context.Background()); err != ErrorWorkerStalled {
+		t.Error("expected error on stalled worker")
+	}
+}
@@ -0,0 +1,310 @@
+// Package importer provides bulk import of accounts from CSV, every row is validated by rules
+// of account service before anything is stored, rows are created in single transaction or in chunks
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/validation"
+)
+
+// columns of CSV, header row is required, external reference column is optional
+const (
+	ColumnName              = "name"
+	ColumnCurrency          = "currency"
+	ColumnBalance           = "balance"
+	ColumnExternalReference = "external_reference"
+)
+
+// MaxRows limit of rows imported at once
+const MaxRows = 100000
+
+var (
+	// ErrorHeader header row misses required columns or has unknown columns
+	ErrorHeader = apperror.New(apperror.KindInvalidArgument, "invalid_import_header",
+		"header must list columns name, currency, balance and optional external_reference")
+	// ErrorMalformed file cannot be read as CSV
+	ErrorMalformed = apperror.New(apperror.KindInvalidArgument, "malformed_import", "malformed csv")
+	// ErrorTooManyRows file has more than MaxRows rows
+	ErrorTooManyRows = apperror.New(apperror.KindTooLarge, "too_many_import_rows", "too many rows to import")
+	// ErrorDuplicateExternalReference account with external reference exists or it is repeated in file
+	ErrorDuplicateExternalReference = apperror.New(apperror.KindConflict, "duplicate_external_reference",
+		"account with external reference already exists")
+	// ErrorNotImported row is valid, but it is not created because import of other rows failed
+	ErrorNotImported = apperror.New(apperror.KindFailedPrecondition, "not_imported",
+		"row is not imported because other rows failed")
+)
+
+// Row account to import, line is number of line of row in file
+type Row struct {
+	Line              int     `json:"-"`
+	CustomerID        string  `json:"-"`
+	Name              string  `json:"name" validate:"required,max=50,name"`
+	Currency          string  `json:"currency" validate:"required,currency"`
+	Balance           float64 `json:"balance" validate:"precision=2"`
+	ExternalReference string  `json:"external_reference" validate:"max=64"`
+
+	// malformed fields which cannot be parsed
+	malformed []apperror.FieldError
+}
+
+// Options mode of import, dry run only validates rows, rows are created in chunks of size
+// when it is set, otherwise all rows are created in single transaction and nothing
+// is created when any row is invalid
+type Options struct {
+	DryRun    bool
+	ChunkSize int
+}
+
+// Account account created of row
+type Account struct {
+	Line              int    `json:"line"`
+	ExternalReference string `json:"external_reference,omitempty"`
+	AccountID         string `json:"account_id"`
+}
+
+// RowError reason of rejecting row
+type RowError struct {
+	Line              int                   `json:"line"`
+	ExternalReference string                `json:"external_reference,omitempty"`
+	Code              string                `json:"code"`
+	Detail            string                `json:"detail"`
+	InvalidParams     []apperror.FieldError `json:"invalid_params,omitempty"`
+}
+
+// Report result of import
+type Report struct {
+	DryRun   bool        `json:"dry_run"`
+	Rows     int         `json:"rows"`
+	Valid    int         `json:"valid"`
+	Created  int         `json:"created"`
+	Failed   int         `json:"failed"`
+	Accounts []*Account  `json:"accounts"`
+	Errors   []*RowError `json:"errors"`
+}
+
+// Validator check rules of account service, implemented by account.Service
+type Validator interface {
+	Check(ctx context.Context, customerID, currency string, balance float64) (string, error)
+}
+
+// Storage interface for creating imported accounts
+type Storage interface {
+	ExistingExternalReferences(ctx context.Context, references []string) ([]string, error)
+	ImportAccounts(ctx context.Context, rows []*Row) ([]*account.Account, error)
+}
+
+// Service imports accounts
+type Service struct {
+	validator Validator
+	storage   Storage
+}
+
+// New is constructor
+func New(validator Validator, storage Storage) *Service {
+	return &Service{
+		validator: validator,
+		storage:   storage,
+	}
+}
+
+// Import read rows of CSV, validate them and create accounts unless it is dry run,
+// errors of rows are reported, error is returned only when file cannot be imported at all
+func (s *Service) Import(ctx context.Context, r io.Reader, opts Options) (*Report, error) {
+	rows, err := readRows(r)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{DryRun: opts.DryRun, Rows: len(rows), Accounts: []*Account{}, Errors: []*RowError{}}
+	valid, err := s.validate(ctx, rows, report)
+	if err != nil {
+		return nil, err
+	}
+	report.Valid = len(valid)
+	if opts.DryRun {
+		return report, nil
+	}
+
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		if len(report.Errors) > 0 {
+			report.fail(valid, ErrorNotImported)
+			return report, nil
+		}
+		chunkSize = len(valid)
+	}
+
+	for start := 0; start < len(valid); start += chunkSize {
+		end := start + chunkSize
+		if end > len(valid) {
+			end = len(valid)
+		}
+		chunk := valid[start:end]
+		if err = ctx.Err(); err != nil {
+			report.fail(valid[start:], err)
+			break
+		}
+
+		accounts, err := s.storage.ImportAccounts(ctx, chunk)
+		if err != nil {
+			report.fail(chunk, err)
+			continue
+		}
+		for i, acc := range accounts {
+			report.Accounts = append(report.Accounts, &Account{
+				Line:              chunk[i].Line,
+				ExternalReference: chunk[i].ExternalReference,
+				AccountID:         acc.ID,
+			})
+		}
+		report.Created += len(accounts)
+	}
+	return report, nil
+}
+
+// validate check rows by rules of request fields and account service, rejected rows are
+// reported, returns valid rows
+func (s *Service) validate(ctx context.Context, rows []*Row, report *Report) ([]*Row, error) {
+	var references []string
+	for _, row := range rows {
+		if row.ExternalReference != "" {
+			references = append(references, row.ExternalReference)
+		}
+	}
+	existing := map[string]bool{}
+	if len(references) > 0 {
+		found, err := s.storage.ExistingExternalReferences(ctx, references)
+		if err != nil {
+			return nil, errors.Wrap(err, "error on find external references")
+		}
+		for _, reference := range found {
+			existing[reference] = true
+		}
+	}
+
+	var valid []*Row
+	for _, row := range rows {
+		err := validation.Validate(row)
+		if len(row.malformed) > 0 {
+			err = &apperror.Error{
+				Kind:    apperror.KindInvalidArgument,
+				Code:    validation.CodeValidationFailed,
+				Message: "row validation failed",
+				Fields:  row.malformed,
+			}
+		}
+		if err == nil {
+			row.Currency = strings.ToLower(row.Currency)
+			row.CustomerID, err = s.validator.Check(ctx, row.CustomerID, row.Currency, row.Balance)
+		}
+		if err == nil && row.ExternalReference != "" {
+			if existing[row.ExternalReference] {
+				err = ErrorDuplicateExternalReference
+			}
+			existing[row.ExternalReference] = true
+		}
+		if err != nil {
+			report.reject(row, err)
+			continue
+		}
+		valid = append(valid, row)
+	}
+	return valid, nil
+}
+
+func (r *Report) reject(row *Row, err error) {
+	e := apperror.From(err)
+	r.Errors = append(r.Errors, &RowError{
+		Line:              row.Line,
+		ExternalReference: row.ExternalReference,
+		Code:              e.Code,
+		Detail:            e.Message,
+		InvalidParams:     e.Fields,
+	})
+	r.Failed++
+}
+
+func (r *Report) fail(rows []*Row, err error) {
+	for _, row := range rows {
+		r.reject(row, err)
+	}
+}
+
+// readRows read all rows of CSV, so that rows are validated before any is created
+func readRows(r io.Reader) ([]*Row, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, ErrorHeader
+	}
+	if err != nil {
+		return nil, malformed(err)
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		switch column {
+		case ColumnName, ColumnCurrency, ColumnBalance, ColumnExternalReference:
+			columns[column] = i
+		default:
+			return nil, ErrorHeader
+		}
+	}
+	for _, column := range []string{ColumnName, ColumnCurrency, ColumnBalance} {
+		if _, ok := columns[column]; !ok {
+			return nil, ErrorHeader
+		}
+	}
+
+	var rows []*Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, malformed(err)
+		}
+		if len(rows) == MaxRows {
+			return nil, ErrorTooManyRows
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := &Row{
+			Line:     line,
+			Name:     strings.TrimSpace(record[columns[ColumnName]]),
+			Currency: strings.TrimSpace(record[columns[ColumnCurrency]]),
+		}
+		if i, ok := columns[ColumnExternalReference]; ok {
+			row.ExternalReference = strings.TrimSpace(record[i])
+		}
+		balance := strings.TrimSpace(record[columns[ColumnBalance]])
+		if row.Balance, err = strconv.ParseFloat(balance, 64); err != nil {
+			row.malformed = append(row.malformed, apperror.FieldError{Field: ColumnBalance, Reason: "must be number"})
+		}
+		rows = append(rows, row)
+	}
+}
+
+// malformed error of parsing CSV, errors of reading are returned as is
+func malformed(err error) error {
+	if _, ok := err.(*csv.ParseError); !ok {
+		return errors.Wrap(err, "error on read rows")
+	}
+	return &apperror.Error{
+		Kind:    ErrorMalformed.Kind,
+		Code:    ErrorMalformed.Code,
+		Message: ErrorMalformed.Message + ": " + err.Error(),
+	}
+}
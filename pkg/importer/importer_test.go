@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/metadata"
+	"github.com/sbutakov/wallet/pkg/validation"
+)
+
+type dummyStorage struct {
+	existing []string
+	chunks   [][]*Row
+	fail     bool
+}
+
+func (d *dummyStorage) CreateAccount(
+	context.Context, string, string, string, float64, string, metadata.Metadata) (*account.Account, error) {
+
+	return &account.Account{}, nil
+}
+
+func (d *dummyStorage) ListAccount(context.Context, metadata.Filter) ([]*account.Account, error) {
+	return nil, nil
+}
+
+func (d *dummyStorage) ListCustomerAccounts(context.Context, string, metadata.Filter) ([]*account.Account, error) {
+	return nil, nil
+}
+
+func (d *dummyStorage) ExistingExternalReferences(context.Context, []string) ([]string, error) {
+	return d.existing, nil
+}
+
+func (d *dummyStorage) ImportAccounts(_ context.Context, rows []*Row) ([]*account.Account, error) {
+	if d.fail && len(d.chunks) > 0 {
+		return nil, errors.New("dummy")
+	}
+	d.chunks = append(d.chunks, rows)
+	accounts := make([]*account.Account, len(rows))
+	for i, row := range rows {
+		accounts[i] = &account.Account{ID: row.Name}
+	}
+	return accounts, nil
+}
+
+func newService(t *testing.T, storage *dummyStorage) *Service {
+	accounts, err := account.New(account.Config{AllowedCurrency: []string{"usd"}}, storage)
+	if err != nil {
+		t.Fatal("unexpected error on init account service")
+	}
+	return New(accounts, storage)
+}
+
+const rows = `name,currency,balance,external_reference
+first,USD,100.50,ref-1
+second,eur,10,ref-2
+third,usd,-1,
+fourth,usd,abc,
+fifth,usd,1,ref-1
+sixth,usd,1,ref-3
+seventh,usd,2.5,ref-4
+`
+
+func TestService_Import(t *testing.T) {
+	storage := &dummyStorage{existing: []string{"ref-3"}}
+	report, err := newService(t, storage).Import(context.Background(), strings.NewReader(rows), Options{DryRun: true})
+	if err != nil {
+		t.Fatal("unexpected error on import")
+	}
+	if report.Rows != 7 || report.Valid != 2 || report.Failed != 5 || report.Created != 0 || len(storage.chunks) != 0 {
+		t.Errorf("unexpected report of dry run %+v", report)
+	}
+
+	expected := map[int]string{
+		3: account.ErrorUnsupportedCurrency.Code,
+		4: account.ErrorBalanceValue.Code,
+		5: validation.CodeValidationFailed,
+		6: ErrorDuplicateExternalReference.Code,
+		7: ErrorDuplicateExternalReference.Code,
+	}
+	for _, rowError := range report.Errors {
+		if expected[rowError.Line] != rowError.Code {
+			t.Errorf("unexpected error %s of line %d", rowError.Code, rowError.Line)
+		}
+	}
+
+	report, err = newService(t, storage).Import(context.Background(), strings.NewReader(rows), Options{})
+	if err != nil || report.Created != 0 || report.Failed != 7 || len(storage.chunks) != 0 {
+		t.Errorf("expected nothing created in single transaction with invalid rows, got %+v", report)
+	}
+
+	report, err = newService(t, storage).Import(context.Background(), strings.NewReader(rows), Options{ChunkSize: 1})
+	if err != nil || report.Created != 2 || len(storage.chunks) != 2 {
+		t.Fatalf("expected valid rows created in chunks, got %+v", report)
+	}
+	if report.Accounts[0].Line != 2 || report.Accounts[0].AccountID != "first" ||
+		report.Accounts[0].ExternalReference != "ref-1" || storage.chunks[0][0].Currency != "usd" {
+		t.Errorf("unexpected imported account %+v", report.Accounts[0])
+	}
+
+	storage = &dummyStorage{fail: true}
+	report, err = newService(t, storage).Import(context.Background(),
+		strings.NewReader("name,balance,currency\nfirst,1,usd\nsecond,2,usd\nthird,3,usd\n"), Options{ChunkSize: 2})
+	if err != nil || report.Created != 2 || report.Failed != 1 || report.Errors[0].Line != 4 {
+		t.Errorf("expected rows of failed chunk reported, got %+v", report)
+	}
+}
+
+func TestService_Import_Malformed(t *testing.T) {
+	for name, data := range map[string]string{
+		"empty":            "",
+		"unknown column":   "name,currency,balance,owner\n",
+		"missing column":   "name,currency\n",
+		"malformed":        "name,currency,balance\n\"dummy,usd,1\n",
+		"wrong row length": "name,currency,balance\ndummy,usd\n",
+	} {
+		if _, err := newService(t, &dummyStorage{}).Import(
+			context.Background(), strings.NewReader(data), Options{}); err == nil {
+			t.Errorf("expected %s file rejected", name)
+		}
+	}
+}
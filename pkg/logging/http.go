@@ -0,0 +1,137 @@
+package logging
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"unicode"
+
+	"github.com/go-chi/chi"
+	"github.com/rs/zerolog"
+	"github.com/satori/go.uuid"
+)
+
+const (
+	// RequestIDHeader header carries request identifier
+	RequestIDHeader = "X-Request-ID"
+
+	maxRequestIDLength = 128
+)
+
+// RequestIDFromContext return identifier of request stored in context
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKeyRequestID).(string)
+	return id
+}
+
+// WithRequestID store request identifier in context
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKeyRequestID, id)
+}
+
+// ContextWithRequestID store request identifier and logger with request identifier in context,
+// new identifier is generated when id is not valid
+func ContextWithRequestID(ctx context.Context, logger *zerolog.Logger, id string) (context.Context, string) {
+	if !validRequestID(id) {
+		id = uuid.NewV4().String()
+	}
+	requestLogger := logger.With().Str("request_id", id).Logger()
+	return WithLogger(WithRequestID(ctx, id), &requestLogger), id
+}
+
+// RequestID middleware accepts request identifier from header or generates new one,
+// returns it in response header and stores logger with request identifier in request context
+func RequestID(logger *zerolog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, id := ContextWithRequestID(r.Context(), logger, r.Header.Get(RequestIDHeader))
+			w.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// AccessLog middleware writes record of every handled request
+func AccessLog(logger *zerolog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			begin := time.Now()
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r)
+
+			var event *zerolog.Event
+			l := FromContext(r.Context(), logger)
+			switch {
+			case rw.status >= http.StatusInternalServerError:
+				event = l.Error()
+			case rw.status >= http.StatusBadRequest:
+				event = l.Warn()
+			default:
+				event = l.Info()
+			}
+
+			route := r.URL.Path
+			if rctx, ok := r.Context().Value(chi.RouteCtxKey).(*chi.Context); ok && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+
+			event.
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Str("route", route).
+				Int("status", rw.status).
+				Int("size", rw.size).
+				Dur("latency", time.Since(begin)).
+				Str("remote_addr", r.RemoteAddr).
+				Str("user_agent", r.UserAgent()).
+				Msg("access")
+		})
+	}
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	size        int
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+// Flush send buffered data to client, streamed responses are flushed through wrapper
+func (w *responseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		flusher.Flush()
+	}
+}
+
+// Unwrap return wrapped writer for http.ResponseController
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) || unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
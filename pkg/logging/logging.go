@@ -0,0 +1,91 @@
+// Package logging provides structured logger, request identifiers and access logs
+package logging
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// Config configuration params of logging
+type Config struct {
+	Level string `default:"info"`
+}
+
+// ParseLevel return configured logging level
+func (c Config) ParseLevel() (zerolog.Level, error) {
+	level, err := zerolog.ParseLevel(strings.ToLower(c.Level))
+	if err != nil {
+		return zerolog.NoLevel, errors.Wrap(err, "error on parse logging level")
+	}
+	return level, nil
+}
+
+type contextKey int
+
+const (
+	contextKeyLogger contextKey = iota
+	contextKeyRequestID
+)
+
+// WithLogger store logger in context
+func WithLogger(ctx context.Context, logger *zerolog.Logger) context.Context {
+	return context.WithValue(ctx, contextKeyLogger, logger)
+}
+
+// FromContext return logger stored in context by request middleware or fallback logger
+func FromContext(ctx context.Context, fallback *zerolog.Logger) *zerolog.Logger {
+	if logger, ok := ctx.Value(contextKeyLogger).(*zerolog.Logger); ok {
+		return logger
+	}
+	return fallback
+}
+
+type kitLogger struct {
+	logger *zerolog.Logger
+}
+
+// NewKitLogger adapter allows go-kit components write to zerolog logger,
+// records having "err" key are written with error level
+func NewKitLogger(logger *zerolog.Logger) kitlog.Logger {
+	return &kitLogger{
+		logger: logger,
+	}
+}
+
+func (l *kitLogger) Log(keyvals ...interface{}) error {
+	event := l.logger.Info()
+	for i := 0; i < len(keyvals); i += 2 {
+		if key, ok := keyvals[i].(string); ok && key == "err" {
+			event = l.logger.Error()
+			break
+		}
+	}
+
+	for i := 0; i < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		var value interface{} = kitlog.ErrMissingValue
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+
+		switch v := value.(type) {
+		case error:
+			if key == "err" {
+				event = event.Err(v)
+				continue
+			}
+			event = event.AnErr(key, v)
+		case string:
+			event = event.Str(key, v)
+		default:
+			event = event.Interface(key, v)
+		}
+	}
+	event.Msg("")
+	return nil
+}
@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestRequestID(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := zerolog.New(buf)
+
+	var requestID string
+	handler := RequestID(&logger)(AccessLog(&logger)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID = RequestIDFromContext(r.Context())
+			w.WriteHeader(http.StatusTeapot)
+		})))
+
+	r := httptest.NewRequest(http.MethodGet, "/dummy", nil)
+	r.Header.Set(RequestIDHeader, "dummy-id")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if requestID != "dummy-id" || w.Header().Get(RequestIDHeader) != "dummy-id" {
+		t.Error("expected request identifier accepted from header")
+	}
+
+	record := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal("unexpected error on decode access log")
+	}
+
+	if record["request_id"] != "dummy-id" || record["status"] != float64(http.StatusTeapot) {
+		t.Error("unexpected access log record")
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/dummy", nil)
+	r.Header.Set(RequestIDHeader, "invalid id\n")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if requestID == "" || requestID == "invalid id\n" || w.Header().Get(RequestIDHeader) != requestID {
+		t.Error("expected generated request identifier")
+	}
+}
+
+func TestAccessLog_Flush(t *testing.T) {
+	logger := zerolog.New(&bytes.Buffer{})
+
+	var flushed, unwrapped bool
+	handler := AccessLog(&logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if ok {
+			w.Write([]byte("dummy"))
+			flusher.Flush()
+		}
+		_, unwrapped = w.(interface{ Unwrap() http.ResponseWriter })
+		flushed = ok
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dummy", nil))
+	if !flushed || !w.Flushed || !unwrapped {
+		t.Error("expected wrapped writer flushed and unwrapped")
+	}
+}
+
+func TestNewKitLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := zerolog.New(buf)
+	NewKitLogger(&logger).Log("err", errors.New("dummy error"), "key", "value") // nolint: errcheck
+
+	record := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal("unexpected error on decode log record")
+	}
+
+	if record["level"] != "error" || record["error"] != "dummy error" || record["key"] != "value" {
+		t.Error("unexpected log record")
+	}
+}
+
+func TestConfig_ParseLevel(t *testing.T) {
+	level, err := Config{Level: "WARN"}.ParseLevel()
+	if err != nil || level != zerolog.WarnLevel {
+		t.Error("unexpected parsed level")
+	}
+
+	if _, err = (Config{Level: "dummy"}).ParseLevel(); err == nil {
+		t.Error("expected error on parse unknown level")
+	}
+}
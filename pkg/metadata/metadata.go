@@ -0,0 +1,28 @@
+// Package metadata provides external identifiers and key-value metadata set by API clients
+// on accounts and payments for correlating them with entities of client systems
+package metadata
+
+import (
+	"github.com/sbutakov/wallet/pkg/apperror"
+)
+
+// limits of metadata, checked by metadata rule of validation
+const (
+	MaxKeys        = 20
+	MaxKeyLength   = 40
+	MaxValueLength = 500
+)
+
+// ErrorDuplicateExternalID entity with external identifier already exists
+var ErrorDuplicateExternalID = apperror.New(
+	apperror.KindConflict, "duplicate_external_id", "entity with external id already exists")
+
+// Metadata key-value pairs of entity, nil and empty metadata are same
+type Metadata map[string]string
+
+// Filter filter of listed entities, entities match filter when they have external identifier
+// and contain all pairs of metadata, empty fields of filter match all entities
+type Filter struct {
+	ExternalID string
+	Metadata   Metadata
+}
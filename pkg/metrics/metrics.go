@@ -0,0 +1,409 @@
+// Package metrics provides counters, gauges and histograms exposed in prometheus text format
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets default histogram buckets of latency in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Counter metric which value only increases
+type Counter interface {
+	With(labelValues ...string) Counter
+	Add(delta float64)
+}
+
+// Gauge metric which value can arbitrary go up and down
+type Gauge interface {
+	With(labelValues ...string) Gauge
+	Set(value float64)
+	Add(delta float64)
+}
+
+// Histogram metric which samples observations and counts them in buckets
+type Histogram interface {
+	With(labelValues ...string) Histogram
+	Observe(value float64)
+}
+
+type collector interface {
+	name() string
+	write(w io.Writer) error
+}
+
+// Registry stores metrics and exposes them
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]collector
+}
+
+// NewRegistry is constructor
+func NewRegistry() *Registry {
+	return &Registry{
+		collectors: make(map[string]collector),
+	}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.collectors[c.name()]; ok {
+		panic(fmt.Sprintf("metric %s already registered", c.name()))
+	}
+	r.collectors[c.name()] = c
+}
+
+// NewCounter register counter with label names
+func (r *Registry) NewCounter(name, help string, labelNames ...string) Counter {
+	f := newFamily(name, help, "counter", labelNames)
+	r.register(f)
+	return &counter{family: f}
+}
+
+// NewGauge register gauge with label names
+func (r *Registry) NewGauge(name, help string, labelNames ...string) Gauge {
+	f := newFamily(name, help, "gauge", labelNames)
+	r.register(f)
+	return &gauge{family: f}
+}
+
+// NewGaugeFunc register gauge which value is taken from function on every exposition
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&gaugeFunc{
+		metricName: name,
+		help:       help,
+		fn:         fn,
+	})
+}
+
+// NewHistogram register histogram with buckets and label names
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labelNames ...string) Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := make([]float64, len(buckets))
+	copy(sorted, buckets)
+	sort.Float64s(sorted)
+
+	h := &histogramFamily{
+		family:  newFamily(name, help, "histogram", labelNames),
+		buckets: sorted,
+		samples: make(map[string]*histogramSeries),
+	}
+	r.register(h)
+	return &histogram{family: h}
+}
+
+// Write write all registered metrics in prometheus text format
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.RLock()
+	collectors := make([]collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		collectors = append(collectors, c)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(collectors, func(i, j int) bool {
+		return collectors[i].name() < collectors[j].name()
+	})
+
+	buf := bufio.NewWriter(w)
+	for _, c := range collectors {
+		if err := c.write(buf); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
+
+// Handler http handler exposes metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		r.Write(w) // nolint: errcheck
+	})
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+}
+
+type family struct {
+	mu         sync.Mutex
+	metricName string
+	help       string
+	kind       string
+	labelNames []string
+	series     map[string]*series
+}
+
+func newFamily(name, help, kind string, labelNames []string) *family {
+	return &family{
+		metricName: name,
+		help:       help,
+		kind:       kind,
+		labelNames: labelNames,
+		series:     make(map[string]*series),
+	}
+}
+
+func (f *family) name() string {
+	return f.metricName
+}
+
+// values return label values ordered as label names of family
+func (f *family) values(labelValues []string) []string {
+	if len(labelValues)%2 != 0 {
+		panic("label values must be key-value pairs")
+	}
+	values := make([]string, len(f.labelNames))
+	for i := 0; i < len(labelValues); i += 2 {
+		for j, name := range f.labelNames {
+			if name == labelValues[i] {
+				values[j] = labelValues[i+1]
+			}
+		}
+	}
+	return values
+}
+
+func (f *family) update(labelValues []string, fn func(value float64) float64) {
+	key := strings.Join(labelValues, "\xff")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: labelValues}
+		f.series[key] = s
+	}
+	s.value = fn(s.value)
+}
+
+func (f *family) write(w io.Writer) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := writeHeader(w, f.metricName, f.help, f.kind); err != nil {
+		return err
+	}
+
+	for _, key := range sortedKeys(f.series) {
+		s := f.series[key]
+		_, err := fmt.Fprintf(w, "%s%s %s\n",
+			f.metricName, formatLabels(f.labelNames, s.labelValues, "", ""), formatValue(s.value))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type counter struct {
+	family      *family
+	labelValues []string
+}
+
+func (c *counter) With(labelValues ...string) Counter {
+	return &counter{
+		family:      c.family,
+		labelValues: append(append([]string{}, c.labelValues...), labelValues...),
+	}
+}
+
+func (c *counter) Add(delta float64) {
+	if delta < 0 {
+		panic("counter cannot decrease")
+	}
+	c.family.update(c.family.values(c.labelValues), func(value float64) float64 {
+		return value + delta
+	})
+}
+
+type gauge struct {
+	family      *family
+	labelValues []string
+}
+
+func (g *gauge) With(labelValues ...string) Gauge {
+	return &gauge{
+		family:      g.family,
+		labelValues: append(append([]string{}, g.labelValues...), labelValues...),
+	}
+}
+
+func (g *gauge) Set(value float64) {
+	g.family.update(g.family.values(g.labelValues), func(float64) float64 {
+		return value
+	})
+}
+
+func (g *gauge) Add(delta float64) {
+	g.family.update(g.family.values(g.labelValues), func(value float64) float64 {
+		return value + delta
+	})
+}
+
+type gaugeFunc struct {
+	metricName string
+	help       string
+	fn         func() float64
+}
+
+func (g *gaugeFunc) name() string {
+	return g.metricName
+}
+
+func (g *gaugeFunc) write(w io.Writer) error {
+	if err := writeHeader(w, g.metricName, g.help, "gauge"); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%s %s\n", g.metricName, formatValue(g.fn()))
+	return err
+}
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+type histogramFamily struct {
+	*family
+	buckets []float64
+	samples map[string]*histogramSeries
+}
+
+func (h *histogramFamily) observe(labelValues []string, value float64) {
+	key := strings.Join(labelValues, "\xff")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.samples[key]
+	if !ok {
+		s = &histogramSeries{
+			labelValues: labelValues,
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.samples[key] = s
+	}
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += value
+}
+
+func (h *histogramFamily) write(w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := writeHeader(w, h.metricName, h.help, h.kind); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(h.samples))
+	for key := range h.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := h.samples[key]
+		for i, bound := range h.buckets {
+			_, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName,
+				formatLabels(h.labelNames, s.labelValues, "le", formatValue(bound)), s.counts[i])
+			if err != nil {
+				return err
+			}
+		}
+
+		labels := formatLabels(h.labelNames, s.labelValues, "", "")
+		_, err := fmt.Fprintf(w, "%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+			h.metricName, formatLabels(h.labelNames, s.labelValues, "le", "+Inf"), s.count,
+			h.metricName, labels, formatValue(s.sum),
+			h.metricName, labels, s.count)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type histogram struct {
+	family      *histogramFamily
+	labelValues []string
+}
+
+func (h *histogram) With(labelValues ...string) Histogram {
+	return &histogram{
+		family:      h.family,
+		labelValues: append(append([]string{}, h.labelValues...), labelValues...),
+	}
+}
+
+func (h *histogram) Observe(value float64) {
+	h.family.observe(h.family.values(h.labelValues), value)
+}
+
+func writeHeader(w io.Writer, name, help, kind string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, escape(help, false), name, kind)
+	return err
+}
+
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		pairs = append(pairs, name+`="`+escape(values[i], true)+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+extraValue+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func escape(s string, quote bool) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	if quote {
+		s = strings.Replace(s, `"`, `\"`, -1)
+	}
+	return s
+}
+
+func sortedKeys(m map[string]*series) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
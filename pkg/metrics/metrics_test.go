@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_Write(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounter("dummy_total", "Dummy counter.", "code")
+	counter.With("code", "200").Add(2)
+	counter.With("code", "500").Add(1)
+
+	histogram := registry.NewHistogram("dummy_seconds", "Dummy histogram.", []float64{1, 0.5})
+	histogram.Observe(0.3)
+	histogram.Observe(0.7)
+
+	registry.NewGaugeFunc("dummy_gauge", "Dummy gauge.", func() float64 {
+		return 42
+	})
+
+	buf := &bytes.Buffer{}
+	if err := registry.Write(buf); err != nil {
+		t.Fatal("unexpected error on write metrics")
+	}
+
+	expected := []string{
+		"# TYPE dummy_total counter",
+		`dummy_total{code="200"} 2`,
+		`dummy_total{code="500"} 1`,
+		"# TYPE dummy_seconds histogram",
+		`dummy_seconds_bucket{le="0.5"} 1`,
+		`dummy_seconds_bucket{le="1"} 2`,
+		`dummy_seconds_bucket{le="+Inf"} 2`,
+		"dummy_seconds_sum 1",
+		"dummy_seconds_count 2",
+		"dummy_gauge 42",
+	}
+	for _, line := range expected {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("expected line %q in exposition", line)
+		}
+	}
+}
+
+func TestRegistry_Duplicate(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounter("dummy_total", "Dummy counter.")
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on duplicate registration")
+		}
+	}()
+	registry.NewCounter("dummy_total", "Dummy counter.")
+}
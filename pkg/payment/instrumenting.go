@@ -0,0 +1,86 @@
+package payment
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/metrics"
+)
+
+type instrumentingService struct {
+	transfers        metrics.Counter
+	transfersAmount  metrics.Counter
+	transferFailures metrics.Counter
+	transferDuration metrics.Histogram
+	next             Interface
+}
+
+// NewInstrumentingService decorate service with collecting metrics of transfers, successful
+// transfers are counted by currency and failed transfers by outcome
+func NewInstrumentingService(registry *metrics.Registry, next Interface) Interface {
+	return &instrumentingService{
+		transfers: registry.NewCounter(
+			"wallet_transfers_total",
+			"Count of successful money transfers.",
+			"currency"),
+		transfersAmount: registry.NewCounter(
+			"wallet_transfers_amount_total",
+			"Amount of transferred money.",
+			"currency"),
+		transferFailures: registry.NewCounter(
+			"wallet_transfer_failures_total",
+			"Count of failed money transfers.",
+			"outcome"),
+		transferDuration: registry.NewHistogram(
+			"wallet_transfer_duration_seconds",
+			"Duration of money transfers in seconds.",
+			metrics.DefaultBuckets,
+			"outcome"),
+		next: next,
+	}
+}
+
+func (s *instrumentingService) TransferMoney(ctx context.Context, accountFromID, accountToID string,
+	amount float64, details Details) (result *Payment, err error) {
+
+	defer func(begin time.Time) {
+		outcome := transferOutcome(err)
+		if err != nil {
+			s.transferFailures.With("outcome", outcome).Add(1)
+		} else {
+			s.transfers.With("currency", result.Currency).Add(1)
+			s.transfersAmount.With("currency", result.Currency).Add(amount)
+		}
+		s.transferDuration.With("outcome", outcome).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return s.next.TransferMoney(ctx, accountFromID, accountToID, amount, details)
+}
+
+func (s *instrumentingService) PaymentList(ctx context.Context, filter Filter) ([]*Payment, error) {
+	return s.next.PaymentList(ctx, filter)
+}
+
+func (s *instrumentingService) StreamPayments(ctx context.Context, filter Filter, sink Sink) error {
+	return s.next.StreamPayments(ctx, filter, sink)
+}
+
+func transferOutcome(err error) string {
+	switch errors.Cause(err) {
+	case nil:
+		return "success"
+	case ErrorNotEnoughMoney:
+		return "not_enough_money"
+	case ErrorDifferentCurrencies:
+		return "different_currencies"
+	case ErrorIncorrectAmount:
+		return "incorrect_amount"
+	case ErrorTransferYourself:
+		return "transfer_yourself"
+	case account.ErrorNotFound:
+		return "account_not_found"
+	}
+	return "error"
+}
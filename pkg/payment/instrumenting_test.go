@@ -0,0 +1,42 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/metrics"
+)
+
+func TestInstrumentingService_TransferMoney(t *testing.T) {
+	storage := &dummyStorage{
+		accounts: map[string]account.Account{
+			"dummy_from": {ID: "dummy_from", Currency: "usd"},
+			dummyTo:      {ID: dummyTo, Currency: "usd"},
+		},
+	}
+
+	registry := metrics.NewRegistry()
+	instance := NewInstrumentingService(registry, New(storage))
+	if _, err := instance.TransferMoney(context.Background(), "dummy_from", dummyTo, 1, Details{}); err != nil {
+		t.Error("unexpected error on transfer money")
+	}
+
+	if _, err := instance.TransferMoney(context.Background(), "dummy_from", "dummy_from", 1, Details{}); err != ErrorTransferYourself {
+		t.Error("unexpected error of decorated service")
+	}
+
+	buf := &bytes.Buffer{}
+	if err := registry.Write(buf); err != nil {
+		t.Fatal("unexpected error on write metrics")
+	}
+
+	if !strings.Contains(buf.String(), `wallet_transfers_total{currency="usd"} 1`) {
+		t.Error("expected successful transfer in metrics")
+	}
+	if !strings.Contains(buf.String(), `wallet_transfer_failures_total{outcome="transfer_yourself"} 1`) {
+		t.Error("expected failed transfer in metrics")
+	}
+}
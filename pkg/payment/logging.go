@@ -0,0 +1,59 @@
+package payment
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+
+	"github.com/sbutakov/wallet/pkg/logging"
+)
+
+type loggingService struct {
+	logger *zerolog.Logger
+	next   Interface
+}
+
+// NewLoggingService decorate service with audit log of transfers
+func NewLoggingService(logger *zerolog.Logger, next Interface) Interface {
+	return &loggingService{
+		logger: logger,
+		next:   next,
+	}
+}
+
+func (s *loggingService) TransferMoney(ctx context.Context, accountFromID, accountToID string, amount float64,
+	details Details) (*Payment, error) {
+
+	result, err := s.next.TransferMoney(ctx, accountFromID, accountToID, amount, details)
+	logger := logging.FromContext(ctx, s.logger)
+	if err != nil {
+		logger.Warn().
+			Err(err).
+			Str("account_from", accountFromID).
+			Str("account_to", accountToID).
+			Float64("amount", amount).
+			Str("external_id", details.ExternalID).
+			Str("category", details.Category).
+			Msg("transfer money failed")
+		return result, err
+	}
+
+	logger.Info().
+		Str("payment_id", result.ID).
+		Str("account_from", accountFromID).
+		Str("account_to", accountToID).
+		Float64("amount", amount).
+		Str("currency", result.Currency).
+		Str("external_id", details.ExternalID).
+		Str("category", details.Category).
+		Msg("transfer money")
+	return result, nil
+}
+
+func (s *loggingService) PaymentList(ctx context.Context, filter Filter) ([]*Payment, error) {
+	return s.next.PaymentList(ctx, filter)
+}
+
+func (s *loggingService) StreamPayments(ctx context.Context, filter Filter, sink Sink) error {
+	return s.next.StreamPayments(ctx, filter, sink)
+}
@@ -2,40 +2,131 @@
 package payment
 
 import (
-	"errors"
+	"context"
+	"strings"
 	"time"
 
 	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/alias"
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/customer"
+	"github.com/sbutakov/wallet/pkg/metadata"
 )
 
 var (
 	// ErrorDifferentCurrencies different currencies
-	ErrorDifferentCurrencies = errors.New("different currencies")
+	ErrorDifferentCurrencies = apperror.New(
+		apperror.KindFailedPrecondition, "currency_mismatch", "different currencies")
 	// ErrorIncorrectAmount amount must be greater than zero
-	ErrorIncorrectAmount = errors.New("amount must be greater than zero")
+	ErrorIncorrectAmount = apperror.New(
+		apperror.KindInvalidArgument, "invalid_amount", "amount must be greater than zero")
 	// ErrorNotEnoughMoney not enough money
-	ErrorNotEnoughMoney = errors.New("not enough money")
+	ErrorNotEnoughMoney = apperror.New(
+		apperror.KindFailedPrecondition, "insufficient_funds", "not enough money")
 	// ErrorTransferYourself sending to yourself
-	ErrorTransferYourself = errors.New("sending to yourself")
+	ErrorTransferYourself = apperror.New(
+		apperror.KindInvalidArgument, "transfer_to_self", "sending to yourself")
 	// ErrorMoneyTransfer error money transfer
-	ErrorMoneyTransfer = errors.New("error money transfer")
+	ErrorMoneyTransfer = apperror.New(
+		apperror.KindInternal, "transfer_failed", "error money transfer")
+	// ErrorUnknownCategory category is not one of Categories
+	ErrorUnknownCategory = apperror.New(
+		apperror.KindInvalidArgument, "unknown_category", "unknown payment category")
+	// ErrorReservedExternalID external identifier of transfer has reserved prefix
+	ErrorReservedExternalID = apperror.New(
+		apperror.KindInvalidArgument, "reserved_external_id", "external id prefix is reserved for payment requests")
 )
 
+// ReservedExternalIDPrefix prefix of external identifiers of transfers accepting payment requests,
+// it is set only by transfers of context returned by WithReservedExternalID
+const ReservedExternalIDPrefix = "payment_request:"
+
+type contextKey int
+
+const contextKeyReservedExternalID contextKey = iota
+
+// WithReservedExternalID return context allowing transfers to set external identifier with reserved prefix
+func WithReservedExternalID(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKeyReservedExternalID, true)
+}
+
+// IsReservedExternalID external identifier has reserved prefix
+func IsReservedExternalID(externalID string) bool {
+	return strings.HasPrefix(externalID, ReservedExternalIDPrefix)
+}
+
+// categories of payments
+const (
+	CategoryTransfer = "transfer"
+	CategoryPurchase = "purchase"
+	CategoryRefund   = "refund"
+	CategorySalary   = "salary"
+	CategoryBills    = "bills"
+	CategoryOther    = "other"
+)
+
+// Categories known categories of payments, category of payment is optional
+var Categories = []string{
+	CategoryTransfer, CategoryPurchase, CategoryRefund, CategorySalary, CategoryBills, CategoryOther,
+}
+
+// MaxDescriptionLength limit of description of payment
+const MaxDescriptionLength = 140
+
 // Payment base type of package
 type Payment struct {
-	ID          string    `json:"id"`
-	Amount      float64   `json:"amount"`
-	AccountTo   string    `json:"account_to"`
-	AccountFrom string    `json:"account_from"`
-	Direction   string    `json:"direction"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          string            `json:"id"`
+	Amount      float64           `json:"amount"`
+	Currency    string            `json:"currency"`
+	AccountTo   string            `json:"account_to"`
+	AccountFrom string            `json:"account_from"`
+	Direction   string            `json:"direction"`
+	ExternalID  string            `json:"external_id,omitempty"`
+	Metadata    metadata.Metadata `json:"metadata,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Category    string            `json:"category,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
 }
 
-// Storage interface transfer, assert account and view payments
+// Details optional details of transfer set by sender, external identifier is unique
+// among transfers, outgoing and incoming transactions share all details. Idempotency key
+// identifies transfer repeated by client, it is external identifier of transfer unless set
+type Details struct {
+	ExternalID     string
+	Metadata       metadata.Metadata
+	Description    string
+	Category       string
+	IdempotencyKey string
+}
+
+// Filter filter of listed payments, query matches payments by words of description,
+// empty fields of filter match all payments
+type Filter struct {
+	metadata.Filter
+	Query    string
+	Category string
+}
+
+// Sink receives payments while they are read from storage, error of sink stops reading
+type Sink func(p *Payment) error
+
+// Storage interface transfer, assert account, resolve alias of receiver and view payments
 type Storage interface {
-	PaymentList() ([]*Payment, error)
-	AssertAccount(id string) (*account.Account, error)
-	TransferMoney(accountFrom, accountTo string, amount float64) (*Payment, error)
+	alias.Resolver
+	PaymentList(ctx context.Context, filter Filter) ([]*Payment, error)
+	CustomerPaymentList(ctx context.Context, customerID string, filter Filter) ([]*Payment, error)
+	StreamPayments(ctx context.Context, filter Filter, sink Sink) error
+	CustomerStreamPayments(ctx context.Context, customerID string, filter Filter, sink Sink) error
+	AssertAccount(ctx context.Context, id string) (*account.Account, error)
+	TransferMoney(ctx context.Context, accountFrom, accountTo string, amount float64, details Details) (*Payment, error)
+}
+
+// Interface methods of payment service, implemented by Service and its decorators
+type Interface interface {
+	PaymentList(ctx context.Context, filter Filter) ([]*Payment, error)
+	StreamPayments(ctx context.Context, filter Filter, sink Sink) error
+	TransferMoney(ctx context.Context, accountFromID, accountToID string, amount float64,
+		details Details) (*Payment, error)
 }
 
 // Service handles with payments
@@ -51,7 +142,12 @@ func New(storage Storage) *Service {
 }
 
 // TransferMoney transfer money between accounts and register transactions in database
-func (s *Service) TransferMoney(accountFromID, accountToID string, amount float64) (*Payment, error) {
+// with details of transfer, receiver not identified by account identifier is resolved as alias
+// to its default account in currency of sender. Transfer repeated with the same idempotency key
+// returns payment made by first one
+func (s *Service) TransferMoney(ctx context.Context, accountFromID, accountToID string, amount float64,
+	details Details) (*Payment, error) {
+
 	if accountFromID == accountToID {
 		return nil, ErrorTransferYourself
 	}
@@ -60,24 +156,98 @@ func (s *Service) TransferMoney(accountFromID, accountToID string, amount float6
 		return nil, ErrorIncorrectAmount
 	}
 
-	accountFrom, err := s.storage.AssertAccount(accountFromID)
+	if details.Category != "" && !IsCategory(details.Category) {
+		return nil, ErrorUnknownCategory
+	}
+
+	if details.ExternalID == "" {
+		details.ExternalID = details.IdempotencyKey
+	}
+	if allowed, _ := ctx.Value(contextKeyReservedExternalID).(bool); !allowed && IsReservedExternalID(details.ExternalID) {
+		return nil, ErrorReservedExternalID
+	}
+
+	accountFrom, err := s.storage.AssertAccount(ctx, accountFromID)
 	if err != nil {
-		return nil, account.ErrorNotFound
+		return nil, err
+	}
+
+	if err = account.Authorize(ctx, accountFrom); err != nil {
+		return nil, err
+	}
+
+	if !alias.IsAccountID(accountToID) {
+		if accountToID, err = alias.Resolve(ctx, s.storage, accountToID, accountFrom.Currency); err != nil {
+			return nil, err
+		}
+		if accountToID == accountFrom.ID {
+			return nil, ErrorTransferYourself
+		}
 	}
 
-	accountTo, err := s.storage.AssertAccount(accountToID)
+	accountTo, err := s.storage.AssertAccount(ctx, accountToID)
 	if err != nil {
-		return nil, account.ErrorNotFound
+		return nil, err
 	}
 
 	if accountFrom.Currency != accountTo.Currency {
 		return nil, ErrorDifferentCurrencies
 	}
 
-	return s.storage.TransferMoney(accountFrom.ID, accountTo.ID, amount)
+	res, err := s.storage.TransferMoney(ctx, accountFrom.ID, accountTo.ID, amount, details)
+	if err == metadata.ErrorDuplicateExternalID && details.IdempotencyKey != "" {
+		return s.repeatedTransfer(ctx, accountFrom.ID, accountTo.ID, amount, details.ExternalID)
+	}
+	return res, err
+}
+
+// repeatedTransfer return payment made by transfer with external identifier, transfer
+// between other accounts or of other amount is not repeated one
+func (s *Service) repeatedTransfer(ctx context.Context, accountFromID, accountToID string, amount float64,
+	externalID string) (*Payment, error) {
+
+	payments, err := s.storage.PaymentList(ctx, Filter{Filter: metadata.Filter{ExternalID: externalID}})
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range payments {
+		if p.AccountFrom == accountFromID && p.AccountTo == accountToID && p.Amount == amount {
+			return p, nil
+		}
+	}
+	return nil, metadata.ErrorDuplicateExternalID
+}
+
+// PaymentList view payments stored in database matching filter,
+// customer principal views only payments of owned accounts
+func (s *Service) PaymentList(ctx context.Context, filter Filter) ([]*Payment, error) {
+	if filter.Category != "" && !IsCategory(filter.Category) {
+		return nil, ErrorUnknownCategory
+	}
+	if customerID := customer.PrincipalCustomerID(ctx); customerID != "" {
+		return s.storage.CustomerPaymentList(ctx, customerID, filter)
+	}
+	return s.storage.PaymentList(ctx, filter)
 }
 
-// PaymentList view payments stored in database
-func (s *Service) PaymentList() ([]*Payment, error) {
-	return s.storage.PaymentList()
+// StreamPayments pass payments matching filter to sink while they are read from database,
+// customer principal views only payments of owned accounts
+func (s *Service) StreamPayments(ctx context.Context, filter Filter, sink Sink) error {
+	if filter.Category != "" && !IsCategory(filter.Category) {
+		return ErrorUnknownCategory
+	}
+	if customerID := customer.PrincipalCustomerID(ctx); customerID != "" {
+		return s.storage.CustomerStreamPayments(ctx, customerID, filter, sink)
+	}
+	return s.storage.StreamPayments(ctx, filter, sink)
+}
+
+// IsCategory check category is one of Categories
+func IsCategory(category string) bool {
+	for _, c := range Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
 }
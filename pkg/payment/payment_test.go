@@ -1,67 +1,280 @@
 package payment
 
 import (
+	"context"
+	"strconv"
 	"testing"
 
+	"github.com/pkg/errors"
+
 	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/alias"
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/customer"
+	"github.com/sbutakov/wallet/pkg/metadata"
+)
+
+// identifiers of accounts, receivers not looking like identifiers are resolved as aliases
+const (
+	dummyTo  = "0f3c9a52-7d1e-4b6a-9c8d-2e4f6a8b0c1d"
+	dummyEUR = "5a7b9c1d-3e5f-4a6b-8c0d-1e2f3a4b5c6d"
+	aliceID  = "9e8d7c6b-5a4f-4e3d-8c2b-1a0f9e8d7c6b"
+	bobID    = "1b2c3d4e-5f6a-4b7c-8d9e-0f1a2b3c4d5e"
+	unknown  = "6c5b4a39-2817-4f6e-9d5c-4b3a29180f7e"
 )
 
 type dummyStorage struct {
 	accounts map[string]account.Account
+	aliases  map[string]alias.Alias
+	payments []*Payment
 }
 
-func (d *dummyStorage) PaymentList() ([]*Payment, error) {
+func (d *dummyStorage) FindAlias(_ context.Context, value string) (*alias.Alias, error) {
+	if res, ok := d.aliases[value]; ok {
+		return &res, nil
+	}
+	return nil, alias.ErrorNotFound
+}
+
+func (d *dummyStorage) PaymentList(_ context.Context, filter Filter) ([]*Payment, error) {
+	var payments []*Payment
+	for _, p := range d.payments {
+		if p.ExternalID == filter.ExternalID {
+			payments = append(payments, p)
+		}
+	}
+	return payments, nil
+}
+
+func (d *dummyStorage) CustomerPaymentList(context.Context, string, Filter) ([]*Payment, error) {
 	return nil, nil
 }
 
-func (d *dummyStorage) AssertAccount(id string) (*account.Account, error) {
+func (d *dummyStorage) StreamPayments(ctx context.Context, filter Filter, sink Sink) error {
+	payments, _ := d.PaymentList(ctx, filter)
+	for _, p := range payments {
+		if err := sink(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *dummyStorage) CustomerStreamPayments(context.Context, string, Filter, Sink) error {
+	return nil
+}
+
+func (d *dummyStorage) AssertAccount(_ context.Context, id string) (*account.Account, error) {
 	if res, ok := d.accounts[id]; ok {
 		return &res, nil
 	}
 	return nil, account.ErrorNotFound
 }
 
-func (d *dummyStorage) TransferMoney(string, string, float64) (*Payment, error) {
-	return &Payment{}, nil
+func (d *dummyStorage) TransferMoney(
+	_ context.Context, accountFrom, accountTo string, amount float64, details Details) (*Payment, error) {
+
+	for _, p := range d.payments {
+		if details.ExternalID != "" && p.ExternalID == details.ExternalID {
+			return nil, metadata.ErrorDuplicateExternalID
+		}
+	}
+	p := &Payment{ID: strconv.Itoa(len(d.payments) + 1), AccountFrom: accountFrom, AccountTo: accountTo,
+		Amount: amount, Currency: d.accounts[accountFrom].Currency, ExternalID: details.ExternalID}
+	d.payments = append(d.payments, p)
+	return p, nil
 }
 
 func TestService_TransferMoney(t *testing.T) {
 	storage := &dummyStorage{
 		accounts: map[string]account.Account{
 			"dummy_from": {Currency: "usd"},
-			"dummy_to":   {Currency: "usd"},
-			"dummy_eur":  {Currency: "eur"},
+			dummyTo:      {Currency: "usd"},
+			dummyEUR:     {Currency: "eur"},
 		},
 	}
 
 	instance := New(storage)
-	_, err := instance.TransferMoney("dummy_from", "dummy_to", 1)
+	_, err := instance.TransferMoney(context.Background(), "dummy_from", dummyTo, 1, Details{})
 	if err != nil {
 		t.Error("unexpected error on transfer money")
 	}
 
-	_, err = instance.TransferMoney("dummy", "dummy", 1)
+	_, err = instance.TransferMoney(context.Background(), "dummy", "dummy", 1, Details{})
 	if err != ErrorTransferYourself {
 		t.Error("error on check accounts for transfer money")
 	}
 
-	_, err = instance.TransferMoney("dummy_from", "dummy_to", 0)
+	_, err = instance.TransferMoney(context.Background(), "dummy_from", dummyTo, 0, Details{})
 	if err != ErrorIncorrectAmount {
 		t.Error("error on check correct amount")
 	}
 
-	_, err = instance.TransferMoney("dummy", "dummy_to", 1)
+	_, err = instance.TransferMoney(context.Background(), "dummy", dummyTo, 1, Details{})
 	if err != account.ErrorNotFound {
 		t.Error("error on assert account_from")
 	}
 
-	_, err = instance.TransferMoney("dummy_from", "dummy", 1)
+	_, err = instance.TransferMoney(context.Background(), "dummy_from", unknown, 1, Details{})
 	if err != account.ErrorNotFound {
 		t.Error("error on assert account_to")
 	}
 
-	_, err = instance.TransferMoney("dummy_from", "dummy_eur", 1)
+	_, err = instance.TransferMoney(context.Background(), "dummy_from", dummyEUR, 1, Details{})
 	if err != ErrorDifferentCurrencies {
 		t.Error("error on check equal currency")
 	}
+
+	_, err = instance.TransferMoney(context.Background(), "dummy_from", dummyTo, 1, Details{Category: "dummy"})
+	if err != ErrorUnknownCategory {
+		t.Error("error on check category")
+	}
+
+	_, err = instance.TransferMoney(context.Background(), "dummy_from", dummyTo, 1, Details{Category: CategoryRefund})
+	if err != nil {
+		t.Error("unexpected error on transfer money with category")
+	}
+
+	if _, err = instance.PaymentList(context.Background(), Filter{Category: "dummy"}); err != ErrorUnknownCategory {
+		t.Error("error on check category of filter")
+	}
+}
+
+func TestService_TransferMoneyIdempotencyKey(t *testing.T) {
+	storage := &dummyStorage{
+		accounts: map[string]account.Account{
+			"dummy_from": {ID: "dummy_from", Currency: "usd"},
+			dummyTo:      {ID: dummyTo, Currency: "usd"},
+		},
+	}
+
+	instance := New(storage)
+	first, err := instance.TransferMoney(context.Background(), "dummy_from", dummyTo, 1, Details{IdempotencyKey: "key"})
+	if err != nil || first.ExternalID != "key" {
+		t.Fatal("expected idempotency key stored as external id")
+	}
+
+	repeated, err := instance.TransferMoney(context.Background(), "dummy_from", dummyTo, 1, Details{IdempotencyKey: "key"})
+	if err != nil || repeated.ID != first.ID || len(storage.payments) != 1 {
+		t.Error("expected payment of first transfer returned on repeated one")
+	}
+
+	_, err = instance.TransferMoney(context.Background(), "dummy_from", dummyTo, 2, Details{IdempotencyKey: "key"})
+	if err != metadata.ErrorDuplicateExternalID {
+		t.Error("expected conflict on other transfer with same key")
+	}
+
+	_, err = instance.TransferMoney(context.Background(), "dummy_from", dummyTo, 1, Details{ExternalID: "key"})
+	if err != metadata.ErrorDuplicateExternalID {
+		t.Error("expected conflict on repeated external id without idempotency key")
+	}
+}
+
+func TestService_TransferMoneyReservedExternalID(t *testing.T) {
+	storage := &dummyStorage{
+		accounts: map[string]account.Account{
+			"dummy_from": {ID: "dummy_from", Currency: "usd"},
+			dummyTo:      {ID: dummyTo, Currency: "usd"},
+		},
+	}
+
+	instance := New(storage)
+	reserved := ReservedExternalIDPrefix + "dummy"
+	for _, details := range []Details{{ExternalID: reserved}, {IdempotencyKey: reserved}} {
+		_, err := instance.TransferMoney(context.Background(), "dummy_from", dummyTo, 1, details)
+		if err != ErrorReservedExternalID {
+			t.Errorf("expected reserved external id rejected, got %v", err)
+		}
+	}
+
+	ctx := WithReservedExternalID(context.Background())
+	if _, err := instance.TransferMoney(ctx, "dummy_from", dummyTo, 1, Details{ExternalID: reserved}); err != nil {
+		t.Error("expected reserved external id set by allowed context")
+	}
+}
+
+func TestService_TransferMoneyOwner(t *testing.T) {
+	storage := &dummyStorage{
+		accounts: map[string]account.Account{
+			aliceID: {ID: aliceID, CustomerID: "customer_alice", Currency: "usd"},
+			bobID:   {ID: bobID, CustomerID: "customer_bob", Currency: "usd"},
+		},
+	}
+
+	instance := New(storage)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{CustomerID: "customer_alice"})
+	if _, err := instance.TransferMoney(ctx, aliceID, bobID, 1, Details{}); err != nil {
+		t.Error("unexpected error on transfer from owned account")
+	}
+
+	if _, err := instance.TransferMoney(ctx, bobID, aliceID, 1, Details{}); err != customer.ErrorNotOwner {
+		t.Error("expected error on transfer from account of another customer")
+	}
+
+	ctx = auth.WithPrincipal(context.Background(), &auth.Principal{})
+	if _, err := instance.TransferMoney(ctx, bobID, aliceID, 1, Details{}); err != nil {
+		t.Error("unexpected error on transfer by principal not representing customer")
+	}
+}
+
+func TestService_TransferMoneyAlias(t *testing.T) {
+	storage := &dummyStorage{
+		accounts: map[string]account.Account{
+			aliceID:  {ID: aliceID, Currency: "usd"},
+			bobID:    {ID: bobID, Currency: "usd"},
+			dummyEUR: {ID: dummyEUR, Currency: "eur"},
+		},
+		aliases: map[string]alias.Alias{
+			"bob": {Value: "bob", Verified: true, Accounts: []*alias.LinkedAccount{
+				{AccountID: bobID, Currency: "usd", Default: true}}},
+			"alice": {Value: "alice", Verified: true, Accounts: []*alias.LinkedAccount{
+				{AccountID: aliceID, Currency: "usd", Default: true}}},
+			"+12025550123": {Value: "+12025550123", Accounts: []*alias.LinkedAccount{
+				{AccountID: bobID, Currency: "usd", Default: true}}},
+		},
+	}
+
+	instance := New(storage)
+	if _, err := instance.TransferMoney(context.Background(), aliceID, "@Bob", 1, Details{}); err != nil {
+		t.Error("unexpected error on transfer to alias")
+	}
+	if _, err := instance.TransferMoney(context.Background(), aliceID, "@alice", 1, Details{}); err != ErrorTransferYourself {
+		t.Error("expected error on transfer to own alias")
+	}
+	if _, err := instance.TransferMoney(context.Background(), aliceID, "+1 202 555 0123", 1, Details{}); err != alias.ErrorNotVerified {
+		t.Error("expected error on transfer to not verified alias")
+	}
+	if _, err := instance.TransferMoney(context.Background(), dummyEUR, "bob", 1, Details{}); err != alias.ErrorNoAccount {
+		t.Error("expected error on transfer to alias without account in currency")
+	}
+	if _, err := instance.TransferMoney(context.Background(), aliceID, "carol", 1, Details{}); err != alias.ErrorNotFound {
+		t.Error("expected error on transfer to unknown alias")
+	}
+}
+
+func TestService_StreamPayments(t *testing.T) {
+	storage := &dummyStorage{
+		payments: []*Payment{{ID: "first"}, {ID: "second"}, {ID: "third"}},
+	}
+
+	instance := New(storage)
+	stop := errors.New("dummy stop")
+	var ids []string
+	err := instance.StreamPayments(context.Background(), Filter{}, func(p *Payment) error {
+		ids = append(ids, p.ID)
+		if len(ids) == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || len(ids) != 2 {
+		t.Errorf("expected streaming stopped by sink, got %v of %v", err, ids)
+	}
+
+	err = instance.StreamPayments(context.Background(), Filter{Category: "dummy"}, func(*Payment) error {
+		return nil
+	})
+	if err != ErrorUnknownCategory {
+		t.Error("expected unknown category rejected")
+	}
 }
@@ -0,0 +1,38 @@
+package payment
+
+import (
+	"context"
+
+	"github.com/sbutakov/wallet/pkg/ratelimit"
+)
+
+type rateLimitingService struct {
+	limiter *ratelimit.Limiter
+	next    Interface
+}
+
+// NewRateLimitingService decorate service with limit of transfers from every source account,
+// transfers of accepted payment requests are limited as well as transfers requested by clients
+func NewRateLimitingService(limiter *ratelimit.Limiter, next Interface) Interface {
+	return &rateLimitingService{
+		limiter: limiter,
+		next:    next,
+	}
+}
+
+func (s *rateLimitingService) TransferMoney(ctx context.Context, accountFromID, accountToID string, amount float64,
+	details Details) (*Payment, error) {
+
+	if err := s.limiter.AllowAccount(ctx, accountFromID); err != nil {
+		return nil, err
+	}
+	return s.next.TransferMoney(ctx, accountFromID, accountToID, amount, details)
+}
+
+func (s *rateLimitingService) PaymentList(ctx context.Context, filter Filter) ([]*Payment, error) {
+	return s.next.PaymentList(ctx, filter)
+}
+
+func (s *rateLimitingService) StreamPayments(ctx context.Context, filter Filter, sink Sink) error {
+	return s.next.StreamPayments(ctx, filter, sink)
+}
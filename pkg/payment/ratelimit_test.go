@@ -0,0 +1,37 @@
+package payment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/ratelimit"
+)
+
+func TestRateLimitingService_TransferMoney(t *testing.T) {
+	storage := &dummyStorage{
+		accounts: map[string]account.Account{
+			"dummy_from":  {ID: "dummy_from", Currency: "usd"},
+			"dummy_other": {ID: "dummy_other", Currency: "usd"},
+			dummyTo:       {ID: dummyTo, Currency: "usd"},
+		},
+	}
+
+	limiter := ratelimit.New(ratelimit.Config{AccountRate: 0.01, AccountBurst: 1}, ratelimit.NewMemory())
+	instance := NewRateLimitingService(limiter, New(storage))
+	if _, err := instance.TransferMoney(context.Background(), "dummy_from", dummyTo, 1, Details{}); err != nil {
+		t.Errorf("unexpected error on first transfer: %v", err)
+	}
+
+	_, err := instance.TransferMoney(WithReservedExternalID(context.Background()), "dummy_from", dummyTo, 1,
+		Details{ExternalID: ReservedExternalIDPrefix + "dummy"})
+	if errors.Cause(err) != ratelimit.ErrorRateLimited {
+		t.Errorf("expected transfer of payment request rate limited, got %v", err)
+	}
+
+	if _, err := instance.TransferMoney(context.Background(), "dummy_other", dummyTo, 1, Details{}); err != nil {
+		t.Errorf("expected transfer from other account allowed, got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package payment
+
+import (
+	"context"
+
+	"github.com/sbutakov/wallet/pkg/tracing"
+)
+
+type tracingService struct {
+	tracer *tracing.Tracer
+	next   Interface
+}
+
+// NewTracingService decorate service with recording span of every method call
+func NewTracingService(tracer *tracing.Tracer, next Interface) Interface {
+	return &tracingService{
+		tracer: tracer,
+		next:   next,
+	}
+}
+
+func (s *tracingService) TransferMoney(ctx context.Context, accountFromID, accountToID string, amount float64,
+	details Details) (result *Payment, err error) {
+
+	span, ctx := s.tracer.StartSpan(ctx, "payment.TransferMoney")
+	span.SetAttribute("account_from", accountFromID)
+	span.SetAttribute("account_to", accountToID)
+	defer func() {
+		span.SetError(err)
+		span.Finish()
+	}()
+	return s.next.TransferMoney(ctx, accountFromID, accountToID, amount, details)
+}
+
+func (s *tracingService) PaymentList(ctx context.Context, filter Filter) (result []*Payment, err error) {
+	span, ctx := s.tracer.StartSpan(ctx, "payment.PaymentList")
+	defer func() {
+		span.SetError(err)
+		span.Finish()
+	}()
+	return s.next.PaymentList(ctx, filter)
+}
+
+func (s *tracingService) StreamPayments(ctx context.Context, filter Filter, sink Sink) (err error) {
+	span, ctx := s.tracer.StartSpan(ctx, "payment.StreamPayments")
+	defer func() {
+		span.SetError(err)
+		span.Finish()
+	}()
+	return s.next.StreamPayments(ctx, filter, sink)
+}
@@ -0,0 +1,313 @@
+// Package paymentrequest provides requests of money addressed to payer account, pending request
+// is accepted by payer transferring requested amount, declined by payer, canceled by requester
+// or expired by sweeper, request leaves pending status once
+package paymentrequest
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/alias"
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/customer"
+	"github.com/sbutakov/wallet/pkg/health"
+	"github.com/sbutakov/wallet/pkg/payment"
+)
+
+// statuses of payment requests
+const (
+	StatusPending  = "pending"
+	StatusAccepted = "accepted"
+	StatusDeclined = "declined"
+	StatusCanceled = "canceled"
+	StatusExpired  = "expired"
+)
+
+// Statuses all statuses of payment requests
+var Statuses = []string{StatusPending, StatusAccepted, StatusDeclined, StatusCanceled, StatusExpired}
+
+// ExternalIDPrefix prefix of external identifier of payment accepting request, external identifiers
+// are unique among transfers, so request is paid at most once, prefix is reserved for accepting requests
+const ExternalIDPrefix = payment.ReservedExternalIDPrefix
+
+var (
+	// ErrorNotFound payment request not found
+	ErrorNotFound = apperror.New(
+		apperror.KindNotFound, "payment_request_not_found", "payment request not found")
+	// ErrorNotPending payment request is already accepted, declined or canceled
+	ErrorNotPending = apperror.New(
+		apperror.KindFailedPrecondition, "payment_request_not_pending", "payment request is not pending")
+	// ErrorExpired payment request is expired
+	ErrorExpired = apperror.New(
+		apperror.KindFailedPrecondition, "payment_request_expired", "payment request is expired")
+	// ErrorIncorrectExpiry expiry is in the past or too far in the future
+	ErrorIncorrectExpiry = apperror.New(
+		apperror.KindInvalidArgument, "incorrect_expiry", "expiry must be in the future within max expiry")
+	// ErrorUnknownStatus status of filter is not one of Statuses
+	ErrorUnknownStatus = apperror.New(
+		apperror.KindInvalidArgument, "unknown_payment_request_status", "unknown status of payment request")
+	// ErrorPaymentMismatch payment with external identifier of request does not pay requested amount
+	ErrorPaymentMismatch = apperror.New(
+		apperror.KindFailedPrecondition, "payment_request_mismatch", "payment does not match payment request")
+)
+
+// Request request of money, payee account requests amount from payer account, payment
+// is set when request is accepted
+type Request struct {
+	ID             string    `json:"id"`
+	PayeeAccountID string    `json:"payee_account_id"`
+	PayerAccountID string    `json:"payer_account_id"`
+	Amount         float64   `json:"amount"`
+	Currency       string    `json:"currency"`
+	Memo           string    `json:"memo,omitempty"`
+	Status         string    `json:"status"`
+	PaymentID      string    `json:"payment_id,omitempty"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Details optional details of payment request, default expiry is used when expiry is zero
+type Details struct {
+	Memo      string
+	ExpiresAt time.Time
+}
+
+// Filter filter of listed payment requests, empty fields match all requests
+type Filter struct {
+	AccountID string
+	Status    string
+}
+
+// Config configuration of payment requests, pending requests are expired by sweeper every interval
+type Config struct {
+	Sweeper       bool          `default:"true"`
+	SweepInterval time.Duration `default:"1m"`
+	DefaultExpiry time.Duration `default:"168h"`
+	MaxExpiry     time.Duration `default:"2160h"`
+}
+
+// Payments interface for transfer money accepting payment request
+type Payments interface {
+	TransferMoney(ctx context.Context, accountFromID, accountToID string, amount float64,
+		details payment.Details) (*payment.Payment, error)
+}
+
+// Storage interface for managing payment requests, pending request past its expiry
+// is viewed as expired before sweeper changes its status. Request is accepted by payment
+// made by pay while request is locked, so it is not declined, canceled or expired meanwhile
+type Storage interface {
+	alias.Resolver
+	AssertAccount(ctx context.Context, id string) (*account.Account, error)
+	CreatePaymentRequest(ctx context.Context, req *Request) (*Request, error)
+	GetPaymentRequest(ctx context.Context, id string) (*Request, error)
+	ListPaymentRequests(ctx context.Context, customerID string, filter Filter) ([]*Request, error)
+	AcceptPaymentRequest(ctx context.Context, id string, pay func(ctx context.Context, req *Request) error) (*Request, error)
+	ResolvePaymentRequest(ctx context.Context, id, status string) (*Request, error)
+	ExpirePaymentRequests(ctx context.Context) (int64, error)
+}
+
+// Service manages payment requests
+type Service struct {
+	config   Config
+	storage  Storage
+	payments Payments
+	now      func() time.Time
+}
+
+// New is constructor
+func New(config Config, storage Storage, payments Payments) *Service {
+	return &Service{
+		config:   config,
+		storage:  storage,
+		payments: payments,
+		now:      time.Now,
+	}
+}
+
+// Create request amount from payer to payee account owned by principal, payer not identified
+// by account identifier is resolved as alias in currency of request
+func (s *Service) Create(ctx context.Context, payeeAccountID, payerAccountID string, amount float64,
+	currency string, details Details) (*Request, error) {
+
+	if amount <= 0 {
+		return nil, payment.ErrorIncorrectAmount
+	}
+
+	currency = strings.ToLower(currency)
+	now := s.now()
+	if details.ExpiresAt.IsZero() {
+		details.ExpiresAt = now.Add(s.config.DefaultExpiry)
+	}
+	if !details.ExpiresAt.After(now) || details.ExpiresAt.After(now.Add(s.config.MaxExpiry)) {
+		return nil, ErrorIncorrectExpiry
+	}
+
+	payee, err := s.storage.AssertAccount(ctx, payeeAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if err = account.Authorize(ctx, payee); err != nil {
+		return nil, err
+	}
+
+	if !alias.IsAccountID(payerAccountID) {
+		if payerAccountID, err = alias.Resolve(ctx, s.storage, payerAccountID, currency); err != nil {
+			return nil, err
+		}
+	}
+	if payerAccountID == payee.ID {
+		return nil, payment.ErrorTransferYourself
+	}
+
+	payer, err := s.storage.AssertAccount(ctx, payerAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if payee.Currency != currency || payer.Currency != currency {
+		return nil, payment.ErrorDifferentCurrencies
+	}
+
+	return s.storage.CreatePaymentRequest(ctx, &Request{
+		PayeeAccountID: payee.ID,
+		PayerAccountID: payer.ID,
+		Amount:         amount,
+		Currency:       currency,
+		Memo:           details.Memo,
+		ExpiresAt:      details.ExpiresAt,
+	})
+}
+
+// Get view payment request, customer principal views only requests of owned accounts
+func (s *Service) Get(ctx context.Context, id string) (*Request, error) {
+	req, err := s.storage.GetPaymentRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.authorize(ctx, req.PayerAccountID); err == customer.ErrorNotOwner {
+		err = s.authorize(ctx, req.PayeeAccountID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// List view payment requests matching filter, customer principal views only requests
+// sent or received by owned accounts
+func (s *Service) List(ctx context.Context, filter Filter) ([]*Request, error) {
+	if filter.Status != "" && !isStatus(filter.Status) {
+		return nil, ErrorUnknownStatus
+	}
+	return s.storage.ListPaymentRequests(ctx, customer.PrincipalCustomerID(ctx), filter)
+}
+
+// Accept transfer requested amount from payer account owned by principal and mark request
+// accepted, request stays pending when transfer fails, request declined, canceled or expired
+// before it is locked by storage is not paid
+func (s *Service) Accept(ctx context.Context, id string) (*Request, error) {
+	req, err := s.pending(ctx, id, func(req *Request) string { return req.PayerAccountID })
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storage.AcceptPaymentRequest(ctx, req.ID, func(ctx context.Context, req *Request) error {
+		_, err := s.payments.TransferMoney(payment.WithReservedExternalID(ctx), req.PayerAccountID,
+			req.PayeeAccountID, req.Amount, payment.Details{ExternalID: ExternalID(req.ID), Description: req.Memo})
+		return err
+	})
+}
+
+// Decline decline request by principal owning payer account
+func (s *Service) Decline(ctx context.Context, id string) (*Request, error) {
+	req, err := s.pending(ctx, id, func(req *Request) string { return req.PayerAccountID })
+	if err != nil {
+		return nil, err
+	}
+	return s.storage.ResolvePaymentRequest(ctx, req.ID, StatusDeclined)
+}
+
+// Cancel cancel request by principal owning payee account
+func (s *Service) Cancel(ctx context.Context, id string) (*Request, error) {
+	req, err := s.pending(ctx, id, func(req *Request) string { return req.PayeeAccountID })
+	if err != nil {
+		return nil, err
+	}
+	return s.storage.ResolvePaymentRequest(ctx, req.ID, StatusCanceled)
+}
+
+// Expire mark pending requests past their expiry expired, requests being paid are not expired
+func (s *Service) Expire(ctx context.Context) (int64, error) {
+	return s.storage.ExpirePaymentRequests(ctx)
+}
+
+// Run expire payment requests every interval until context is done, heartbeat beats
+// after every successful sweep, requests are viewed expired without sweeper as well
+func (s *Service) Run(ctx context.Context, heartbeat *health.Heartbeat, onError func(error)) {
+	if !s.config.Sweeper {
+		return
+	}
+
+	ticker := time.NewTicker(s.config.SweepInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.Expire(ctx); err != nil {
+			onError(err)
+		} else {
+			heartbeat.Beat()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pending return pending request, principal must own account of party acting on request
+func (s *Service) pending(ctx context.Context, id string, party func(*Request) string) (*Request, error) {
+	req, err := s.storage.GetPaymentRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.authorize(ctx, party(req)); err != nil {
+		return nil, err
+	}
+
+	switch req.Status {
+	case StatusPending:
+		return req, nil
+	case StatusExpired:
+		return nil, ErrorExpired
+	}
+	return nil, ErrorNotPending
+}
+
+func (s *Service) authorize(ctx context.Context, accountID string) error {
+	if customer.PrincipalCustomerID(ctx) == "" {
+		return nil
+	}
+	acc, err := s.storage.AssertAccount(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	return account.Authorize(ctx, acc)
+}
+
+// ExternalID return external identifier of payment accepting request
+func ExternalID(id string) string {
+	return ExternalIDPrefix + id
+}
+
+func isStatus(status string) bool {
+	for _, s := range Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
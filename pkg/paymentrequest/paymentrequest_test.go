@@ -0,0 +1,243 @@
+package paymentrequest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/alias"
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/customer"
+	"github.com/sbutakov/wallet/pkg/health"
+	"github.com/sbutakov/wallet/pkg/payment"
+)
+
+const (
+	aliceID = "9e8d7c6b-5a4f-4e3d-8c2b-1a0f9e8d7c6b"
+	bobID   = "1b2c3d4e-5f6a-4b7c-8d9e-0f1a2b3c4d5e"
+	bobEUR  = "5a7b9c1d-3e5f-4a6b-8c0d-1e2f3a4b5c6d"
+)
+
+type dummyStorage struct {
+	accounts   map[string]account.Account
+	requests   map[string]*Request
+	resolved   string
+	concurrent string
+	expired    int64
+	cancel     context.CancelFunc
+}
+
+func (d *dummyStorage) FindAlias(_ context.Context, value string) (*alias.Alias, error) {
+	if value != "bob" {
+		return nil, alias.ErrorNotFound
+	}
+	return &alias.Alias{Value: value, Verified: true, Accounts: []*alias.LinkedAccount{
+		{AccountID: bobID, Currency: "usd", Default: true}}}, nil
+}
+
+func (d *dummyStorage) AssertAccount(_ context.Context, id string) (*account.Account, error) {
+	if res, ok := d.accounts[id]; ok {
+		return &res, nil
+	}
+	return nil, account.ErrorNotFound
+}
+
+func (d *dummyStorage) CreatePaymentRequest(_ context.Context, req *Request) (*Request, error) {
+	req.ID = "created"
+	req.Status = StatusPending
+	return req, nil
+}
+
+func (d *dummyStorage) GetPaymentRequest(_ context.Context, id string) (*Request, error) {
+	if res, ok := d.requests[id]; ok {
+		req := *res
+		return &req, nil
+	}
+	return nil, ErrorNotFound
+}
+
+func (d *dummyStorage) ListPaymentRequests(context.Context, string, Filter) ([]*Request, error) {
+	return nil, nil
+}
+
+func (d *dummyStorage) AcceptPaymentRequest(ctx context.Context, id string,
+	pay func(context.Context, *Request) error) (*Request, error) {
+
+	req, err := d.GetPaymentRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// request resolved by concurrent decline before it is locked
+	if d.concurrent != "" {
+		d.requests[id].Status = d.concurrent
+	}
+	if d.requests[id].Status != StatusPending {
+		return nil, ErrorNotPending
+	}
+	if err = pay(ctx, req); err != nil {
+		return nil, err
+	}
+	return d.ResolvePaymentRequest(ctx, id, StatusAccepted)
+}
+
+func (d *dummyStorage) ResolvePaymentRequest(_ context.Context, id, status string) (*Request, error) {
+	d.resolved = status
+	d.requests[id].Status = status
+	req := *d.requests[id]
+	return &req, nil
+}
+
+func (d *dummyStorage) ExpirePaymentRequests(context.Context) (int64, error) {
+	d.expired++
+	if d.cancel != nil {
+		d.cancel()
+	}
+	return 1, nil
+}
+
+type dummyPayments struct {
+	transfers []payment.Details
+	err       error
+}
+
+func (d *dummyPayments) TransferMoney(
+	_ context.Context, _, _ string, _ float64, details payment.Details) (*payment.Payment, error) {
+
+	d.transfers = append(d.transfers, details)
+	return &payment.Payment{}, d.err
+}
+
+func newDummyStorage() *dummyStorage {
+	return &dummyStorage{
+		accounts: map[string]account.Account{
+			aliceID: {ID: aliceID, CustomerID: "customer_alice", Currency: "usd"},
+			bobID:   {ID: bobID, CustomerID: "customer_bob", Currency: "usd"},
+			bobEUR:  {ID: bobEUR, CustomerID: "customer_bob", Currency: "eur"},
+		},
+		requests: map[string]*Request{
+			"pending": {ID: "pending", PayeeAccountID: aliceID, PayerAccountID: bobID, Amount: 10,
+				Currency: "usd", Status: StatusPending},
+			"expired": {ID: "expired", PayeeAccountID: aliceID, PayerAccountID: bobID, Amount: 10,
+				Currency: "usd", Status: StatusExpired},
+			"declined": {ID: "declined", PayeeAccountID: aliceID, PayerAccountID: bobID, Amount: 10,
+				Currency: "usd", Status: StatusDeclined},
+		},
+	}
+}
+
+func TestService_Create(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	service := New(Config{DefaultExpiry: time.Hour, MaxExpiry: 24 * time.Hour}, newDummyStorage(), &dummyPayments{})
+	service.now = func() time.Time { return now }
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{CustomerID: "customer_alice"})
+	req, err := service.Create(ctx, aliceID, "@Bob", 10.5, "USD", Details{Memo: "dinner"})
+	if err != nil || req.PayerAccountID != bobID || req.Currency != "usd" || !req.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("unexpected created request %v %v", req, err)
+	}
+
+	for _, c := range []struct {
+		payee, payer string
+		amount       float64
+		currency     string
+		details      Details
+		err          error
+	}{
+		{aliceID, bobID, 0, "usd", Details{}, payment.ErrorIncorrectAmount},
+		{aliceID, bobID, 1, "usd", Details{ExpiresAt: now.Add(-time.Minute)}, ErrorIncorrectExpiry},
+		{aliceID, bobID, 1, "usd", Details{ExpiresAt: now.Add(48 * time.Hour)}, ErrorIncorrectExpiry},
+		{bobID, aliceID, 1, "usd", Details{}, customer.ErrorNotOwner},
+		{aliceID, aliceID, 1, "usd", Details{}, payment.ErrorTransferYourself},
+		{aliceID, bobEUR, 1, "usd", Details{}, payment.ErrorDifferentCurrencies},
+		{aliceID, "carol", 1, "usd", Details{}, alias.ErrorNotFound},
+	} {
+		if _, err = service.Create(ctx, c.payee, c.payer, c.amount, c.currency, c.details); err != c.err {
+			t.Errorf("expected %v on create request from %s, got %v", c.err, c.payer, err)
+		}
+	}
+}
+
+func TestService_Accept(t *testing.T) {
+	storage := newDummyStorage()
+	payments := &dummyPayments{err: payment.ErrorNotEnoughMoney}
+	service := New(Config{}, storage, payments)
+
+	bob := auth.WithPrincipal(context.Background(), &auth.Principal{CustomerID: "customer_bob"})
+	if _, err := service.Accept(bob, "pending"); err != payment.ErrorNotEnoughMoney || storage.resolved != "" {
+		t.Error("expected request pending when transfer fails")
+	}
+
+	payments.err = nil
+	req, err := service.Accept(bob, "pending")
+	if err != nil || req.Status != StatusAccepted || payments.transfers[1].ExternalID != ExternalID("pending") {
+		t.Errorf("unexpected accepted request %v %v", req, err)
+	}
+	if _, err = service.Accept(bob, "pending"); err != ErrorNotPending {
+		t.Error("expected accepted request not accepted again")
+	}
+
+	storage.requests["pending"].Status = StatusPending
+	storage.concurrent = StatusDeclined
+	if _, err = service.Accept(bob, "pending"); err != ErrorNotPending || len(payments.transfers) != 2 {
+		t.Error("expected request declined concurrently not paid")
+	}
+	storage.concurrent = ""
+
+	if _, err = service.Accept(bob, "expired"); err != ErrorExpired {
+		t.Error("expected error on accept expired request")
+	}
+	alice := auth.WithPrincipal(context.Background(), &auth.Principal{CustomerID: "customer_alice"})
+	if _, err = service.Accept(alice, "declined"); err != customer.ErrorNotOwner {
+		t.Error("expected payee forbidden to accept request")
+	}
+}
+
+func TestService_Resolve(t *testing.T) {
+	storage := newDummyStorage()
+	service := New(Config{}, storage, &dummyPayments{})
+
+	alice := auth.WithPrincipal(context.Background(), &auth.Principal{CustomerID: "customer_alice"})
+	bob := auth.WithPrincipal(context.Background(), &auth.Principal{CustomerID: "customer_bob"})
+	if _, err := service.Decline(alice, "pending"); err != customer.ErrorNotOwner {
+		t.Error("expected payee forbidden to decline request")
+	}
+	if _, err := service.Cancel(bob, "pending"); err != customer.ErrorNotOwner {
+		t.Error("expected payer forbidden to cancel request")
+	}
+	if req, err := service.Get(alice, "pending"); err != nil || req.ID != "pending" {
+		t.Error("unexpected error on view request by payee")
+	}
+	if req, err := service.Decline(bob, "pending"); err != nil || req.Status != StatusDeclined {
+		t.Error("unexpected error on decline request")
+	}
+	if _, err := service.Cancel(alice, "pending"); err != ErrorNotPending {
+		t.Error("expected declined request not canceled")
+	}
+
+	carol := auth.WithPrincipal(context.Background(), &auth.Principal{CustomerID: "customer_carol"})
+	if _, err := service.Get(carol, "pending"); err != customer.ErrorNotOwner {
+		t.Error("expected request of other customers hidden")
+	}
+	if _, err := service.List(context.Background(), Filter{Status: "dummy"}); err != ErrorUnknownStatus {
+		t.Error("expected error on unknown status")
+	}
+}
+
+func TestService_Run(t *testing.T) {
+	storage := newDummyStorage()
+	ctx, cancel := context.WithCancel(context.Background())
+	storage.cancel = cancel
+
+	heartbeat := health.NewHeartbeat(time.Minute)
+	New(Config{Sweeper: true, SweepInterval: time.Hour}, storage, &dummyPayments{}).Run(ctx, heartbeat,
+		func(err error) { t.Error("unexpected error on expire requests") })
+	if storage.expired != 1 || heartbeat.Check(context.Background()) != nil {
+		t.Error("expected requests expired once before cancel")
+	}
+
+	New(Config{}, storage, &dummyPayments{}).Run(context.Background(), heartbeat, nil)
+	if storage.expired != 1 {
+		t.Error("expected disabled sweeper not run")
+	}
+}
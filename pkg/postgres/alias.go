@@ -0,0 +1,247 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/alias"
+	"github.com/sbutakov/wallet/pkg/audit"
+)
+
+const aliasColumns = "id,kind,value,customer_id,verified_at,created_at"
+
+// CreateAlias create alias linked to account as default account of its currency,
+// handles are verified on creation
+func (p *Postgres) CreateAlias(ctx context.Context, a *alias.Alias, acc *account.Account) (*alias.Alias, error) {
+	res := new(alias.Alias)
+	return res, p.beginTransaction(ctx, func(tx *transaction) error {
+		id := uuid.NewV4().String()
+		q := "INSERT INTO aliases(id,kind,value,customer_id,verified_at) " +
+			"VALUES($1, $2, $3, $4, CASE WHEN $5 THEN NOW() END)"
+		_, err := tx.Exec(q, id, a.Kind, a.Value, nullString(a.CustomerID), a.Verified)
+		if isUniqueViolation(err) {
+			return alias.ErrorTaken
+		}
+		if err != nil {
+			return err
+		}
+
+		q = "INSERT INTO alias_accounts(alias_id, account_id, currency, is_default) VALUES($1, $2, $3, TRUE)"
+		if _, err = tx.Exec(q, id, acc.ID, acc.Currency); err != nil {
+			return err
+		}
+
+		if err = loadAlias(tx, "SELECT "+aliasColumns+" FROM aliases WHERE id=$1", id, res); err != nil {
+			return err
+		}
+		return appendAudit(tx, audit.ActionCreateAlias, audit.EntityAlias, res.ID, nil, res)
+	})
+}
+
+// GetAlias return alias with linked accounts
+func (p *Postgres) GetAlias(ctx context.Context, id string) (*alias.Alias, error) {
+	res := new(alias.Alias)
+	return res, p.beginTransaction(ctx, func(tx *transaction) error {
+		return loadAlias(tx, "SELECT "+aliasColumns+" FROM aliases WHERE id=$1", id, res)
+	})
+}
+
+// FindAlias return alias by normalized value
+func (p *Postgres) FindAlias(ctx context.Context, value string) (*alias.Alias, error) {
+	res := new(alias.Alias)
+	return res, p.beginTransaction(ctx, func(tx *transaction) error {
+		return loadAlias(tx, "SELECT "+aliasColumns+" FROM aliases WHERE value=$1", value, res)
+	})
+}
+
+// ListAliases return aliases with linked accounts, aliases of all customers are returned
+// when customer is empty
+func (p *Postgres) ListAliases(ctx context.Context, customerID string) ([]*alias.Alias, error) {
+	aliases := []*alias.Alias{}
+	return aliases, p.beginTransaction(ctx, func(tx *transaction) error {
+		q := "SELECT " + aliasColumns + " FROM aliases WHERE ($1 = '' OR customer_id::text = $1) ORDER BY created_at"
+		rows, err := tx.Query(q, customerID)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+		byID := map[string]*alias.Alias{}
+		var ids []string
+		for rows.Next() {
+			res := &alias.Alias{Accounts: []*alias.LinkedAccount{}}
+			if err = scanAlias(rows, res); err != nil {
+				return err
+			}
+			aliases = append(aliases, res)
+			byID[res.ID] = res
+			ids = append(ids, res.ID)
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		q = "SELECT alias_id, account_id, currency, is_default FROM alias_accounts " +
+			"WHERE alias_id = ANY($1) ORDER BY currency, account_id"
+		accounts, err := tx.Query(q, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+
+		defer accounts.Close()
+		for accounts.Next() {
+			var aliasID string
+			acc := new(alias.LinkedAccount)
+			if err = accounts.Scan(&aliasID, &acc.AccountID, &acc.Currency, &acc.Default); err != nil {
+				return err
+			}
+			byID[aliasID].Accounts = append(byID[aliasID].Accounts, acc)
+		}
+		return accounts.Err()
+	})
+}
+
+// DeleteAlias delete alias and its links to accounts
+func (p *Postgres) DeleteAlias(ctx context.Context, id string) error {
+	return p.beginTransaction(ctx, func(tx *transaction) error {
+		before := new(alias.Alias)
+		if err := loadAlias(tx, "SELECT "+aliasColumns+" FROM aliases WHERE id=$1 FOR UPDATE", id, before); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM aliases WHERE id=$1", id); err != nil {
+			return err
+		}
+		return appendAudit(tx, audit.ActionDeleteAlias, audit.EntityAlias, id, before, nil)
+	})
+}
+
+// LinkAliasAccount link account to alias, previous default account of currency is replaced
+// when account becomes default
+func (p *Postgres) LinkAliasAccount(
+	ctx context.Context, id string, acc *account.Account, isDefault bool) (*alias.Alias, error) {
+
+	res := new(alias.Alias)
+	return res, p.updateAlias(ctx, id, res, func(tx *transaction, before *alias.Alias) error {
+		hasDefault := false
+		for _, linked := range before.Accounts {
+			hasDefault = hasDefault || linked.Default && linked.Currency == acc.Currency
+		}
+
+		isDefault = isDefault || !hasDefault
+		if isDefault {
+			q := "UPDATE alias_accounts SET is_default = FALSE WHERE alias_id=$1 AND currency=$2 AND account_id<>$3"
+			if _, err := tx.Exec(q, id, acc.Currency, acc.ID); err != nil {
+				return err
+			}
+		}
+
+		q := "INSERT INTO alias_accounts(alias_id, account_id, currency, is_default) VALUES($1, $2, $3, $4) " +
+			"ON CONFLICT (alias_id, account_id) DO UPDATE SET is_default = alias_accounts.is_default OR $4"
+		_, err := tx.Exec(q, id, acc.ID, acc.Currency, isDefault)
+		return err
+	})
+}
+
+// UnlinkAliasAccount unlink account from alias, account of the same currency with least identifier
+// becomes default when default account is unlinked
+func (p *Postgres) UnlinkAliasAccount(ctx context.Context, id, accountID string) (*alias.Alias, error) {
+	res := new(alias.Alias)
+	return res, p.updateAlias(ctx, id, res, func(tx *transaction, _ *alias.Alias) error {
+		var currency string
+		var wasDefault bool
+		q := "DELETE FROM alias_accounts WHERE alias_id=$1 AND account_id=$2 RETURNING currency, is_default"
+		err := tx.QueryRow(q, id, accountID).Scan(&currency, &wasDefault)
+		if err == sql.ErrNoRows || isInvalidText(err) {
+			return account.ErrorNotFound
+		}
+		if err != nil || !wasDefault {
+			return err
+		}
+
+		q = "UPDATE alias_accounts SET is_default = TRUE WHERE alias_id=$1 AND account_id = " +
+			"(SELECT account_id FROM alias_accounts WHERE alias_id=$1 AND currency=$2 ORDER BY account_id LIMIT 1)"
+		_, err = tx.Exec(q, id, currency)
+		return err
+	})
+}
+
+// VerifyAlias mark alias verified, verified alias stays verified
+func (p *Postgres) VerifyAlias(ctx context.Context, id string) (*alias.Alias, error) {
+	res := new(alias.Alias)
+	return res, p.updateAlias(ctx, id, res, func(tx *transaction, _ *alias.Alias) error {
+		_, err := tx.Exec("UPDATE aliases SET verified_at = COALESCE(verified_at, NOW()) WHERE id=$1", id)
+		return err
+	})
+}
+
+// updateAlias change alias locked until end of transaction and record change in audit log
+func (p *Postgres) updateAlias(ctx context.Context, id string, res *alias.Alias,
+	update func(tx *transaction, before *alias.Alias) error) error {
+
+	return p.beginTransaction(ctx, func(tx *transaction) error {
+		before := new(alias.Alias)
+		if err := loadAlias(tx, "SELECT "+aliasColumns+" FROM aliases WHERE id=$1 FOR UPDATE", id, before); err != nil {
+			return err
+		}
+		if err := update(tx, before); err != nil {
+			return err
+		}
+		if err := loadAlias(tx, "SELECT "+aliasColumns+" FROM aliases WHERE id=$1", id, res); err != nil {
+			return err
+		}
+
+		action := audit.ActionUpdateAlias
+		if res.Verified && !before.Verified {
+			action = audit.ActionVerifyAlias
+		}
+		return appendAudit(tx, action, audit.EntityAlias, id, before, res)
+	})
+}
+
+// loadAlias scan alias selected by query and its linked accounts
+func loadAlias(tx *transaction, q string, arg string, res *alias.Alias) error {
+	err := scanAlias(tx.QueryRow(q, arg), res)
+	if err == sql.ErrNoRows || isInvalidText(err) {
+		return alias.ErrorNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	q = "SELECT account_id, currency, is_default FROM alias_accounts WHERE alias_id=$1 ORDER BY currency, account_id"
+	rows, err := tx.Query(q, res.ID)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+	res.Accounts = []*alias.LinkedAccount{}
+	for rows.Next() {
+		acc := new(alias.LinkedAccount)
+		if err = rows.Scan(&acc.AccountID, &acc.Currency, &acc.Default); err != nil {
+			return err
+		}
+		res.Accounts = append(res.Accounts, acc)
+	}
+	return rows.Err()
+}
+
+func scanAlias(row scanner, res *alias.Alias) error {
+	var customerID sql.NullString
+	var verifiedAt pq.NullTime
+	err := row.Scan(&res.ID, &res.Kind, &res.Value, &customerID, &verifiedAt, &res.CreatedAt)
+	if err != nil {
+		return err
+	}
+	res.CustomerID = customerID.String
+	res.Verified = verifiedAt.Valid
+	res.VerifiedAt = nil
+	if verifiedAt.Valid {
+		res.VerifiedAt = &verifiedAt.Time
+	}
+	return nil
+}
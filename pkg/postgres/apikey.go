@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+
+	"github.com/sbutakov/wallet/pkg/audit"
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/customer"
+)
+
+const apiKeyColumns = "id,customer_id,name,scopes,created_at,revoked_at"
+
+// CreateAPIKey store api key by hash of its secret
+func (p *Postgres) CreateAPIKey(
+	ctx context.Context, customerID, name string, scopes []string, hash string) (*auth.APIKey, error) {
+
+	key := new(auth.APIKey)
+	return key, p.beginTransaction(ctx, func(tx *transaction) error {
+		q := "INSERT INTO api_keys(id,customer_id,name,key_hash,scopes) VALUES($1, $2, $3, $4, $5) " +
+			"RETURNING " + apiKeyColumns
+		row := tx.QueryRow(q, uuid.NewV4().String(), nullString(customerID), name, hash, pq.Array(scopes))
+		err := scanAPIKey(row, key)
+		if isForeignKeyViolation(err) || isInvalidText(err) {
+			return customer.ErrorNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return appendAudit(tx, audit.ActionCreateAPIKey, audit.EntityAPIKey, key.ID, nil, key)
+	})
+}
+
+// FindAPIKey return api key by hash of its secret
+func (p *Postgres) FindAPIKey(ctx context.Context, hash string) (*auth.APIKey, error) {
+	key := new(auth.APIKey)
+	return key, p.beginTransaction(ctx, func(tx *transaction) error {
+		q := "SELECT " + apiKeyColumns + " FROM api_keys WHERE key_hash=$1"
+		err := scanAPIKey(tx.QueryRow(q, hash), key)
+		if err == sql.ErrNoRows {
+			return auth.ErrorNotFound
+		}
+		return err
+	})
+}
+
+// ListAPIKeys return stored api keys
+func (p *Postgres) ListAPIKeys(ctx context.Context) ([]*auth.APIKey, error) {
+	var keys []*auth.APIKey
+	return keys, p.beginTransaction(ctx, func(tx *transaction) error {
+		q := "SELECT " + apiKeyColumns + " FROM api_keys ORDER BY created_at"
+		rows, err := tx.Query(q)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+		for rows.Next() {
+			key := new(auth.APIKey)
+			if err = scanAPIKey(rows, key); err != nil {
+				return err
+			}
+			keys = append(keys, key)
+		}
+		return rows.Err()
+	})
+}
+
+// RevokeAPIKey mark api key revoked
+func (p *Postgres) RevokeAPIKey(ctx context.Context, id string) error {
+	return p.beginTransaction(ctx, func(tx *transaction) error {
+		before := new(auth.APIKey)
+		q := "SELECT " + apiKeyColumns + " FROM api_keys WHERE id=$1 AND revoked_at IS NULL FOR UPDATE"
+		err := scanAPIKey(tx.QueryRow(q, id), before)
+		if err == sql.ErrNoRows || isInvalidText(err) {
+			return auth.ErrorNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		after := new(auth.APIKey)
+		q = "UPDATE api_keys SET revoked_at = NOW() WHERE id=$1 RETURNING " + apiKeyColumns
+		if err = scanAPIKey(tx.QueryRow(q, id), after); err != nil {
+			return err
+		}
+		return appendAudit(tx, audit.ActionRevokeAPIKey, audit.EntityAPIKey, id, before, after)
+	})
+}
+
+func scanAPIKey(row scanner, key *auth.APIKey) error {
+	var customerID sql.NullString
+	var revokedAt pq.NullTime
+	err := row.Scan(
+		&key.ID,
+		&customerID,
+		&key.Name,
+		pq.Array(&key.Scopes),
+		&key.CreatedAt,
+		&revokedAt,
+	)
+	if err != nil {
+		return err
+	}
+	key.CustomerID = customerID.String
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return nil
+}
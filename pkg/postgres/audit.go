@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/sbutakov/wallet/pkg/audit"
+)
+
+const (
+	auditColumns = "id,occurred_at,actor,request_id,action,entity_type,entity_id,before,after,prev_hash,hash"
+
+	// auditLockKey key of advisory lock serializing appends to audit chain
+	auditLockKey = 7291
+)
+
+// appendAudit append entry to audit log within transaction of operation, appends are
+// serialized by advisory lock held until end of transaction so chain never forks
+func appendAudit(
+	tx *transaction, action, entityType, entityID string, before, after interface{}) error {
+
+	entry, err := audit.NewEntry(tx.ctx, action, entityType, entityID, before, after)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec("SELECT pg_advisory_xact_lock($1)", auditLockKey); err != nil {
+		return err
+	}
+
+	err = tx.QueryRow("SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1").Scan(&entry.PrevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	if err = tx.QueryRow("SELECT nextval('audit_log_id_seq')").Scan(&entry.ID); err != nil {
+		return err
+	}
+
+	entry.Hash = entry.ComputeHash()
+	q := "INSERT INTO audit_log(" + auditColumns + ") VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)"
+	_, err = tx.Exec(q,
+		entry.ID,
+		entry.OccurredAt,
+		entry.Actor,
+		nullString(entry.RequestID),
+		entry.Action,
+		entry.EntityType,
+		entry.EntityID,
+		nullString(string(entry.Before)),
+		nullString(string(entry.After)),
+		entry.PrevHash,
+		entry.Hash,
+	)
+	return err
+}
+
+// ListAuditEntries return audit entries matched filter ordered by identifier
+func (p *Postgres) ListAuditEntries(ctx context.Context, filter audit.Filter) ([]*audit.Entry, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, condition+"$"+strconv.Itoa(len(args)))
+	}
+
+	addCondition("id > ", filter.AfterID)
+	if filter.EntityType != "" {
+		addCondition("entity_type = ", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		addCondition("entity_id = ", filter.EntityID)
+	}
+	args = append(args, filter.Limit)
+
+	q := "SELECT " + auditColumns + " FROM audit_log WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY id LIMIT $" + strconv.Itoa(len(args))
+
+	var entries []*audit.Entry
+	return entries, p.beginTransaction(ctx, func(tx *transaction) error {
+		rows, err := tx.Query(q, args...)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+		for rows.Next() {
+			entry := new(audit.Entry)
+			if err = scanAuditEntry(rows, entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		return rows.Err()
+	})
+}
+
+func scanAuditEntry(row scanner, entry *audit.Entry) error {
+	var requestID, before, after sql.NullString
+	err := row.Scan(
+		&entry.ID,
+		&entry.OccurredAt,
+		&entry.Actor,
+		&requestID,
+		&entry.Action,
+		&entry.EntityType,
+		&entry.EntityID,
+		&before,
+		&after,
+		&entry.PrevHash,
+		&entry.Hash,
+	)
+	if err != nil {
+		return err
+	}
+	entry.RequestID = requestID.String
+	if before.Valid {
+		entry.Before = json.RawMessage(before.String)
+	}
+	if after.Valid {
+		entry.After = json.RawMessage(after.String)
+	}
+	return nil
+}
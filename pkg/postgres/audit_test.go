@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/sbutakov/wallet/pkg/audit"
+)
+
+// testDSNEnv environment variable with DSN of database used by tests of storage, tests
+// append to audit log which cannot be cleaned, so database must be dedicated to tests
+const testDSNEnv = "POSTGRES_TEST_DSN"
+
+// newTestPostgres make storage initialized by schema script in database of test DSN
+func newTestPostgres(t *testing.T) *Postgres {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skip(testDSNEnv + " is not set")
+	}
+
+	instance, err := New(Config{DSN: dsn, FilePath: "../../etc/db/schema.sql"})
+	if err != nil {
+		t.Fatalf("unexpected error on init storage: %v", err)
+	}
+	t.Cleanup(func() { _ = instance.connection.Close() })
+	return instance
+}
+
+func TestPostgres_AuditChain(t *testing.T) {
+	instance := newTestPostgres(t)
+	ctx := context.Background()
+	for _, name := range []string{"Alice", "Bob"} {
+		if _, err := instance.CreateCustomer(ctx, name, ""); err != nil {
+			t.Fatalf("unexpected error on create customer: %v", err)
+		}
+	}
+
+	genesis, err := instance.ListAuditEntries(ctx, audit.Filter{Limit: 1})
+	if err != nil || len(genesis) != 1 {
+		t.Fatal("expected first entry of audit log")
+	}
+
+	if genesis[0].PrevHash != "" {
+		t.Errorf("expected empty link of first entry, got %q", genesis[0].PrevHash)
+	}
+
+	result, err := audit.New(instance).Verify(ctx)
+	if err != nil || result.Entries < 2 {
+		t.Errorf("expected valid chain read from storage: %v", err)
+	}
+
+	last, err := instance.ListAuditEntries(ctx, audit.Filter{AfterID: result.LastID - 1, Limit: 1})
+	if err != nil || len(last) != 1 || last[0].Hash != result.LastHash || len(last[0].Hash) != 64 {
+		t.Error("expected hash of last entry read from storage as computed")
+	}
+}
@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/sbutakov/wallet/pkg/audit"
+	"github.com/sbutakov/wallet/pkg/closing"
+)
+
+// errorCodePeriodClosed raised by triggers on change of payments or snapshots of closed period
+const errorCodePeriodClosed = "WL001"
+
+// LastSnapshotDay return last day of taken snapshots, zero time when no snapshot is taken
+func (p *Postgres) LastSnapshotDay(ctx context.Context) (time.Time, error) {
+	var last time.Time
+	return last, p.beginTransaction(ctx, func(tx *transaction) error {
+		var day sql.NullString
+		q := "SELECT to_char(MAX(day), 'YYYY-MM-DD') FROM balance_snapshots"
+		if err := tx.QueryRow(q).Scan(&day); err != nil || !day.Valid {
+			return err
+		}
+
+		var err error
+		last, err = closing.ParseDay(day.String)
+		return err
+	})
+}
+
+// TakeSnapshots record balances at end of day of accounts opened before end of day, balance is derived
+// from current balance, so snapshot does not depend on previous one, snapshots taken before are kept.
+// Accounts are locked for share, so transfers in flight are committed before balances are derived
+// and later transfers are stamped after snapshot
+func (p *Postgres) TakeSnapshots(ctx context.Context, day time.Time) (int64, error) {
+	var taken int64
+	return taken, p.beginTransaction(ctx, func(tx *transaction) error {
+		var err error
+		taken, err = takeSnapshots(tx, day)
+		return err
+	})
+}
+
+func takeSnapshots(tx *transaction, day time.Time) (int64, error) {
+	q := "SELECT id FROM accounts WHERE created_at < $1 ORDER BY id FOR SHARE"
+	if _, err := tx.Exec(q, day.AddDate(0, 0, 1)); err != nil {
+		return 0, err
+	}
+
+	q = "INSERT INTO balance_snapshots(account_id, day, as_of, balance) " +
+		"SELECT a.id, $1::date, $2, " + derivedBalance("$2") + " FROM accounts a WHERE a.created_at < $2 " +
+		"ON CONFLICT DO NOTHING"
+	res, err := tx.Exec(q, day.Format(closing.DayLayout), day.AddDate(0, 0, 1), paymentOutgoingDirection)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// VerifySnapshots return snapshots of days within [from, to) differing from balances derived
+// from payments, snapshots and payments are read from the same snapshot of database
+func (p *Postgres) VerifySnapshots(ctx context.Context, from, to time.Time) (*closing.VerifyResult, error) {
+	result := &closing.VerifyResult{Mismatches: []*closing.Mismatch{}}
+	return result, p.beginTransaction(ctx, func(tx *transaction) error {
+		if _, err := tx.Exec("SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY"); err != nil {
+			return err
+		}
+
+		start, end := from.Format(closing.DayLayout), to.Format(closing.DayLayout)
+		q := "SELECT COUNT(*) FROM balance_snapshots WHERE day >= $1::date AND day < $2::date"
+		if err := tx.QueryRow(q, start, end).Scan(&result.Snapshots); err != nil {
+			return err
+		}
+
+		q = "SELECT account_id, to_char(day, 'YYYY-MM-DD'), balance, derived FROM (" +
+			"SELECT s.account_id, s.day, s.balance, " + derivedBalance("s.as_of") + " AS derived " +
+			"FROM balance_snapshots s JOIN accounts a ON a.id = s.account_id " +
+			"WHERE s.day >= $1::date AND s.day < $2::date) v " +
+			"WHERE balance <> derived ORDER BY day, account_id"
+		rows, err := tx.Query(q, start, end, paymentOutgoingDirection)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+		for rows.Next() {
+			res := new(closing.Mismatch)
+			if err = rows.Scan(&res.AccountID, &res.Day, &res.Balance, &res.Derived); err != nil {
+				return err
+			}
+			result.Mismatches = append(result.Mismatches, res)
+		}
+		return rows.Err()
+	})
+}
+
+// ClosePeriod close days before until, missing snapshots of last closed day are taken within
+// transaction of closing, closings are serialized, so closed period only grows
+func (p *Postgres) ClosePeriod(ctx context.Context, until time.Time) (*closing.Closing, error) {
+	result := new(closing.Closing)
+	return result, p.beginTransaction(ctx, func(tx *transaction) error {
+		if _, err := tx.Exec("LOCK TABLE period_closings IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+			return err
+		}
+
+		day := until.Format(closing.DayLayout)
+		var closed bool
+		q := "SELECT EXISTS(SELECT 1 FROM period_closings WHERE closed_until >= $1::date)"
+		if err := tx.QueryRow(q, day).Scan(&closed); err != nil {
+			return err
+		}
+		if closed {
+			return closing.ErrorAlreadyClosed
+		}
+
+		if _, err := takeSnapshots(tx, until.AddDate(0, 0, -1)); err != nil {
+			return err
+		}
+
+		q = "INSERT INTO period_closings(closed_until, actor) VALUES($1::date, $2) " +
+			"RETURNING to_char(closed_until, 'YYYY-MM-DD'), closed_at, actor"
+		err := tx.QueryRow(q, day, audit.ActorFromContext(ctx)).
+			Scan(&result.ClosedUntil, &result.ClosedAt, &result.Actor)
+		if err != nil {
+			return err
+		}
+		return appendAudit(tx, audit.ActionClosePeriod, audit.EntityPeriod, result.ClosedUntil, nil, result)
+	})
+}
+
+// ListClosings return closings of periods, last closing is first
+func (p *Postgres) ListClosings(ctx context.Context) ([]*closing.Closing, error) {
+	closings := []*closing.Closing{}
+	return closings, p.beginTransaction(ctx, func(tx *transaction) error {
+		q := "SELECT to_char(closed_until, 'YYYY-MM-DD'), closed_at, actor FROM period_closings " +
+			"ORDER BY closed_until DESC"
+		rows, err := tx.Query(q)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+		for rows.Next() {
+			res := new(closing.Closing)
+			if err = rows.Scan(&res.ClosedUntil, &res.ClosedAt, &res.Actor); err != nil {
+				return err
+			}
+			closings = append(closings, res)
+		}
+		return rows.Err()
+	})
+}
+
+// isPeriodClosed change of payment or snapshot is rejected because it falls into closed period
+func isPeriodClosed(err error) bool {
+	e, ok := err.(*pq.Error)
+	return ok && e.Code == errorCodePeriodClosed
+}
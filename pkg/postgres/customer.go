@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/satori/go.uuid"
+
+	"github.com/sbutakov/wallet/pkg/audit"
+	"github.com/sbutakov/wallet/pkg/customer"
+)
+
+const customerColumns = "id,name,email,created_at"
+
+// CreateCustomer create customer
+func (p *Postgres) CreateCustomer(ctx context.Context, name, email string) (*customer.Customer, error) {
+	c := new(customer.Customer)
+	return c, p.beginTransaction(ctx, func(tx *transaction) error {
+		q := "INSERT INTO customers(id,name,email) VALUES($1, $2, $3) RETURNING " + customerColumns
+		row := tx.QueryRow(q, uuid.NewV4().String(), name, nullString(email))
+		if err := scanCustomer(row, c); err != nil {
+			return err
+		}
+		return appendAudit(tx, audit.ActionCreateCustomer, audit.EntityCustomer, c.ID, nil, c)
+	})
+}
+
+// ListCustomers return stored customers
+func (p *Postgres) ListCustomers(ctx context.Context) ([]*customer.Customer, error) {
+	var customers []*customer.Customer
+	return customers, p.beginTransaction(ctx, func(tx *transaction) error {
+		q := "SELECT " + customerColumns + " FROM customers ORDER BY created_at"
+		rows, err := tx.Query(q)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+		for rows.Next() {
+			c := new(customer.Customer)
+			if err = scanCustomer(rows, c); err != nil {
+				return err
+			}
+			customers = append(customers, c)
+		}
+		return rows.Err()
+	})
+}
+
+// AssertCustomer assert customer stored in database
+func (p *Postgres) AssertCustomer(ctx context.Context, id string) (*customer.Customer, error) {
+	c := new(customer.Customer)
+	return c, p.beginTransaction(ctx, func(tx *transaction) error {
+		q := "SELECT " + customerColumns + " FROM customers WHERE id=$1"
+		err := scanCustomer(tx.QueryRow(q, id), c)
+		if err == sql.ErrNoRows || isInvalidText(err) {
+			return customer.ErrorNotFound
+		}
+		return err
+	})
+}
+
+func scanCustomer(row scanner, c *customer.Customer) error {
+	var email sql.NullString
+	err := row.Scan(
+		&c.ID,
+		&c.Name,
+		&email,
+		&c.CreatedAt,
+	)
+	c.Email = email.String
+	return err
+}
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// schemaTables tables created by initialize sql script
+var schemaTables = []string{
+	"customers",
+	"accounts",
+	"payments",
+	"api_keys",
+	"audit_log",
+	"rate_limits",
+	"report_currency_balances",
+	"report_daily_account_volume",
+	"balance_snapshots",
+	"period_closings",
+	"events",
+	"projections",
+	"account_summaries",
+	"account_counters",
+	"account_activity",
+	"aliases",
+	"alias_accounts",
+	"payment_requests",
+}
+
+// Ping check connection to database server is alive
+func (p *Postgres) Ping(ctx context.Context) error {
+	if err := p.connection.PingContext(ctx); err != nil {
+		return errors.Wrap(err, "error on ping database server")
+	}
+	return nil
+}
+
+// AssertSchema check all tables of schema are created
+func (p *Postgres) AssertSchema(ctx context.Context) error {
+	q := "SELECT to_regclass($1) IS NOT NULL"
+	for _, table := range schemaTables {
+		var exists bool
+		if err := p.connection.QueryRowContext(ctx, q, table).Scan(&exists); err != nil {
+			return errors.Wrap(err, "error on check schema")
+		}
+		if !exists {
+			return errors.Errorf("table %s does not exist", table)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+	"github.com/satori/go.uuid"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/audit"
+	"github.com/sbutakov/wallet/pkg/customer"
+	"github.com/sbutakov/wallet/pkg/importer"
+	"github.com/sbutakov/wallet/pkg/projection"
+)
+
+// ExistingExternalReferences return external references of accounts stored in database
+func (p *Postgres) ExistingExternalReferences(ctx context.Context, references []string) ([]string, error) {
+	var existing []string
+	return existing, p.beginTransaction(ctx, func(tx *transaction) error {
+		rows, err := tx.Query("SELECT external_id FROM accounts WHERE external_id = ANY($1)", pq.Array(references))
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+		for rows.Next() {
+			var reference string
+			if err = rows.Scan(&reference); err != nil {
+				return err
+			}
+			existing = append(existing, reference)
+		}
+		return rows.Err()
+	})
+}
+
+// ImportAccounts create accounts of rows in single transaction, accounts are returned in order of rows
+func (p *Postgres) ImportAccounts(ctx context.Context, rows []*importer.Row) ([]*account.Account, error) {
+	var accounts []*account.Account
+	return accounts, p.beginTransaction(ctx, func(tx *transaction) error {
+		q := "INSERT INTO accounts(id,customer_id,name,currency,balance,external_id) " +
+			"VALUES($1, $2, $3, $4, $5, $6) RETURNING " + accountColumns
+		for _, row := range rows {
+			acc := new(account.Account)
+			err := scanAccount(tx.QueryRow(q, uuid.NewV4().String(), nullString(row.CustomerID),
+				row.Name, row.Currency, row.Balance, nullString(row.ExternalReference)), acc)
+			if isForeignKeyViolation(err) || isInvalidText(err) {
+				return customer.ErrorNotFound
+			}
+			if isUniqueViolation(err) {
+				return importer.ErrorDuplicateExternalReference
+			}
+			if err != nil {
+				return err
+			}
+
+			if err = appendAudit(tx, audit.ActionCreateAccount, audit.EntityAccount, acc.ID, nil, acc); err != nil {
+				return err
+			}
+			if err = appendEvent(tx, projection.EventAccountCreated, acc.ID, acc); err != nil {
+				return err
+			}
+			accounts = append(accounts, acc)
+		}
+		return nil
+	})
+}
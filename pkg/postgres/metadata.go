@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/sbutakov/wallet/pkg/metadata"
+)
+
+// metadataJSON encode metadata for jsonb column, absent metadata is stored as empty object
+func metadataJSON(m metadata.Metadata) (string, error) {
+	if len(m) == 0 {
+		return "{}", nil
+	}
+	data, err := json.Marshal(m)
+	return string(data), err
+}
+
+// decodeMetadata decode metadata of jsonb column, empty object is decoded as nil
+func decodeMetadata(data []byte) (metadata.Metadata, error) {
+	var m metadata.Metadata
+	if err := json.Unmarshal(data, &m); err != nil || len(m) == 0 {
+		return nil, err
+	}
+	return m, nil
+}
+
+// filterConditions append conditions of filter on columns of table alias to where clause,
+// metadata is matched by containment, so it is served by gin index
+func filterConditions(
+	where string, alias string, filter metadata.Filter, args []interface{}) (string, []interface{}, error) {
+
+	if filter.ExternalID != "" {
+		args = append(args, filter.ExternalID)
+		where = and(where, alias+"external_id=$"+strconv.Itoa(len(args)))
+	}
+	if len(filter.Metadata) > 0 {
+		m, err := metadataJSON(filter.Metadata)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, m)
+		where = and(where, alias+"metadata @> $"+strconv.Itoa(len(args))+"::jsonb")
+	}
+	return where, args, nil
+}
+
+// and append condition to where clause
+func and(where, condition string) string {
+	if where == "" {
+		return " WHERE " + condition
+	}
+	return where + " AND " + condition
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/sbutakov/wallet/pkg/metrics"
+)
+
+// RegisterMetrics register gauges of connection pool statistics
+func (p *Postgres) RegisterMetrics(registry *metrics.Registry) {
+	gauges := []struct {
+		name  string
+		help  string
+		value func(stats sql.DBStats) float64
+	}{
+		{"db_max_open_connections", "Maximum number of open connections to the database.",
+			func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+		{"db_open_connections", "Number of established connections both in use and idle.",
+			func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+		{"db_in_use_connections", "Number of connections currently in use.",
+			func(s sql.DBStats) float64 { return float64(s.InUse) }},
+		{"db_idle_connections", "Number of idle connections.",
+			func(s sql.DBStats) float64 { return float64(s.Idle) }},
+		{"db_wait_count_total", "Total number of connections waited for.",
+			func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+		{"db_wait_duration_seconds_total", "Total time blocked waiting for a new connection.",
+			func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+		{"db_max_idle_closed_total", "Total number of connections closed due to max idle limit.",
+			func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+		{"db_max_lifetime_closed_total", "Total number of connections closed due to max lifetime.",
+			func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+	}
+
+	for _, gauge := range gauges {
+		value := gauge.value
+		registry.NewGaugeFunc(gauge.name, gauge.help, func() float64 {
+			return value(p.Stats())
+		})
+	}
+}
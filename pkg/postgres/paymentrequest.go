@@ -0,0 +1,245 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+
+	"github.com/sbutakov/wallet/pkg/audit"
+	"github.com/sbutakov/wallet/pkg/paymentrequest"
+)
+
+// paymentRequestColumns columns of payment requests, pending request past its expiry is selected
+// as expired before sweeper changes its status
+const paymentRequestColumns = "r.id, r.payee_account, r.payer_account, r.amount, r.currency, r.memo, " +
+	"CASE WHEN r.status = '" + paymentrequest.StatusPending + "' AND r.expires_at <= NOW() " +
+	"THEN '" + paymentrequest.StatusExpired + "' ELSE r.status END, " +
+	"r.payment_id, r.expires_at, r.created_at, r.updated_at"
+
+// unpaidRequest condition of request r without payment accepting it
+const unpaidRequest = "NOT EXISTS (SELECT 1 FROM payments p WHERE p.direction = 'outgoing' AND " +
+	"p.external_id = '" + paymentrequest.ExternalIDPrefix + "' || r.id::text)"
+
+// CreatePaymentRequest create pending payment request
+func (p *Postgres) CreatePaymentRequest(
+	ctx context.Context, req *paymentrequest.Request) (*paymentrequest.Request, error) {
+
+	res := new(paymentrequest.Request)
+	return res, p.beginTransaction(ctx, func(tx *transaction) error {
+		id := uuid.NewV4().String()
+		q := "INSERT INTO payment_requests(id, payee_account, payer_account, amount, currency, memo, expires_at) " +
+			"VALUES($1, $2, $3, $4, $5, $6, $7)"
+		_, err := tx.Exec(q, id, req.PayeeAccountID, req.PayerAccountID, req.Amount, req.Currency,
+			nullString(req.Memo), req.ExpiresAt)
+		if err != nil {
+			return err
+		}
+
+		if err = loadPaymentRequest(tx, id, false, res); err != nil {
+			return err
+		}
+		return appendAudit(tx, audit.ActionCreatePaymentRequest, audit.EntityPaymentRequest, id, nil, res)
+	})
+}
+
+// GetPaymentRequest return payment request
+func (p *Postgres) GetPaymentRequest(ctx context.Context, id string) (*paymentrequest.Request, error) {
+	res := new(paymentrequest.Request)
+	return res, p.beginTransaction(ctx, func(tx *transaction) error {
+		return loadPaymentRequest(tx, id, false, res)
+	})
+}
+
+// ListPaymentRequests return payment requests matching filter sent or received by accounts of customer,
+// requests of all customers are returned when customer is empty
+func (p *Postgres) ListPaymentRequests(ctx context.Context, customerID string,
+	filter paymentrequest.Filter) ([]*paymentrequest.Request, error) {
+
+	requests := []*paymentrequest.Request{}
+	return requests, p.beginTransaction(ctx, func(tx *transaction) error {
+		q := "SELECT " + paymentRequestColumns + " FROM payment_requests r " +
+			"JOIN accounts payee ON payee.id = r.payee_account JOIN accounts payer ON payer.id = r.payer_account " +
+			"WHERE ($1 = '' OR payee.customer_id::text = $1 OR payer.customer_id::text = $1) " +
+			"AND ($2 = '' OR r.payee_account::text = $2 OR r.payer_account::text = $2) " +
+			"AND ($3 = '' OR CASE WHEN r.status = '" + paymentrequest.StatusPending + "' AND r.expires_at <= NOW() " +
+			"THEN '" + paymentrequest.StatusExpired + "' ELSE r.status END = $3) " +
+			"ORDER BY r.created_at DESC"
+		rows, err := tx.Query(q, customerID, filter.AccountID, filter.Status)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+		for rows.Next() {
+			res := new(paymentrequest.Request)
+			if err = scanPaymentRequest(rows, res); err != nil {
+				return err
+			}
+			requests = append(requests, res)
+		}
+		return rows.Err()
+	})
+}
+
+// AcceptPaymentRequest lock pending request, pay it by payment with its external identifier made
+// within the same transaction and mark request accepted, payment made before is not repeated,
+// payment must transfer requested amount from payer to payee
+func (p *Postgres) AcceptPaymentRequest(ctx context.Context, id string,
+	pay func(ctx context.Context, req *paymentrequest.Request) error) (*paymentrequest.Request, error) {
+
+	res := new(paymentrequest.Request)
+	return res, p.beginTransaction(ctx, func(tx *transaction) error {
+		before := new(paymentrequest.Request)
+		if err := loadPaymentRequest(tx, id, true, before); err != nil {
+			return err
+		}
+		switch before.Status {
+		case paymentrequest.StatusPending:
+		case paymentrequest.StatusExpired:
+			return paymentrequest.ErrorExpired
+		default:
+			return paymentrequest.ErrorNotPending
+		}
+
+		paymentID, err := requestPaymentID(tx, before)
+		if err == sql.ErrNoRows {
+			if err = pay(withTransaction(tx), before); err != nil {
+				return err
+			}
+			paymentID, err = requestPaymentID(tx, before)
+		}
+		if err == sql.ErrNoRows {
+			return errors.Errorf("payment accepting request %s not found", id)
+		}
+		if err != nil {
+			return err
+		}
+
+		q := "UPDATE payment_requests SET status=$2, payment_id=$3, updated_at=NOW() WHERE id=$1"
+		if _, err = tx.Exec(q, id, paymentrequest.StatusAccepted, paymentID); err != nil {
+			return err
+		}
+		if err = loadPaymentRequest(tx, id, false, res); err != nil {
+			return err
+		}
+		return appendAudit(tx, audit.ActionAcceptPaymentRequest, audit.EntityPaymentRequest, id, before, res)
+	})
+}
+
+// requestPaymentID return identifier of payment made with external identifier of request
+func requestPaymentID(tx *transaction, req *paymentrequest.Request) (string, error) {
+	var paymentID, accountFrom, accountTo string
+	var amount float64
+	q := "SELECT id, account, account_to, amount FROM payments WHERE external_id=$1 AND direction = 'outgoing'"
+	err := tx.QueryRow(q, paymentrequest.ExternalID(req.ID)).Scan(&paymentID, &accountFrom, &accountTo, &amount)
+	if err != nil {
+		return "", err
+	}
+	if accountFrom != req.PayerAccountID || accountTo != req.PayeeAccountID || amount != req.Amount {
+		return "", errors.Wrapf(paymentrequest.ErrorPaymentMismatch, "payment %s of request %s", paymentID, req.ID)
+	}
+	return paymentID, nil
+}
+
+// ResolvePaymentRequest move pending request without payment to declined or canceled status
+func (p *Postgres) ResolvePaymentRequest(ctx context.Context, id, status string) (*paymentrequest.Request, error) {
+	res := new(paymentrequest.Request)
+	return res, p.beginTransaction(ctx, func(tx *transaction) error {
+		before := new(paymentrequest.Request)
+		if err := loadPaymentRequest(tx, id, true, before); err != nil {
+			return err
+		}
+		if before.Status == paymentrequest.StatusExpired {
+			return paymentrequest.ErrorExpired
+		}
+
+		q := "UPDATE payment_requests r SET status=$2, updated_at=NOW() " +
+			"WHERE id=$1 AND status = '" + paymentrequest.StatusPending + "' AND " + unpaidRequest
+		result, err := tx.Exec(q, id, status)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return paymentrequest.ErrorNotPending
+		}
+
+		if err = loadPaymentRequest(tx, id, false, res); err != nil {
+			return err
+		}
+		action := audit.ActionDeclinePaymentRequest
+		if status == paymentrequest.StatusCanceled {
+			action = audit.ActionCancelPaymentRequest
+		}
+		return appendAudit(tx, action, audit.EntityPaymentRequest, id, before, res)
+	})
+}
+
+// ExpirePaymentRequests mark pending requests past their expiry and without payment expired
+func (p *Postgres) ExpirePaymentRequests(ctx context.Context) (int64, error) {
+	var expired int64
+	return expired, p.beginTransaction(ctx, func(tx *transaction) error {
+		q := "UPDATE payment_requests r SET status=$1, updated_at=NOW() " +
+			"WHERE status = '" + paymentrequest.StatusPending + "' AND expires_at <= NOW() AND " + unpaidRequest +
+			" RETURNING " + paymentRequestColumns
+		rows, err := tx.Query(q, paymentrequest.StatusExpired)
+		if err != nil {
+			return err
+		}
+
+		var requests []*paymentrequest.Request
+		for rows.Next() {
+			res := new(paymentrequest.Request)
+			if err = scanPaymentRequest(rows, res); err != nil {
+				rows.Close() // nolint: errcheck
+				return err
+			}
+			requests = append(requests, res)
+		}
+		if err = rows.Close(); err != nil {
+			return err
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		for _, res := range requests {
+			err = appendAudit(tx, audit.ActionExpirePaymentRequest, audit.EntityPaymentRequest, res.ID, nil, res)
+			if err != nil {
+				return err
+			}
+		}
+		expired = int64(len(requests))
+		return nil
+	})
+}
+
+// loadPaymentRequest scan payment request, request is locked until end of transaction when lock is set
+func loadPaymentRequest(tx *transaction, id string, lock bool, res *paymentrequest.Request) error {
+	q := "SELECT " + paymentRequestColumns + " FROM payment_requests r WHERE r.id=$1"
+	if lock {
+		q += " FOR UPDATE"
+	}
+	err := scanPaymentRequest(tx.QueryRow(q, id), res)
+	if err == sql.ErrNoRows || isInvalidText(err) {
+		return paymentrequest.ErrorNotFound
+	}
+	return err
+}
+
+func scanPaymentRequest(row scanner, res *paymentrequest.Request) error {
+	var memo, paymentID sql.NullString
+	err := row.Scan(&res.ID, &res.PayeeAccountID, &res.PayerAccountID, &res.Amount, &res.Currency, &memo,
+		&res.Status, &paymentID, &res.ExpiresAt, &res.CreatedAt, &res.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	res.Memo = memo.String
+	res.PaymentID = paymentID.String
+	return nil
+}
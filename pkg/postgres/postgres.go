@@ -2,8 +2,10 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"io/ioutil"
+	"strconv"
 	"time"
 
 	"github.com/lib/pq"
@@ -11,7 +13,13 @@ import (
 	"github.com/satori/go.uuid"
 
 	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/audit"
+	"github.com/sbutakov/wallet/pkg/closing"
+	"github.com/sbutakov/wallet/pkg/customer"
+	"github.com/sbutakov/wallet/pkg/metadata"
 	"github.com/sbutakov/wallet/pkg/payment"
+	"github.com/sbutakov/wallet/pkg/projection"
+	"github.com/sbutakov/wallet/pkg/tracing"
 )
 
 const (
@@ -22,7 +30,18 @@ const (
 	paymentOutgoingDirection = "outgoing"
 	paymentIncomingDirection = "incoming"
 
-	errorCodeConnectionFailure = "08006"
+	errorCodeConnectionFailure   = "08006"
+	errorCodeForeignKeyViolation = "23503"
+	errorCodeInvalidText         = "22P02"
+	errorCodeUniqueViolation     = "23505"
+
+	accountColumns = "id,customer_id,name,currency,balance,external_id,metadata,created_at"
+	paymentColumns = "p.id,p.account,p.account_to,p.amount,a.currency,p.direction,p.external_id,p.metadata," +
+		"p.description,p.category,p.created_at"
+
+	// searchConfig text search configuration of descriptions of payments, words are not stemmed
+	// because descriptions are written in any language, index payments_description_idx uses it
+	searchConfig = "'simple'"
 )
 
 // ErrorBadConnection connection failure
@@ -35,6 +54,13 @@ func (e *ErrorBadConnection) Error() string {
 	return e.msg
 }
 
+func badConnection(err error) error {
+	if e, ok := err.(*pq.Error); ok && e.Code == errorCodeConnectionFailure {
+		return &ErrorBadConnection{parent: err, msg: e.Message}
+	}
+	return err
+}
+
 // Config configuration params for connect to database server
 type Config struct {
 	DSN                string
@@ -47,6 +73,7 @@ type Config struct {
 // Postgres execute query to database
 type Postgres struct {
 	connection *sql.DB
+	tracer     *tracing.Tracer
 }
 
 // New is constructor
@@ -81,8 +108,8 @@ func New(config Config) (*Postgres, error) {
 			return nil, errors.Wrap(err, "error on read initialize sql script")
 		}
 
-		err = postgres.beginTransaction(func(tx *sql.Tx) error {
-			_, err = connection.Exec(string(initQuery))
+		err = postgres.beginTransaction(context.Background(), func(tx *transaction) error {
+			_, err = tx.Exec(string(initQuery))
 			return err
 		})
 		if err != nil {
@@ -93,58 +120,106 @@ func New(config Config) (*Postgres, error) {
 	return postgres, nil
 }
 
-func (p *Postgres) beginTransaction(doQuery func(tx *sql.Tx) error) error {
-	tx, err := p.connection.Begin()
+// Stats return statistics of connection pool
+func (p *Postgres) Stats() sql.DBStats {
+	return p.connection.Stats()
+}
+
+// SetTracer set tracer recording spans of transactions and sql statements
+func (p *Postgres) SetTracer(tracer *tracing.Tracer) {
+	p.tracer = tracer
+}
+
+// beginTransaction run queries within transaction, transaction of context (see withTransaction)
+// is joined and committed by its owner
+func (p *Postgres) beginTransaction(ctx context.Context, doQuery func(tx *transaction) error) (err error) {
+	span, ctx := p.tracer.StartSpan(ctx, "postgres.transaction")
+	defer func() {
+		span.SetError(err)
+		span.Finish()
+	}()
+
+	if outer, ok := ctx.Value(contextKeyTransaction).(*transaction); ok {
+		return badConnection(doQuery(&transaction{ctx: ctx, tx: outer.tx, tracer: p.tracer}))
+	}
+
+	sqlTx, err := p.connection.BeginTx(ctx, nil)
 	if err != nil {
 		return errors.Wrap(err, "error on begin transaction")
 	}
-	defer tx.Rollback() // nolint: errcheck
+	tx := &transaction{ctx: ctx, tx: sqlTx, tracer: p.tracer}
+	defer sqlTx.Rollback() // nolint: errcheck
 
 	if err = doQuery(tx); err != nil {
-		if e, ok := err.(*pq.Error); ok && e.Code == errorCodeConnectionFailure {
-			return &ErrorBadConnection{parent: err, msg: e.Message}
-		}
-		return err
+		return badConnection(err)
 	}
 
-	if err = tx.Commit(); err != nil {
+	if err = sqlTx.Commit(); err != nil {
 		return errors.Wrap(err, "error on commit transaction")
 	}
 	return nil
 }
 
-// CreateAccount create account
-func (p *Postgres) CreateAccount(name, currency string, balance float64) (*account.Account, error) {
+// CreateAccount create account owned by customer, customer and external identifier are optional
+func (p *Postgres) CreateAccount(ctx context.Context, customerID, name, currency string, balance float64,
+	externalID string, meta metadata.Metadata) (*account.Account, error) {
+
 	acc := new(account.Account)
-	return acc, p.beginTransaction(func(tx *sql.Tx) error {
+	return acc, p.beginTransaction(ctx, func(tx *transaction) error {
+		m, err := metadataJSON(meta)
+		if err != nil {
+			return err
+		}
+
 		id := uuid.NewV4().String()
-		q := "INSERT INTO accounts(id,name,currency,balance) VALUES($1, $2, $3, $4)"
-		_, err := tx.Exec(q, id, name, currency, balance)
+		q := "INSERT INTO accounts(id,customer_id,name,currency,balance,external_id,metadata) " +
+			"VALUES($1, $2, $3, $4, $5, $6, $7)"
+		_, err = tx.Exec(q, id, nullString(customerID), name, currency, balance, nullString(externalID), m)
+		if isForeignKeyViolation(err) || isInvalidText(err) {
+			return customer.ErrorNotFound
+		}
+		if isUniqueViolation(err) {
+			return metadata.ErrorDuplicateExternalID
+		}
 		if err != nil {
 			return err
 		}
 
-		q = "SELECT id,name,currency,balance,created_at FROM accounts WHERE id=$1"
-		row := tx.QueryRow(q, id)
-		if row == nil {
-			return account.ErrorNotFound
+		q = "SELECT " + accountColumns + " FROM accounts WHERE id=$1"
+		if err = scanAccount(tx.QueryRow(q, id), acc); err != nil {
+			return err
 		}
-		return row.Scan(
-			&acc.ID,
-			&acc.Name,
-			&acc.Currency,
-			&acc.Balance,
-			&acc.CreatedAt,
-		)
+		if err = appendAudit(tx, audit.ActionCreateAccount, audit.EntityAccount, acc.ID, nil, acc); err != nil {
+			return err
+		}
+		return appendEvent(tx, projection.EventAccountCreated, acc.ID, acc)
 	})
 }
 
-// ListAccount return stored accounts
-func (p *Postgres) ListAccount() ([]*account.Account, error) {
+// ListAccount return stored accounts matching filter
+func (p *Postgres) ListAccount(ctx context.Context, filter metadata.Filter) ([]*account.Account, error) {
+	return p.listAccounts(ctx, "accounts", "", filter)
+}
+
+// ListCustomerAccounts return accounts owned by customer matching filter
+func (p *Postgres) ListCustomerAccounts(
+	ctx context.Context, customerID string, filter metadata.Filter) ([]*account.Account, error) {
+
+	return p.listAccounts(ctx, "accounts", " WHERE customer_id=$1", filter, customerID)
+}
+
+func (p *Postgres) listAccounts(ctx context.Context,
+	table, where string, filter metadata.Filter, args ...interface{}) ([]*account.Account, error) {
+
 	var accounts []*account.Account
-	return accounts, p.beginTransaction(func(tx *sql.Tx) error {
-		q := "SELECT id,name,currency,balance,created_at FROM accounts ORDER BY created_at"
-		rows, err := tx.Query(q)
+	return accounts, p.beginTransaction(ctx, func(tx *transaction) error {
+		where, args, err := filterConditions(where, "", filter, args)
+		if err != nil {
+			return err
+		}
+
+		q := "SELECT " + accountColumns + " FROM " + table + where + " ORDER BY created_at"
+		rows, err := tx.Query(q, args...)
 		if err != nil {
 			return err
 		}
@@ -152,46 +227,51 @@ func (p *Postgres) ListAccount() ([]*account.Account, error) {
 		defer rows.Close()
 		for rows.Next() {
 			account := new(account.Account)
-			err = rows.Scan(
-				&account.ID,
-				&account.Name,
-				&account.Currency,
-				&account.Balance,
-				&account.CreatedAt,
-			)
-			if err != nil {
+			if err = scanAccount(rows, account); err != nil {
 				return err
 			}
 			accounts = append(accounts, account)
 		}
-		return nil
+		return rows.Err()
 	})
 }
 
 // AssertAccount assert account stored in database
-func (p *Postgres) AssertAccount(id string) (*account.Account, error) {
+func (p *Postgres) AssertAccount(ctx context.Context, id string) (*account.Account, error) {
 	acc := new(account.Account)
-	return acc, p.beginTransaction(func(tx *sql.Tx) error {
-		q := "SELECT id,name,currency,balance,created_at FROM accounts WHERE id=$1"
-		rows := tx.QueryRow(q, id)
-		if rows == nil {
+	return acc, p.beginTransaction(ctx, func(tx *transaction) error {
+		q := "SELECT " + accountColumns + " FROM accounts WHERE id=$1"
+		err := scanAccount(tx.QueryRow(q, id), acc)
+		if err == sql.ErrNoRows || isInvalidText(err) {
 			return account.ErrorNotFound
 		}
-
-		return rows.Scan(
-			&acc.ID,
-			&acc.Name,
-			&acc.Currency,
-			&acc.Balance,
-			&acc.CreatedAt,
-		)
+		return err
 	})
 }
 
-// TransferMoney transfer money between accounts
-func (p *Postgres) TransferMoney(accountFrom, accountTo string, amount float64) (*payment.Payment, error) {
+// TransferMoney transfer money between accounts, both transactions share details of transfer,
+// external identifier is unique among outgoing transactions. Transactions are stamped by clock
+// after accounts are locked, so snapshot of balances taken under lock includes every earlier payment
+func (p *Postgres) TransferMoney(ctx context.Context, accountFrom, accountTo string, amount float64,
+	details payment.Details) (*payment.Payment, error) {
+
 	paymentResult := new(payment.Payment)
-	return paymentResult, p.beginTransaction(func(tx *sql.Tx) error {
+	return paymentResult, p.beginTransaction(ctx, func(tx *transaction) error {
+		m, err := metadataJSON(details.Metadata)
+		if err != nil {
+			return err
+		}
+
+		before, err := lockAccounts(tx, accountFrom, accountTo)
+		if err != nil {
+			return err
+		}
+
+		var createdAt time.Time
+		if err = tx.QueryRow("SELECT clock_timestamp()").Scan(&createdAt); err != nil {
+			return err
+		}
+
 		q := "UPDATE accounts SET balance = balance - $1 WHERE balance >= $1 AND id=$2"
 		res, err := tx.Exec(q, amount, accountFrom)
 		if err != nil {
@@ -211,14 +291,25 @@ func (p *Postgres) TransferMoney(accountFrom, accountTo string, amount float64)
 		}
 
 		outgoingTransactUUID := uuid.NewV4().String()
-		q = "INSERT INTO payments(id, account, account_to,amount,direction) " +
-			"VALUES($1, $2, $3, $4, $5)"
+		q = "INSERT INTO payments(id, account, account_to,amount,direction,external_id,metadata," +
+			"description,category,created_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)"
 		_, err = tx.Exec(q,
 			outgoingTransactUUID,
 			accountFrom,
 			accountTo,
 			amount,
-			paymentOutgoingDirection)
+			paymentOutgoingDirection,
+			nullString(details.ExternalID),
+			m,
+			nullString(details.Description),
+			nullString(details.Category),
+			createdAt)
+		if isUniqueViolation(err) {
+			return metadata.ErrorDuplicateExternalID
+		}
+		if isPeriodClosed(err) {
+			return closing.ErrorPeriodClosed
+		}
 		if err != nil {
 			return err
 		}
@@ -229,34 +320,121 @@ func (p *Postgres) TransferMoney(accountFrom, accountTo string, amount float64)
 			accountTo,
 			accountFrom,
 			amount,
-			paymentIncomingDirection)
+			paymentIncomingDirection,
+			nullString(details.ExternalID),
+			m,
+			nullString(details.Description),
+			nullString(details.Category),
+			createdAt)
 		if err != nil {
 			return err
 		}
 
-		q = "SELECT id,account,account_to,amount,direction,created_at FROM payments WHERE id=$1"
+		q = "SELECT " + paymentColumns + " FROM payments p JOIN accounts a ON a.id = p.account " +
+			"WHERE p.id=$1"
 		row := tx.QueryRow(q, outgoingTransactUUID)
 		if row == nil {
 			return payment.ErrorMoneyTransfer
 		}
 
-		return row.Scan(
-			&paymentResult.ID,
-			&paymentResult.AccountFrom,
-			&paymentResult.AccountTo,
-			&paymentResult.Amount,
-			&paymentResult.Direction,
-			&paymentResult.CreatedAt,
-		)
+		if err = scanPayment(row, paymentResult); err != nil {
+			return err
+		}
+
+		after, err := lockAccounts(tx, accountFrom, accountTo)
+		if err != nil {
+			return err
+		}
+		err = appendAudit(tx, audit.ActionTransferMoney, audit.EntityPayment, paymentResult.ID,
+			transferSnapshot{Accounts: before},
+			transferSnapshot{Accounts: after, Payment: paymentResult})
+		if err != nil {
+			return err
+		}
+		return appendEvent(tx, projection.EventMoneyTransferred, paymentResult.ID, paymentResult)
 	})
 }
 
-// PaymentList returned payments stored in database
-func (p *Postgres) PaymentList() ([]*payment.Payment, error) {
+// transferSnapshot state of accounts and payment recorded in audit log on transfer money
+type transferSnapshot struct {
+	Accounts []*account.Account `json:"accounts"`
+	Payment  *payment.Payment   `json:"payment,omitempty"`
+}
+
+// lockAccounts return accounts locked until end of transaction, rows are locked
+// in order of identifiers for avoiding deadlock of concurrent transfers
+func lockAccounts(tx *transaction, ids ...string) ([]*account.Account, error) {
+	q := "SELECT " + accountColumns + " FROM accounts WHERE id = ANY($1) ORDER BY id FOR UPDATE"
+	rows, err := tx.Query(q, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var accounts []*account.Account
+	for rows.Next() {
+		acc := new(account.Account)
+		if err = scanAccount(rows, acc); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, acc)
+	}
+	return accounts, rows.Err()
+}
+
+// PaymentList returned payments stored in database matching filter
+func (p *Postgres) PaymentList(ctx context.Context, filter payment.Filter) ([]*payment.Payment, error) {
 	var payments []*payment.Payment
-	return payments, p.beginTransaction(func(tx *sql.Tx) error {
-		q := "SELECT id, account, account_to, amount, direction, created_at FROM payments"
-		rows, err := tx.Query(q)
+	return payments, p.streamPayments(ctx, "", filter, collectPayments(&payments))
+}
+
+// CustomerPaymentList returned payments of accounts owned by customer matching filter
+func (p *Postgres) CustomerPaymentList(
+	ctx context.Context, customerID string, filter payment.Filter) ([]*payment.Payment, error) {
+
+	var payments []*payment.Payment
+	return payments, p.streamPayments(ctx, " WHERE a.customer_id=$1", filter, collectPayments(&payments), customerID)
+}
+
+// StreamPayments pass payments stored in database matching filter to sink while rows are read
+func (p *Postgres) StreamPayments(ctx context.Context, filter payment.Filter, sink payment.Sink) error {
+	return p.streamPayments(ctx, "", filter, sink)
+}
+
+// CustomerStreamPayments pass payments of accounts owned by customer matching filter to sink while rows are read
+func (p *Postgres) CustomerStreamPayments(
+	ctx context.Context, customerID string, filter payment.Filter, sink payment.Sink) error {
+
+	return p.streamPayments(ctx, " WHERE a.customer_id=$1", filter, sink, customerID)
+}
+
+func collectPayments(payments *[]*payment.Payment) payment.Sink {
+	return func(res *payment.Payment) error {
+		*payments = append(*payments, res)
+		return nil
+	}
+}
+
+func (p *Postgres) streamPayments(ctx context.Context,
+	where string, filter payment.Filter, sink payment.Sink, args ...interface{}) error {
+
+	return p.beginTransaction(ctx, func(tx *transaction) error {
+		where, args, err := filterConditions(where, "p.", filter.Filter, args)
+		if err != nil {
+			return err
+		}
+		if filter.Category != "" {
+			args = append(args, filter.Category)
+			where = and(where, "p.category=$"+strconv.Itoa(len(args)))
+		}
+		if filter.Query != "" {
+			args = append(args, filter.Query)
+			where = and(where, "to_tsvector("+searchConfig+", p.description) @@ "+
+				"plainto_tsquery("+searchConfig+", $"+strconv.Itoa(len(args))+")")
+		}
+
+		q := "SELECT " + paymentColumns + " FROM payments p JOIN accounts a ON a.id = p.account" + where
+		rows, err := tx.Query(q, args...)
 		if err != nil {
 			return err
 		}
@@ -264,21 +442,87 @@ func (p *Postgres) PaymentList() ([]*payment.Payment, error) {
 		defer rows.Close()
 		for rows.Next() {
 			res := new(payment.Payment)
-			err = rows.Scan(
-				&res.ID,
-				&res.AccountFrom,
-				&res.AccountTo,
-				&res.Amount,
-				&res.Direction,
-				&res.CreatedAt,
-			)
-			if err != nil {
+			if err = scanPayment(rows, res); err != nil {
 				return errors.Wrap(err, "error scan row")
 			}
-
-			payments = append(payments, res)
+			if err = sink(res); err != nil {
+				return err
+			}
 		}
 
-		return nil
+		return rows.Err()
 	})
 }
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAccount(row scanner, acc *account.Account) error {
+	var customerID, externalID sql.NullString
+	var meta []byte
+	err := row.Scan(
+		&acc.ID,
+		&customerID,
+		&acc.Name,
+		&acc.Currency,
+		&acc.Balance,
+		&externalID,
+		&meta,
+		&acc.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	acc.CustomerID = customerID.String
+	acc.ExternalID = externalID.String
+	acc.Metadata, err = decodeMetadata(meta)
+	return err
+}
+
+func scanPayment(row scanner, res *payment.Payment) error {
+	var externalID, description, category sql.NullString
+	var meta []byte
+	err := row.Scan(
+		&res.ID,
+		&res.AccountFrom,
+		&res.AccountTo,
+		&res.Amount,
+		&res.Currency,
+		&res.Direction,
+		&externalID,
+		&meta,
+		&description,
+		&category,
+		&res.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	res.ExternalID = externalID.String
+	res.Description = description.String
+	res.Category = category.String
+	res.Metadata, err = decodeMetadata(meta)
+	return err
+}
+
+func isForeignKeyViolation(err error) bool {
+	e, ok := err.(*pq.Error)
+	return ok && e.Code == errorCodeForeignKeyViolation
+}
+
+func isUniqueViolation(err error) bool {
+	e, ok := err.(*pq.Error)
+	return ok && e.Code == errorCodeUniqueViolation
+}
+
+// isInvalidText value cannot be parsed as column type, e.g. malformed uuid
+func isInvalidText(err error) bool {
+	e, ok := err.(*pq.Error)
+	return ok && e.Code == errorCodeInvalidText
+}
+
+// nullString store empty string as NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
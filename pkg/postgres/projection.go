@@ -0,0 +1,254 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/metadata"
+	"github.com/sbutakov/wallet/pkg/projection"
+)
+
+// eventLockKey key of advisory lock serializing appends of events, lock is held until end
+// of transaction, so identifiers of events grow in order of commits and projections reading
+// events after their position never skip event committed later
+const eventLockKey = 7292
+
+// transferLegs outgoing and incoming legs of transfers within events ($1, $2]
+const transferLegs = "SELECT (payload->>'account_from')::uuid AS account, 'outgoing' AS direction, " +
+	"-(payload->>'amount')::numeric AS amount, id, occurred_at, (payload->>'id')::uuid AS payment " +
+	"FROM events WHERE id > $1 AND id <= $2 AND type = '" + projection.EventMoneyTransferred + "' " +
+	"UNION ALL " +
+	"SELECT (payload->>'account_to')::uuid, 'incoming', (payload->>'amount')::numeric, id, occurred_at, " +
+	"(payload->>'id')::uuid " +
+	"FROM events WHERE id > $1 AND id <= $2 AND type = '" + projection.EventMoneyTransferred + "'"
+
+// projectionTables read models of projections
+var projectionTables = map[string]string{
+	projection.AccountSummaries: "account_summaries",
+	projection.AccountCounters:  "account_counters",
+	projection.AccountActivity:  "account_activity",
+}
+
+// projectionStatements apply events ($1, $2] to read models of projections, events are applied
+// as set, accounts are created before transfers of batch change their balances
+var projectionStatements = map[string][]string{
+	projection.AccountSummaries: {
+		"INSERT INTO account_summaries(id,customer_id,name,currency,balance,external_id,metadata,created_at) " +
+			"SELECT (payload->>'id')::uuid, (payload->>'customer_id')::uuid, payload->>'name', " +
+			"payload->>'currency', (payload->>'balance')::numeric, payload->>'external_id', " +
+			"COALESCE(payload->'metadata', '{}'::jsonb), (payload->>'created_at')::timestamptz " +
+			"FROM events WHERE id > $1 AND id <= $2 AND type = '" + projection.EventAccountCreated + "' " +
+			"ON CONFLICT (id) DO NOTHING",
+		"UPDATE account_summaries s SET balance = s.balance + d.amount " +
+			"FROM (SELECT account, SUM(amount) AS amount FROM (" + transferLegs + ") l GROUP BY account) d " +
+			"WHERE s.id = d.account",
+	},
+	projection.AccountCounters: {
+		"INSERT INTO account_counters(account_id, incoming, outgoing, received, sent) " +
+			"SELECT account, COUNT(*) FILTER (WHERE direction = 'incoming'), " +
+			"COUNT(*) FILTER (WHERE direction = 'outgoing'), " +
+			"COALESCE(SUM(amount) FILTER (WHERE direction = 'incoming'), 0), " +
+			"COALESCE(-SUM(amount) FILTER (WHERE direction = 'outgoing'), 0) " +
+			"FROM (" + transferLegs + ") l GROUP BY account " +
+			"ON CONFLICT (account_id) DO UPDATE SET " +
+			"incoming = account_counters.incoming + EXCLUDED.incoming, " +
+			"outgoing = account_counters.outgoing + EXCLUDED.outgoing, " +
+			"received = account_counters.received + EXCLUDED.received, " +
+			"sent = account_counters.sent + EXCLUDED.sent",
+	},
+	projection.AccountActivity: {
+		"INSERT INTO account_activity(account_id, last_payment_id, last_activity_at) " +
+			"SELECT DISTINCT ON (account) account, payment, occurred_at " +
+			"FROM (" + transferLegs + ") l ORDER BY account, id DESC " +
+			"ON CONFLICT (account_id) DO UPDATE SET " +
+			"last_payment_id = EXCLUDED.last_payment_id, last_activity_at = EXCLUDED.last_activity_at",
+	},
+}
+
+// appendEvent append event consumed by projections within transaction of operation,
+// payload is entity created by operation
+func appendEvent(tx *transaction, eventType, entityID string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "error on marshal event payload")
+	}
+
+	if _, err = tx.Exec("SELECT pg_advisory_xact_lock($1)", eventLockKey); err != nil {
+		return err
+	}
+	q := "INSERT INTO events(type, entity_id, payload) VALUES($1, $2, $3)"
+	_, err = tx.Exec(q, eventType, entityID, data)
+	return err
+}
+
+// ApplyEvents apply at most limit events following position of projection, position is locked
+// until events are applied, so concurrent replicas never apply the same events twice
+func (p *Postgres) ApplyEvents(ctx context.Context, name string, limit int) (int, error) {
+	var applied int
+	return applied, p.beginTransaction(ctx, func(tx *transaction) error {
+		position, err := lockProjection(tx, name)
+		if err != nil {
+			return err
+		}
+
+		var last int64
+		q := "SELECT COUNT(*), COALESCE(MAX(id), $1) FROM " +
+			"(SELECT id FROM events WHERE id > $1 ORDER BY id LIMIT $2) e"
+		if err = tx.QueryRow(q, position, limit).Scan(&applied, &last); err != nil || applied == 0 {
+			return err
+		}
+		return applyEvents(tx, name, position, last)
+	})
+}
+
+// RebuildProjection discard read model of projection and apply all stored events within
+// single transaction, readers view previous read model until rebuild is committed
+func (p *Postgres) RebuildProjection(ctx context.Context, name string) error {
+	return p.beginTransaction(ctx, func(tx *transaction) error {
+		if _, err := lockProjection(tx, name); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM " + projectionTables[name]); err != nil {
+			return err
+		}
+
+		var last int64
+		if err := tx.QueryRow("SELECT COALESCE(MAX(id), 0) FROM events").Scan(&last); err != nil {
+			return err
+		}
+		return applyEvents(tx, name, 0, last)
+	})
+}
+
+// ProjectionStatus return positions of projections and their lag behind last event
+func (p *Postgres) ProjectionStatus(ctx context.Context) ([]*projection.Status, error) {
+	statuses := []*projection.Status{}
+	return statuses, p.beginTransaction(ctx, func(tx *transaction) error {
+		if _, err := tx.Exec("SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY"); err != nil {
+			return err
+		}
+
+		q := "SELECT p.name, p.position, p.updated_at, " +
+			"(SELECT COALESCE(MAX(id), 0) FROM events), " +
+			"(SELECT COUNT(*) FROM events e WHERE e.id > p.position), " +
+			"COALESCE((SELECT EXTRACT(EPOCH FROM NOW() - MIN(e.occurred_at)) FROM events e " +
+			"WHERE e.id > p.position), 0) " +
+			"FROM projections p ORDER BY p.name"
+		rows, err := tx.Query(q)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+		for rows.Next() {
+			res := new(projection.Status)
+			err = rows.Scan(&res.Name, &res.Position, &res.UpdatedAt, &res.LastEventID, &res.Lag, &res.LagSeconds)
+			if err != nil {
+				return err
+			}
+			statuses = append(statuses, res)
+		}
+		return rows.Err()
+	})
+}
+
+// ListAccountSummaries return summaries of accounts matching filter, summaries of all customers
+// are returned when customer is empty
+func (p *Postgres) ListAccountSummaries(
+	ctx context.Context, customerID string, filter metadata.Filter) ([]*projection.AccountSummary, error) {
+
+	summaries := []*projection.AccountSummary{}
+	return summaries, p.beginTransaction(ctx, func(tx *transaction) error {
+		var where string
+		var args []interface{}
+		if customerID != "" {
+			where, args = " WHERE s.customer_id::text=$1", []interface{}{customerID}
+		}
+		where, args, err := filterConditions(where, "s.", filter, args)
+		if err != nil {
+			return err
+		}
+
+		q := "SELECT s.id, s.customer_id, s.name, s.currency, s.balance, s.external_id, s.metadata, " +
+			"COALESCE(c.incoming, 0), COALESCE(c.outgoing, 0), COALESCE(c.received, 0), COALESCE(c.sent, 0), " +
+			"a.last_activity_at, s.created_at FROM account_summaries s " +
+			"LEFT JOIN account_counters c ON c.account_id = s.id " +
+			"LEFT JOIN account_activity a ON a.account_id = s.id" + where + " ORDER BY s.created_at"
+		rows, err := tx.Query(q, args...)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+		for rows.Next() {
+			res := new(projection.AccountSummary)
+			var customerID, externalID sql.NullString
+			var meta []byte
+			var lastActivityAt pq.NullTime
+			err = rows.Scan(&res.ID, &customerID, &res.Name, &res.Currency, &res.Balance, &externalID, &meta,
+				&res.Incoming, &res.Outgoing, &res.Received, &res.Sent, &lastActivityAt, &res.CreatedAt)
+			if err != nil {
+				return err
+			}
+			res.CustomerID = customerID.String
+			res.ExternalID = externalID.String
+			if lastActivityAt.Valid {
+				res.LastActivityAt = &lastActivityAt.Time
+			}
+			if res.Metadata, err = decodeMetadata(meta); err != nil {
+				return err
+			}
+			summaries = append(summaries, res)
+		}
+		return rows.Err()
+	})
+}
+
+// AccountSummaries return storage of accounts listing accounts from account summaries
+// instead of accounts locked by transfers, listed balances lag behind transfers
+func (p *Postgres) AccountSummaries() account.Storage {
+	return &summaryAccounts{Postgres: p}
+}
+
+// summaryAccounts storage of accounts listing accounts from projection
+type summaryAccounts struct {
+	*Postgres
+}
+
+// ListAccount return summaries of accounts matching filter
+func (s *summaryAccounts) ListAccount(ctx context.Context, filter metadata.Filter) ([]*account.Account, error) {
+	return s.listAccounts(ctx, "account_summaries", "", filter)
+}
+
+// ListCustomerAccounts return summaries of accounts owned by customer matching filter
+func (s *summaryAccounts) ListCustomerAccounts(
+	ctx context.Context, customerID string, filter metadata.Filter) ([]*account.Account, error) {
+
+	return s.listAccounts(ctx, "account_summaries", " WHERE customer_id=$1", filter, customerID)
+}
+
+// lockProjection return position of projection locked until end of transaction
+func lockProjection(tx *transaction, name string) (int64, error) {
+	var position int64
+	err := tx.QueryRow("SELECT position FROM projections WHERE name=$1 FOR UPDATE", name).Scan(&position)
+	if err == sql.ErrNoRows {
+		return 0, projection.ErrorUnknownProjection
+	}
+	return position, err
+}
+
+// applyEvents apply events (from, to] to read model of projection and move its position
+func applyEvents(tx *transaction, name string, from, to int64) error {
+	for _, q := range projectionStatements[name] {
+		if _, err := tx.Exec(q, from, to); err != nil {
+			return errors.Wrapf(err, "error on apply events to %s", name)
+		}
+	}
+	_, err := tx.Exec("UPDATE projections SET position = $2, updated_at = NOW() WHERE name=$1", name, to)
+	return err
+}
@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/sbutakov/wallet/pkg/ratelimit"
+)
+
+// Take take token from bucket of key shared by replicas, clock of database server is used
+// so buckets are refilled same regardless of replica clocks
+func (p *Postgres) Take(ctx context.Context, key string, limit ratelimit.Limit) (bool, time.Duration, error) {
+	var (
+		allowed bool
+		wait    time.Duration
+	)
+	err := p.beginTransaction(ctx, func(tx *transaction) error {
+		q := "INSERT INTO rate_limits(key,tokens,updated_at) VALUES($1, $2, now()) ON CONFLICT (key) DO NOTHING"
+		if _, err := tx.Exec(q, key, limit.Burst); err != nil {
+			return err
+		}
+
+		var (
+			bucket ratelimit.Bucket
+			now    time.Time
+		)
+		q = "SELECT tokens,updated_at,now() FROM rate_limits WHERE key=$1 FOR UPDATE"
+		if err := tx.QueryRow(q, key).Scan(&bucket.Tokens, &bucket.UpdatedAt, &now); err != nil {
+			return err
+		}
+
+		allowed, wait = bucket.Take(now, limit)
+		q = "UPDATE rate_limits SET tokens=$2, updated_at=$3 WHERE key=$1"
+		_, err := tx.Exec(q, key, bucket.Tokens, bucket.UpdatedAt)
+		return err
+	})
+	return allowed, wait, err
+}
@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/sbutakov/wallet/pkg/reporting"
+)
+
+// reportViews materialized views of cached reports, every view has unique index,
+// so it is refreshed without locking out readers
+var reportViews = []string{
+	"report_currency_balances",
+	"report_daily_account_volume",
+}
+
+// BalancesByCurrency return number and total balance of accounts per currency
+func (p *Postgres) BalancesByCurrency(ctx context.Context, cached bool) ([]*reporting.CurrencyBalance, error) {
+	q := "SELECT currency, COUNT(*), COALESCE(SUM(balance), 0) FROM accounts GROUP BY currency ORDER BY currency"
+	if cached {
+		q = "SELECT currency, accounts, total FROM report_currency_balances ORDER BY currency"
+	}
+
+	balances := []*reporting.CurrencyBalance{}
+	return balances, p.beginTransaction(ctx, func(tx *transaction) error {
+		rows, err := tx.Query(q)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+		for rows.Next() {
+			res := new(reporting.CurrencyBalance)
+			if err = rows.Scan(&res.Currency, &res.Accounts, &res.Total); err != nil {
+				return err
+			}
+			balances = append(balances, res)
+		}
+		return rows.Err()
+	})
+}
+
+// DailyVolume return number and sum of outgoing payments per day of UTC and currency
+func (p *Postgres) DailyVolume(
+	ctx context.Context, period reporting.Period, cached bool) ([]*reporting.DailyVolume, error) {
+
+	q := "SELECT (p.created_at AT TIME ZONE 'UTC')::date AS day, a.currency, COUNT(*), SUM(p.amount) " +
+		"FROM payments p JOIN accounts a ON a.id = p.account " +
+		"WHERE p.direction = $1 AND p.created_at >= $2 AND p.created_at < $3 " +
+		"GROUP BY day, a.currency ORDER BY day, a.currency"
+	if cached {
+		q = "SELECT v.day, a.currency, SUM(v.transfers), SUM(v.volume) " +
+			"FROM report_daily_account_volume v JOIN accounts a ON a.id = v.account " +
+			"WHERE v.direction = $1 AND v.day >= $2::date AND v.day < $3::date " +
+			"GROUP BY v.day, a.currency ORDER BY v.day, a.currency"
+	}
+
+	volumes := []*reporting.DailyVolume{}
+	return volumes, p.beginTransaction(ctx, func(tx *transaction) error {
+		rows, err := tx.Query(q, paymentOutgoingDirection, period.From, period.To)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+		for rows.Next() {
+			var day time.Time
+			res := new(reporting.DailyVolume)
+			if err = rows.Scan(&day, &res.Currency, &res.Transfers, &res.Volume); err != nil {
+				return err
+			}
+			res.Day = day.Format(reporting.DayLayout)
+			volumes = append(volumes, res)
+		}
+		return rows.Err()
+	})
+}
+
+// TopAccounts return accounts of currency ordered by sum of payments sent or received within period,
+// received payments are incoming transactions of receivers
+func (p *Postgres) TopAccounts(
+	ctx context.Context, query reporting.TopQuery, cached bool) ([]*reporting.TopAccount, error) {
+
+	direction := paymentOutgoingDirection
+	if query.Direction == reporting.DirectionReceivers {
+		direction = paymentIncomingDirection
+	}
+
+	q := "SELECT a.id, a.name, a.currency, t.transfers, t.volume FROM (" +
+		"SELECT p.account, COUNT(*) AS transfers, SUM(p.amount) AS volume FROM payments p " +
+		"WHERE p.direction = $1 AND p.created_at >= $2 AND p.created_at < $3 GROUP BY p.account" +
+		") t JOIN accounts a ON a.id = t.account " +
+		"WHERE $4 = '' OR a.currency = $4 ORDER BY t.volume DESC, a.id LIMIT $5"
+	if cached {
+		q = "SELECT a.id, a.name, a.currency, t.transfers, t.volume FROM (" +
+			"SELECT v.account, SUM(v.transfers) AS transfers, SUM(v.volume) AS volume " +
+			"FROM report_daily_account_volume v " +
+			"WHERE v.direction = $1 AND v.day >= $2::date AND v.day < $3::date GROUP BY v.account" +
+			") t JOIN accounts a ON a.id = t.account " +
+			"WHERE $4 = '' OR a.currency = $4 ORDER BY t.volume DESC, a.id LIMIT $5"
+	}
+
+	accounts := []*reporting.TopAccount{}
+	return accounts, p.beginTransaction(ctx, func(tx *transaction) error {
+		rows, err := tx.Query(q, direction, query.From, query.To, query.Currency, query.Limit)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+		for rows.Next() {
+			res := new(reporting.TopAccount)
+			if err = rows.Scan(&res.AccountID, &res.Name, &res.Currency, &res.Transfers, &res.Volume); err != nil {
+				return err
+			}
+			accounts = append(accounts, res)
+		}
+		return rows.Err()
+	})
+}
+
+// RefreshReports refresh materialized views of cached reports
+func (p *Postgres) RefreshReports(ctx context.Context) error {
+	for _, view := range reportViews {
+		err := p.beginTransaction(ctx, func(tx *transaction) error {
+			_, err := tx.Exec("REFRESH MATERIALIZED VIEW CONCURRENTLY " + view)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/payment"
+	"github.com/sbutakov/wallet/pkg/statement"
+)
+
+// signedAmount amount of payment p, outgoing payment $3 decreases balance of its account
+const signedAmount = "CASE WHEN p.direction = $3 THEN -p.amount ELSE p.amount END"
+
+// balanceAt balance of account a at time $2, latest snapshot taken at or before time is brought
+// forward by payments made since snapshot, balance of account without snapshot is derived from
+// its current balance
+var balanceAt = "COALESCE((SELECT s.balance + COALESCE((SELECT SUM(" + signedAmount + ") FROM payments p " +
+	"WHERE p.account = s.account_id AND p.created_at >= s.as_of AND p.created_at < $2), 0) " +
+	"FROM balance_snapshots s WHERE s.account_id = a.id AND s.as_of <= $2 ORDER BY s.day DESC LIMIT 1), " +
+	derivedBalance("$2") + ")"
+
+// derivedBalance balance of account a at time is its current balance less payments made since then
+func derivedBalance(at string) string {
+	return "a.balance - COALESCE((SELECT SUM(" + signedAmount + ") FROM payments p " +
+		"WHERE p.account = a.id AND p.created_at >= " + at + "), 0)"
+}
+
+// StatementPayments pass balance of account at start of period and payments created within [from, to)
+// to sink while rows are read, all statements read the same snapshot, so opening balance and payments are consistent
+func (p *Postgres) StatementPayments(
+	ctx context.Context, accountID string, from, to time.Time, sink statement.Sink) error {
+
+	return p.beginTransaction(ctx, func(tx *transaction) error {
+		if _, err := tx.Exec("SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY"); err != nil {
+			return err
+		}
+
+		var opening float64
+		q := "SELECT " + balanceAt + " FROM accounts a WHERE a.id=$1"
+		err := tx.QueryRow(q, accountID, from, paymentOutgoingDirection).Scan(&opening)
+		if err == sql.ErrNoRows || isInvalidText(err) {
+			return account.ErrorNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err = sink.Opening(opening); err != nil {
+			return err
+		}
+
+		q = "SELECT " + paymentColumns + " FROM payments p JOIN accounts a ON a.id = p.account " +
+			"WHERE p.account=$1 AND p.created_at >= $2 AND p.created_at < $3 ORDER BY p.created_at, p.id"
+		rows, err := tx.Query(q, accountID, from, to)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+		for rows.Next() {
+			res := new(payment.Payment)
+			if err = scanPayment(rows, res); err != nil {
+				return errors.Wrap(err, "error scan row")
+			}
+			if err = sink.Payment(res); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
+}
+
+// BalanceAt return balance of account at time, payments created before time are included
+func (p *Postgres) BalanceAt(ctx context.Context, accountID string, at time.Time) (float64, error) {
+	var balance float64
+	return balance, p.beginTransaction(ctx, func(tx *transaction) error {
+		q := "SELECT " + balanceAt + " FROM accounts a WHERE a.id=$1"
+		err := tx.QueryRow(q, accountID, at, paymentOutgoingDirection).Scan(&balance)
+		if err == sql.ErrNoRows || isInvalidText(err) {
+			return account.ErrorNotFound
+		}
+		return err
+	})
+}
+
+// BalancesAt return balances at time of accounts opened before time, accounts of all customers
+// are returned when customer is empty
+func (p *Postgres) BalancesAt(ctx context.Context, customerID string, at time.Time) ([]*statement.Balance, error) {
+	balances := []*statement.Balance{}
+	return balances, p.beginTransaction(ctx, func(tx *transaction) error {
+		if _, err := tx.Exec("SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY"); err != nil {
+			return err
+		}
+
+		q := "SELECT a.id, a.currency, " + balanceAt + " FROM accounts a " +
+			"WHERE a.created_at < $2 AND ($1 = '' OR a.customer_id::text = $1) ORDER BY a.id"
+		rows, err := tx.Query(q, customerID, at, paymentOutgoingDirection)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+		for rows.Next() {
+			res := new(statement.Balance)
+			if err = rows.Scan(&res.AccountID, &res.Currency, &res.Balance); err != nil {
+				return err
+			}
+			balances = append(balances, res)
+		}
+		return rows.Err()
+	})
+}
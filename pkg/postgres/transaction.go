@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/sbutakov/wallet/pkg/tracing"
+)
+
+const maxTracedStatementLength = 256
+
+// transaction executes statements within database transaction and records span of every statement
+type transaction struct {
+	ctx    context.Context
+	tx     *sql.Tx
+	tracer *tracing.Tracer
+}
+
+type contextKey int
+
+const contextKeyTransaction contextKey = iota
+
+// withTransaction return context of transaction, storage methods called with it run
+// within transaction instead of their own one
+func withTransaction(tx *transaction) context.Context {
+	return context.WithValue(tx.ctx, contextKeyTransaction, tx)
+}
+
+// Exec execute statement without returning rows
+func (t *transaction) Exec(query string, args ...interface{}) (result sql.Result, err error) {
+	span := t.startSpan(query)
+	defer func() {
+		span.SetError(err)
+		span.Finish()
+	}()
+	return t.tx.ExecContext(t.ctx, query, args...)
+}
+
+// Query execute statement returning rows
+func (t *transaction) Query(query string, args ...interface{}) (rows *sql.Rows, err error) {
+	span := t.startSpan(query)
+	defer func() {
+		span.SetError(err)
+		span.Finish()
+	}()
+	return t.tx.QueryContext(t.ctx, query, args...)
+}
+
+// QueryRow execute statement returning at most one row
+func (t *transaction) QueryRow(query string, args ...interface{}) *sql.Row {
+	span := t.startSpan(query)
+	defer span.Finish()
+	return t.tx.QueryRowContext(t.ctx, query, args...)
+}
+
+func (t *transaction) startSpan(query string) *tracing.Span {
+	if t.tracer == nil {
+		return nil
+	}
+
+	span, _ := t.tracer.StartSpan(t.ctx, "postgres.statement")
+	statement := strings.Join(strings.Fields(query), " ")
+	if len(statement) > maxTracedStatementLength {
+		statement = statement[:maxTracedStatementLength]
+	}
+	span.SetAttribute("db.system", "postgresql")
+	span.SetAttribute("db.statement", statement)
+	return span
+}
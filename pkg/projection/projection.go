@@ -0,0 +1,198 @@
+// Package projection provides read models of accounts maintained from events of accounts and payments,
+// read models are stored apart from accounts locked by transfers, every projection applies events
+// in order of their identifiers and records position of last applied event
+package projection
+
+import (
+	"context"
+	"time"
+
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/customer"
+	"github.com/sbutakov/wallet/pkg/health"
+	"github.com/sbutakov/wallet/pkg/metadata"
+)
+
+// events consumed by projections, payload of event is entity created by operation
+const (
+	EventAccountCreated   = "account.created"
+	EventMoneyTransferred = "payment.transferred"
+)
+
+// projections maintained from events
+const (
+	// AccountSummaries accounts with balances
+	AccountSummaries = "account_summaries"
+	// AccountCounters number and sum of incoming and outgoing payments per account
+	AccountCounters = "account_counters"
+	// AccountActivity last payment of account
+	AccountActivity = "account_activity"
+)
+
+// Names all projections
+var Names = []string{AccountSummaries, AccountCounters, AccountActivity}
+
+var (
+	// ErrorUnknownProjection projection is not one of Names
+	ErrorUnknownProjection = apperror.New(
+		apperror.KindNotFound, "projection_not_found", "projection not found")
+)
+
+// Status position of projection in stream of events, lag is number of events not applied yet
+// and age of oldest of them
+type Status struct {
+	Name        string    `json:"name"`
+	Position    int64     `json:"position"`
+	LastEventID int64     `json:"last_event_id"`
+	Lag         int64     `json:"lag"`
+	LagSeconds  float64   `json:"lag_seconds"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// AccountSummary account joined with its counters and last activity, read models are
+// eventually consistent with accounts and payments
+type AccountSummary struct {
+	ID             string            `json:"id"`
+	CustomerID     string            `json:"customer_id,omitempty"`
+	Name           string            `json:"name"`
+	Currency       string            `json:"currency"`
+	Balance        float64           `json:"balance"`
+	ExternalID     string            `json:"external_id,omitempty"`
+	Metadata       metadata.Metadata `json:"metadata,omitempty"`
+	Incoming       int64             `json:"incoming"`
+	Outgoing       int64             `json:"outgoing"`
+	Received       float64           `json:"received"`
+	Sent           float64           `json:"sent"`
+	LastActivityAt *time.Time        `json:"last_activity_at"`
+	CreatedAt      time.Time         `json:"created_at"`
+}
+
+// Config configuration of projections, events are applied every interval in batches,
+// listing of accounts is served from account summaries when ServeAccounts is set
+type Config struct {
+	Enabled       bool          `default:"true"`
+	Interval      time.Duration `default:"1s"`
+	BatchSize     int           `default:"1000"`
+	ServeAccounts bool          `default:"false"`
+}
+
+// Storage interface for applying events to projections and viewing read models,
+// events and position of projection are applied within single transaction
+type Storage interface {
+	ApplyEvents(ctx context.Context, name string, limit int) (int, error)
+	RebuildProjection(ctx context.Context, name string) error
+	ProjectionStatus(ctx context.Context) ([]*Status, error)
+	ListAccountSummaries(ctx context.Context, customerID string, filter metadata.Filter) ([]*AccountSummary, error)
+}
+
+// Service maintains and views projections
+type Service struct {
+	config  Config
+	storage Storage
+}
+
+// New is constructor
+func New(config Config, storage Storage) *Service {
+	return &Service{
+		config:  config,
+		storage: storage,
+	}
+}
+
+// CatchUp apply all stored events to every projection, projection is applied in batches
+// until batch is not full
+func (s *Service) CatchUp(ctx context.Context) error {
+	for _, name := range Names {
+		for {
+			applied, err := s.storage.ApplyEvents(ctx, name, s.config.BatchSize)
+			if err != nil {
+				return err
+			}
+			if applied < s.config.BatchSize {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// Run apply events to projections every interval until context is done, heartbeat beats
+// after every successful catch up, projections are not maintained when they are disabled
+func (s *Service) Run(ctx context.Context, heartbeat *health.Heartbeat, onError func(error)) {
+	if !s.config.Enabled {
+		return
+	}
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+	for {
+		if err := s.CatchUp(ctx); err != nil {
+			onError(err)
+		} else {
+			heartbeat.Beat()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Rebuild discard read model of projection and apply all events from first one,
+// read model is replaced at once, so readers never see it partially rebuilt
+func (s *Service) Rebuild(ctx context.Context, name string) (*Status, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	if !isProjection(name) {
+		return nil, ErrorUnknownProjection
+	}
+	if err := s.storage.RebuildProjection(ctx, name); err != nil {
+		return nil, err
+	}
+
+	statuses, err := s.storage.ProjectionStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		if status.Name == name {
+			return status, nil
+		}
+	}
+	return nil, ErrorUnknownProjection
+}
+
+// Status view positions and lags of projections
+func (s *Service) Status(ctx context.Context) ([]*Status, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	return s.storage.ProjectionStatus(ctx)
+}
+
+// AccountSummaries view summaries of accounts matching filter, customer principal views
+// only summaries of owned accounts
+func (s *Service) AccountSummaries(ctx context.Context, filter metadata.Filter) ([]*AccountSummary, error) {
+	return s.storage.ListAccountSummaries(ctx, customer.PrincipalCustomerID(ctx), filter)
+}
+
+func isProjection(name string) bool {
+	for _, n := range Names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize reject customers, projections are shared by all of them
+func authorize(ctx context.Context) error {
+	if principal := auth.PrincipalFromContext(ctx); principal != nil && principal.CustomerID != "" {
+		return auth.ErrorForbidden
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package projection
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/health"
+	"github.com/sbutakov/wallet/pkg/metadata"
+)
+
+type dummyStorage struct {
+	pending  map[string]int
+	batches  int
+	rebuilt  string
+	customer string
+	failures int
+	cancel   context.CancelFunc
+}
+
+func (d *dummyStorage) ApplyEvents(_ context.Context, name string, limit int) (int, error) {
+	if d.failures > 0 {
+		d.failures--
+		return 0, errors.New("dummy")
+	}
+	d.batches++
+	applied := d.pending[name]
+	if applied > limit {
+		applied = limit
+	}
+	d.pending[name] -= applied
+	if d.cancel != nil {
+		d.cancel()
+	}
+	return applied, nil
+}
+
+func (d *dummyStorage) RebuildProjection(_ context.Context, name string) error {
+	d.rebuilt = name
+	return nil
+}
+
+func (d *dummyStorage) ProjectionStatus(context.Context) ([]*Status, error) {
+	return []*Status{{Name: d.rebuilt}}, nil
+}
+
+func (d *dummyStorage) ListAccountSummaries(
+	_ context.Context, customerID string, _ metadata.Filter) ([]*AccountSummary, error) {
+
+	d.customer = customerID
+	return nil, nil
+}
+
+func TestService_CatchUp(t *testing.T) {
+	storage := &dummyStorage{pending: map[string]int{AccountSummaries: 25, AccountActivity: 10}}
+	service := New(Config{BatchSize: 10}, storage)
+
+	if err := service.CatchUp(context.Background()); err != nil {
+		t.Fatal("unexpected error on catch up")
+	}
+	// summaries in 3 batches, counters in 1 empty batch, activity in full and empty batches
+	if storage.batches != 6 || storage.pending[AccountSummaries] != 0 || storage.pending[AccountActivity] != 0 {
+		t.Errorf("expected all events applied in batches, got %d batches, pending %v", storage.batches, storage.pending)
+	}
+}
+
+func TestService_Rebuild(t *testing.T) {
+	storage := &dummyStorage{}
+	service := New(Config{}, storage)
+
+	status, err := service.Rebuild(context.Background(), AccountCounters)
+	if err != nil || storage.rebuilt != AccountCounters || status.Name != AccountCounters {
+		t.Errorf("expected projection rebuilt, got %v, %v", status, err)
+	}
+	if _, err = service.Rebuild(context.Background(), "dummy"); err != ErrorUnknownProjection {
+		t.Error("expected unknown projection rejected")
+	}
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{ID: "key", CustomerID: "dummy"})
+	if _, err = service.Rebuild(ctx, AccountCounters); err != auth.ErrorForbidden {
+		t.Error("expected rebuild forbidden to customers")
+	}
+	if _, err = service.Status(ctx); err != auth.ErrorForbidden {
+		t.Error("expected status forbidden to customers")
+	}
+	if _, err = service.AccountSummaries(ctx, metadata.Filter{}); err != nil || storage.customer != "dummy" {
+		t.Error("expected customer views summaries of owned accounts")
+	}
+}
+
+func TestService_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	storage := &dummyStorage{pending: map[string]int{}, failures: 1, cancel: cancel}
+	heartbeat := health.NewHeartbeat(time.Minute)
+
+	var failures int
+	New(Config{Enabled: true, Interval: time.Millisecond, BatchSize: 10}, storage).Run(ctx, heartbeat, func(error) {
+		failures++
+	})
+	if storage.batches != len(Names) || failures != 1 || heartbeat.Check(context.Background()) != nil {
+		t.Errorf("expected events applied until cancel, batches %d, failed %d", storage.batches, failures)
+	}
+
+	storage = &dummyStorage{pending: map[string]int{}}
+	New(Config{}, storage).Run(context.Background(), heartbeat, nil)
+	if storage.batches != 0 {
+		t.Error("expected events not applied when disabled")
+	}
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval interval of removing full buckets from memory
+const sweepInterval = time.Minute
+
+type memoryBucket struct {
+	Bucket
+	limit Limit
+}
+
+// Memory backend keeping buckets in memory of replica
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	swept   time.Time
+	now     func() time.Time
+}
+
+// NewMemory is constructor
+func NewMemory() *Memory {
+	return &Memory{
+		buckets: map[string]*memoryBucket{},
+		now:     time.Now,
+	}
+}
+
+// Take take token from bucket of key
+func (m *Memory) Take(_ context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if now.Sub(m.swept) > sweepInterval {
+		m.sweep(now)
+	}
+
+	bucket, ok := m.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{}
+		m.buckets[key] = bucket
+	}
+	bucket.limit = limit
+	allowed, wait := bucket.Take(now, limit)
+	return allowed, wait, nil
+}
+
+// sweep remove full buckets, they are created again full on next call
+func (m *Memory) sweep(now time.Time) {
+	for key, bucket := range m.buckets {
+		if bucket.full(now, bucket.limit) {
+			delete(m.buckets, key)
+		}
+	}
+	m.swept = now
+}
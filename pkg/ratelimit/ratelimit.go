@@ -0,0 +1,136 @@
+// Package ratelimit provides token bucket rate limiting of API clients and source accounts
+// of payments, buckets are kept in memory of replica or shared by replicas in storage
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/apperror"
+)
+
+// backends of buckets
+const (
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
+// ErrorRateLimited rate limit of client or account exceeded
+var ErrorRateLimited = apperror.New(apperror.KindRateLimited, "rate_limited", "rate limit exceeded")
+
+// Config rate limiting configuration, limit with zero rate is not applied
+type Config struct {
+	Enabled      bool    `default:"true"`
+	Backend      string  `default:"memory"`
+	ClientRate   float64 `default:"10"`
+	ClientBurst  int     `default:"20"`
+	AccountRate  float64 `default:"1"`
+	AccountBurst int     `default:"5"`
+}
+
+// Limit tokens of bucket are refilled at rate per second up to burst, every call takes one token
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Backend storage of buckets, take token from bucket of key or report time until token is available
+type Backend interface {
+	Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error)
+}
+
+// LimitError call is rejected until retry after elapses
+type LimitError struct {
+	Key        string
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("rate limit of %s exceeded, retry after %s", e.Key, e.RetryAfter)
+}
+
+// Cause return ErrorRateLimited
+func (e *LimitError) Cause() error {
+	return ErrorRateLimited
+}
+
+// RetryAfterSeconds whole seconds to wait before retry, at least one
+func (e *LimitError) RetryAfterSeconds() int {
+	return int(math.Max(1, math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// Limiter applies limits of clients and accounts
+type Limiter struct {
+	backend Backend
+	client  Limit
+	account Limit
+}
+
+// New is constructor
+func New(cfg Config, backend Backend) *Limiter {
+	return &Limiter{
+		backend: backend,
+		client:  Limit{Rate: cfg.ClientRate, Burst: cfg.ClientBurst},
+		account: Limit{Rate: cfg.AccountRate, Burst: cfg.AccountBurst},
+	}
+}
+
+// AllowClient take token of API client
+func (l *Limiter) AllowClient(ctx context.Context, client string) error {
+	return l.take(ctx, "client:"+client, l.client)
+}
+
+// AllowAccount take token of source account of payment
+func (l *Limiter) AllowAccount(ctx context.Context, accountID string) error {
+	return l.take(ctx, "account:"+accountID, l.account)
+}
+
+func (l *Limiter) take(ctx context.Context, key string, limit Limit) error {
+	if limit.Rate <= 0 {
+		return nil
+	}
+
+	allowed, wait, err := l.backend.Take(ctx, key, limit)
+	if err != nil {
+		return errors.Wrap(err, "error on take token")
+	}
+	if !allowed {
+		return &LimitError{Key: key, RetryAfter: wait}
+	}
+	return nil
+}
+
+// Bucket state of token bucket
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// Take refill bucket by time elapsed since update and take token, when bucket is empty
+// returns time until token is available, bucket of zero value is full
+func (b *Bucket) Take(now time.Time, limit Limit) (bool, time.Duration) {
+	burst := float64(limit.Burst)
+	if b.UpdatedAt.IsZero() {
+		b.Tokens = burst
+	} else if elapsed := now.Sub(b.UpdatedAt); elapsed > 0 {
+		b.Tokens = math.Min(burst, b.Tokens+elapsed.Seconds()*limit.Rate)
+	}
+	if now.After(b.UpdatedAt) {
+		b.UpdatedAt = now
+	}
+
+	if b.Tokens >= 1 {
+		b.Tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.Tokens) / limit.Rate * float64(time.Second))
+}
+
+// full bucket has refilled up to burst by now, so it is same as bucket of zero value
+func (b *Bucket) full(now time.Time, limit Limit) bool {
+	return b.Tokens+now.Sub(b.UpdatedAt).Seconds()*limit.Rate >= float64(limit.Burst)
+}
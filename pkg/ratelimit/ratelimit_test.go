@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	memory := NewMemory()
+	memory.now = func() time.Time { return now }
+	limiter := New(Config{ClientRate: 2, ClientBurst: 2, AccountRate: 0}, memory)
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := limiter.AllowClient(ctx, "dummy"); err != nil {
+			t.Fatalf("unexpected error on call %d within burst: %v", i, err)
+		}
+	}
+
+	err := limiter.AllowClient(ctx, "dummy")
+	limited, ok := err.(*LimitError)
+	if !ok || errors.Cause(err) != ErrorRateLimited {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+	if limited.RetryAfter != 500*time.Millisecond || limited.RetryAfterSeconds() != 1 {
+		t.Errorf("unexpected retry after %s", limited.RetryAfter)
+	}
+
+	if err = limiter.AllowClient(ctx, "other"); err != nil {
+		t.Error("expected buckets of clients are independent")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if err = limiter.AllowClient(ctx, "dummy"); err != nil {
+		t.Error("expected token refilled")
+	}
+
+	for i := 0; i < 10; i++ {
+		if err = limiter.AllowAccount(ctx, "dummy"); err != nil {
+			t.Fatal("expected limit with zero rate not applied")
+		}
+	}
+}
+
+func TestMemory_Sweep(t *testing.T) {
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	memory := NewMemory()
+	memory.now = func() time.Time { return now }
+
+	limit := Limit{Rate: 1, Burst: 1}
+	memory.Take(context.Background(), "full", limit)                           // nolint: errcheck
+	memory.Take(context.Background(), "drained", limit)                        // nolint: errcheck
+	memory.Take(context.Background(), "drained", Limit{Rate: 0.001, Burst: 1}) // nolint: errcheck
+
+	now = now.Add(2 * sweepInterval)
+	memory.Take(context.Background(), "new", limit) // nolint: errcheck
+	if _, ok := memory.buckets["full"]; ok {
+		t.Error("expected refilled bucket removed")
+	}
+	if _, ok := memory.buckets["drained"]; !ok {
+		t.Error("expected bucket not refilled yet kept")
+	}
+}
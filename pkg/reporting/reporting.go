@@ -0,0 +1,209 @@
+// Package reporting provides aggregates of balances and transfers for finance reports,
+// reports of transfers cover whole days of UTC, so they may be served by materialized
+// views refreshed in background instead of aggregating payments on every call
+package reporting
+
+import (
+	"context"
+	"time"
+
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/health"
+)
+
+// directions of top accounts
+const (
+	DirectionSenders   = "senders"
+	DirectionReceivers = "receivers"
+)
+
+const (
+	// DayLayout layout of days of reports
+	DayLayout = "2006-01-02"
+	// MaxPeriodDays limit of days of reported period
+	MaxPeriodDays = 366
+
+	day             = 24 * time.Hour
+	defaultDays     = 30
+	defaultTopLimit = 10
+	maxTopLimit     = 100
+)
+
+var (
+	// ErrorPeriod period is empty or too long
+	ErrorPeriod = apperror.New(apperror.KindInvalidArgument, "invalid_report_period",
+		"period must start before it ends and cover at most 366 days")
+	// ErrorUnknownDirection direction is not senders or receivers
+	ErrorUnknownDirection = apperror.New(apperror.KindInvalidArgument, "unknown_direction",
+		"direction must be senders or receivers")
+	// ErrorIncorrectLimit limit must be positive
+	ErrorIncorrectLimit = apperror.New(
+		apperror.KindInvalidArgument, "invalid_limit", "limit must be greater than zero")
+)
+
+// CurrencyBalance total balance of accounts in currency
+type CurrencyBalance struct {
+	Currency string  `json:"currency"`
+	Accounts int64   `json:"accounts"`
+	Total    float64 `json:"total"`
+}
+
+// DailyVolume number and sum of transfers in currency made within day
+type DailyVolume struct {
+	Day       string  `json:"day"`
+	Currency  string  `json:"currency"`
+	Transfers int64   `json:"transfers"`
+	Volume    float64 `json:"volume"`
+}
+
+// TopAccount number and sum of transfers sent or received by account within period
+type TopAccount struct {
+	AccountID string  `json:"account_id"`
+	Name      string  `json:"name"`
+	Currency  string  `json:"currency"`
+	Transfers int64   `json:"transfers"`
+	Volume    float64 `json:"volume"`
+}
+
+// Period whole days of UTC from first day until last day exclusive
+type Period struct {
+	From time.Time
+	To   time.Time
+}
+
+// TopQuery params of top accounts, accounts of all currencies are ranked when currency is empty
+type TopQuery struct {
+	Period
+	Direction string
+	Currency  string
+	Limit     int
+}
+
+// Config configuration of reports, reports are read from materialized views refreshed
+// every interval when cache is enabled, so they lag behind payments
+type Config struct {
+	Cache           bool
+	RefreshInterval time.Duration `default:"5m"`
+}
+
+// Storage interface for aggregating accounts and payments, cached aggregates are read
+// from materialized views
+type Storage interface {
+	BalancesByCurrency(ctx context.Context, cached bool) ([]*CurrencyBalance, error)
+	DailyVolume(ctx context.Context, period Period, cached bool) ([]*DailyVolume, error)
+	TopAccounts(ctx context.Context, query TopQuery, cached bool) ([]*TopAccount, error)
+	RefreshReports(ctx context.Context) error
+}
+
+// Service computes reports
+type Service struct {
+	config  Config
+	storage Storage
+}
+
+// New is constructor
+func New(config Config, storage Storage) *Service {
+	return &Service{
+		config:  config,
+		storage: storage,
+	}
+}
+
+// Balances total balance of accounts per currency
+func (s *Service) Balances(ctx context.Context) ([]*CurrencyBalance, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	return s.storage.BalancesByCurrency(ctx, s.config.Cache)
+}
+
+// DailyVolume number and sum of transfers per day and currency within period
+func (s *Service) DailyVolume(ctx context.Context, period Period) ([]*DailyVolume, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	period, err := period.normalize()
+	if err != nil {
+		return nil, err
+	}
+	return s.storage.DailyVolume(ctx, period, s.config.Cache)
+}
+
+// TopAccounts accounts sent or received most money within period, ordered by volume
+func (s *Service) TopAccounts(ctx context.Context, query TopQuery) ([]*TopAccount, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	if query.Direction != DirectionSenders && query.Direction != DirectionReceivers {
+		return nil, ErrorUnknownDirection
+	}
+	if query.Limit < 0 {
+		return nil, ErrorIncorrectLimit
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultTopLimit
+	}
+	if query.Limit > maxTopLimit {
+		query.Limit = maxTopLimit
+	}
+
+	var err error
+	if query.Period, err = query.Period.normalize(); err != nil {
+		return nil, err
+	}
+	return s.storage.TopAccounts(ctx, query, s.config.Cache)
+}
+
+// Run refresh cached reports every interval until context is done, heartbeat beats
+// after every successful refresh, reports are not refreshed when cache is disabled
+func (s *Service) Run(ctx context.Context, heartbeat *health.Heartbeat, onError func(error)) {
+	if !s.config.Cache {
+		return
+	}
+
+	ticker := time.NewTicker(s.config.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.storage.RefreshReports(ctx); err != nil {
+			onError(err)
+		} else {
+			heartbeat.Beat()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ParseDay parse day of report in DayLayout
+func ParseDay(value string) (time.Time, error) {
+	return time.Parse(DayLayout, value)
+}
+
+// normalize truncate bounds of period to days, period is last days until tomorrow
+// unless bounds are set
+func (p Period) normalize() (Period, error) {
+	if p.To.IsZero() {
+		p.To = time.Now().UTC().Truncate(day).Add(day)
+	}
+	if p.From.IsZero() {
+		p.From = p.To.AddDate(0, 0, -defaultDays)
+	}
+	p.From, p.To = p.From.UTC().Truncate(day), p.To.UTC().Truncate(day)
+	if !p.From.Before(p.To) || p.To.Sub(p.From) > MaxPeriodDays*day {
+		return p, ErrorPeriod
+	}
+	return p, nil
+}
+
+// authorize reject customers, reports aggregate accounts of all of them
+func authorize(ctx context.Context) error {
+	if principal := auth.PrincipalFromContext(ctx); principal != nil && principal.CustomerID != "" {
+		return auth.ErrorForbidden
+	}
+	return nil
+}
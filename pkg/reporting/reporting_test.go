@@ -0,0 +1,126 @@
+package reporting
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/health"
+)
+
+type dummyStorage struct {
+	cached    bool
+	period    Period
+	query     TopQuery
+	refreshed int
+	cancel    context.CancelFunc
+}
+
+func (d *dummyStorage) BalancesByCurrency(_ context.Context, cached bool) ([]*CurrencyBalance, error) {
+	d.cached = cached
+	return nil, nil
+}
+
+func (d *dummyStorage) DailyVolume(_ context.Context, period Period, cached bool) ([]*DailyVolume, error) {
+	d.period, d.cached = period, cached
+	return nil, nil
+}
+
+func (d *dummyStorage) TopAccounts(_ context.Context, query TopQuery, cached bool) ([]*TopAccount, error) {
+	d.query, d.cached = query, cached
+	return nil, nil
+}
+
+func (d *dummyStorage) RefreshReports(context.Context) error {
+	d.refreshed++
+	if d.refreshed == 1 {
+		return errors.New("dummy")
+	}
+	d.cancel()
+	return nil
+}
+
+func TestService_DailyVolume(t *testing.T) {
+	storage := &dummyStorage{}
+	service := New(Config{Cache: true}, storage)
+
+	from := time.Date(2026, 9, 1, 10, 30, 0, 0, time.UTC)
+	if _, err := service.DailyVolume(context.Background(), Period{From: from}); err != nil {
+		t.Fatal("unexpected error on daily volume")
+	}
+	tomorrow := time.Now().UTC().Truncate(day).Add(day)
+	if !storage.cached || !storage.period.From.Equal(from.Truncate(day)) || !storage.period.To.Equal(tomorrow) {
+		t.Errorf("expected period of whole days until tomorrow, got %v", storage.period)
+	}
+
+	if _, err := service.DailyVolume(context.Background(), Period{}); err != nil ||
+		storage.period.To.Sub(storage.period.From) != defaultDays*day {
+		t.Errorf("expected default period, got %v, %v", storage.period, err)
+	}
+
+	for _, period := range []Period{
+		{From: from, To: from.Add(time.Hour)},
+		{From: from, To: from.AddDate(0, 0, -1)},
+		{From: from, To: from.AddDate(0, 0, MaxPeriodDays+1)},
+	} {
+		if _, err := service.DailyVolume(context.Background(), period); err != ErrorPeriod {
+			t.Errorf("expected period %v rejected, got %v", period, err)
+		}
+	}
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{ID: "key", CustomerID: "dummy"})
+	if _, err := service.DailyVolume(ctx, Period{}); err != auth.ErrorForbidden {
+		t.Error("expected reports forbidden to customers")
+	}
+	if _, err := service.Balances(ctx); err != auth.ErrorForbidden {
+		t.Error("expected reports forbidden to customers")
+	}
+}
+
+func TestService_TopAccounts(t *testing.T) {
+	storage := &dummyStorage{}
+	service := New(Config{}, storage)
+
+	if _, err := service.TopAccounts(context.Background(), TopQuery{Direction: DirectionSenders}); err != nil {
+		t.Fatal("unexpected error on top accounts")
+	}
+	if storage.cached || storage.query.Limit != defaultTopLimit {
+		t.Errorf("expected default limit, got %d", storage.query.Limit)
+	}
+
+	query := TopQuery{Direction: DirectionReceivers, Limit: 1000}
+	if _, err := service.TopAccounts(context.Background(), query); err != nil || storage.query.Limit != maxTopLimit {
+		t.Errorf("expected limit truncated, got %d", storage.query.Limit)
+	}
+
+	if _, err := service.TopAccounts(context.Background(), TopQuery{Direction: "dummy"}); err != ErrorUnknownDirection {
+		t.Error("expected unknown direction rejected")
+	}
+	query = TopQuery{Direction: DirectionSenders, Limit: -1}
+	if _, err := service.TopAccounts(context.Background(), query); err != ErrorIncorrectLimit {
+		t.Error("expected negative limit rejected")
+	}
+}
+
+func TestService_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	storage := &dummyStorage{cancel: cancel}
+	heartbeat := health.NewHeartbeat(time.Minute)
+
+	var failures int
+	New(Config{Cache: true, RefreshInterval: time.Millisecond}, storage).Run(ctx, heartbeat, func(error) {
+		failures++
+	})
+	if storage.refreshed != 2 || failures != 1 || heartbeat.Check(context.Background()) != nil {
+		t.Errorf("expected reports refreshed until cancel, refreshed %d, failed %d", storage.refreshed, failures)
+	}
+
+	storage = &dummyStorage{}
+	New(Config{}, storage).Run(context.Background(), heartbeat, nil)
+	if storage.refreshed != 0 {
+		t.Error("expected reports not refreshed without cache")
+	}
+}
@@ -0,0 +1,89 @@
+package statement
+
+import (
+	"context"
+	"time"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/customer"
+)
+
+var (
+	// ErrorFutureBalance balance is asked at time in future
+	ErrorFutureBalance = apperror.New(
+		apperror.KindInvalidArgument, "future_balance", "balance cannot be asked at time in future")
+	// ErrorNotOpened account was opened at or after time of balance
+	ErrorNotOpened = apperror.New(
+		apperror.KindNotFound, "account_not_opened", "account was not opened at time of balance")
+)
+
+// Balance balance of account at time, payments created before time are included
+type Balance struct {
+	AccountID string    `json:"account_id"`
+	Currency  string    `json:"currency"`
+	Balance   float64   `json:"balance"`
+	AsOf      time.Time `json:"as_of"`
+}
+
+// BalanceStorage interface for deriving balances at time from payments made since then
+type BalanceStorage interface {
+	BalanceAt(ctx context.Context, accountID string, at time.Time) (float64, error)
+	BalancesAt(ctx context.Context, customerID string, at time.Time) ([]*Balance, error)
+}
+
+// Balance balance of account at time, current balance is overwritten by payments,
+// so balance at time is derived from payments made since then
+func (s *Service) Balance(ctx context.Context, accountID string, at time.Time) (*Balance, error) {
+	if at.After(time.Now()) {
+		return nil, ErrorFutureBalance
+	}
+
+	acc, err := s.storage.AssertAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err = account.Authorize(ctx, acc); err != nil {
+		return nil, err
+	}
+	if !acc.CreatedAt.Before(at) {
+		return nil, ErrorNotOpened
+	}
+
+	balance, err := s.storage.BalanceAt(ctx, acc.ID, at)
+	if err != nil {
+		return nil, err
+	}
+	return &Balance{AccountID: acc.ID, Currency: acc.Currency, Balance: round(balance), AsOf: at.UTC()}, nil
+}
+
+// Balances balances of all accounts opened before time, customer principal views
+// only balances of owned accounts
+func (s *Service) Balances(ctx context.Context, at time.Time) ([]*Balance, error) {
+	if at.After(time.Now()) {
+		return nil, ErrorFutureBalance
+	}
+
+	balances, err := s.storage.BalancesAt(ctx, customer.PrincipalCustomerID(ctx), at)
+	if err != nil {
+		return nil, err
+	}
+	for _, balance := range balances {
+		balance.Balance = round(balance.Balance)
+		balance.AsOf = at.UTC()
+	}
+	return balances, nil
+}
+
+// ParseAsOf parse time of balance, date is end of day, so balance of date includes its payments,
+// end of today is current time
+func ParseAsOf(value string) (time.Time, error) {
+	if t, err := time.Parse(dateLayout, value); err == nil {
+		end, now := t.AddDate(0, 0, 1), time.Now()
+		if !t.After(now) && end.After(now) {
+			return now, nil
+		}
+		return end, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,165 @@
+package statement
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sbutakov/wallet/pkg/payment"
+)
+
+// ContentTypes content types of formats
+var ContentTypes = map[string]string{
+	FormatCSV:       "text/csv; charset=utf-8",
+	FormatJSONLines: "application/x-ndjson",
+	FormatText:      "text/plain; charset=utf-8",
+}
+
+// writer write parts of statement in format
+type writer interface {
+	Opening(st *Statement) error
+	Line(line *Line) error
+	Closing(st *Statement) error
+}
+
+func newWriter(format string, w io.Writer) writer {
+	switch format {
+	case FormatJSONLines:
+		return &jsonLinesWriter{encoder: json.NewEncoder(w)}
+	case FormatText:
+		return &textWriter{w: w}
+	}
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// csvWriter write statement as rows of same columns, balances are rows of their own type
+type csvWriter struct {
+	w        *csv.Writer
+	currency string
+}
+
+func (c *csvWriter) Opening(st *Statement) error {
+	c.currency = st.Account.Currency
+	c.w.Write([]string{ // nolint: errcheck
+		"type", "created_at", "payment_id", "direction", "counterparty", "amount", "balance", "currency",
+		"category", "description"})
+	return c.write("opening_balance", st.From, "", "", "", "", st.OpeningBalance, st.Account.Currency, nil)
+}
+
+func (c *csvWriter) Line(line *Line) error {
+	p := line.Payment
+	return c.write("payment", p.CreatedAt, p.ID, p.Direction, p.AccountTo, formatAmount(line.Amount),
+		line.Balance, c.currency, p)
+}
+
+func (c *csvWriter) Closing(st *Statement) error {
+	if err := c.write("closing_balance", st.To, "", "", "", "", st.ClosingBalance, st.Account.Currency,
+		nil); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// write row of statement, category and description are taken from payment of row
+func (c *csvWriter) write(kind string, at time.Time, id, direction, counterparty, amount string, balance float64,
+	currency string, p *payment.Payment) error {
+
+	var category, description string
+	if p != nil {
+		category, description = p.Category, p.Description
+	}
+	c.w.Write([]string{ // nolint: errcheck
+		kind, at.Format(time.RFC3339), id, direction, counterparty, amount, formatAmount(balance), currency,
+		category, description})
+	return c.w.Error()
+}
+
+type jsonLine struct {
+	Type         string     `json:"type"`
+	AccountID    string     `json:"account_id,omitempty"`
+	Currency     string     `json:"currency,omitempty"`
+	From         *time.Time `json:"from,omitempty"`
+	To           *time.Time `json:"to,omitempty"`
+	PaymentID    string     `json:"payment_id,omitempty"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	Direction    string     `json:"direction,omitempty"`
+	Counterparty string     `json:"counterparty,omitempty"`
+	Amount       string     `json:"amount,omitempty"`
+	Balance      string     `json:"balance"`
+	Category     string     `json:"category,omitempty"`
+	Description  string     `json:"description,omitempty"`
+}
+
+// jsonLinesWriter write statement as JSON object per line, amounts are decimal strings
+type jsonLinesWriter struct {
+	encoder *json.Encoder
+}
+
+func (j *jsonLinesWriter) Opening(st *Statement) error {
+	return j.encoder.Encode(jsonLine{
+		Type:      "opening_balance",
+		AccountID: st.Account.ID,
+		Currency:  st.Account.Currency,
+		From:      &st.From,
+		To:        &st.To,
+		Balance:   formatAmount(st.OpeningBalance),
+	})
+}
+
+func (j *jsonLinesWriter) Line(line *Line) error {
+	return j.encoder.Encode(jsonLine{
+		Type:         "payment",
+		PaymentID:    line.Payment.ID,
+		CreatedAt:    &line.Payment.CreatedAt,
+		Direction:    line.Payment.Direction,
+		Counterparty: line.Payment.AccountTo,
+		Amount:       formatAmount(line.Amount),
+		Balance:      formatAmount(line.Balance),
+		Category:     line.Payment.Category,
+		Description:  line.Payment.Description,
+	})
+}
+
+func (j *jsonLinesWriter) Closing(st *Statement) error {
+	return j.encoder.Encode(jsonLine{
+		Type:    "closing_balance",
+		Balance: formatAmount(st.ClosingBalance),
+	})
+}
+
+const textLineFormat = "%-20s  %-36s  %-8s  %-36s  %14s  %14s\n"
+
+// textWriter write statement as fixed width table, so it is printed without buffering rows
+type textWriter struct {
+	w io.Writer
+}
+
+func (t *textWriter) Opening(st *Statement) error {
+	_, err := fmt.Fprintf(t.w, "Statement of account %s (%s)\nCurrency: %s\nPeriod: %s - %s\n\n"+
+		"Opening balance: %s\n\n"+textLineFormat+"%s\n",
+		st.Account.Name, st.Account.ID, strings.ToUpper(st.Account.Currency),
+		st.From.Format(time.RFC3339), st.To.Format(time.RFC3339), formatAmount(st.OpeningBalance),
+		"DATE", "PAYMENT", "TYPE", "COUNTERPARTY", "AMOUNT", "BALANCE", strings.Repeat("-", 138))
+	return err
+}
+
+func (t *textWriter) Line(line *Line) error {
+	p := line.Payment
+	_, err := fmt.Fprintf(t.w, textLineFormat, p.CreatedAt.UTC().Format(time.RFC3339), p.ID, p.Direction,
+		p.AccountTo, formatAmount(line.Amount), formatAmount(line.Balance))
+	return err
+}
+
+func (t *textWriter) Closing(st *Statement) error {
+	_, err := fmt.Fprintf(t.w, "\nClosing balance: %s\n", formatAmount(st.ClosingBalance))
+	return err
+}
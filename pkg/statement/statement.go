@@ -0,0 +1,174 @@
+// Package statement provides statements of account for period streamed in CSV, JSON Lines
+// and plain text, opening balance is followed by payments of period and closing balance
+package statement
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"math"
+	"time"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/payment"
+)
+
+// formats of statement
+const (
+	FormatCSV       = "csv"
+	FormatJSONLines = "jsonl"
+	FormatText      = "txt"
+)
+
+const (
+	outgoingPayment  = "outgoing"
+	balancePrecision = 100
+	dateLayout       = "2006-01-02"
+)
+
+// Formats supported formats of statement
+var Formats = []string{FormatCSV, FormatJSONLines, FormatText}
+
+var (
+	// ErrorUnknownFormat format of statement is not supported
+	ErrorUnknownFormat = apperror.New(
+		apperror.KindInvalidArgument, "unknown_statement_format", "unknown statement format")
+	// ErrorPeriod period ends before it starts
+	ErrorPeriod = apperror.New(apperror.KindInvalidArgument, "invalid_period", "period must end after it starts")
+)
+
+// Statement statement of account for period [From, To), balances are set while statement is written
+type Statement struct {
+	Account        *account.Account
+	From           time.Time
+	To             time.Time
+	Format         string
+	OpeningBalance float64
+	ClosingBalance float64
+}
+
+// Line payment of statement, amount is negative for outgoing payments,
+// balance is balance of account after payment
+type Line struct {
+	Payment *payment.Payment
+	Amount  float64
+	Balance float64
+}
+
+// Sink receives balance of account at start of period and then payments of period in order of creation
+type Sink interface {
+	Opening(balance float64) error
+	Payment(p *payment.Payment) error
+}
+
+// Storage interface for streaming payments of account and deriving balances
+type Storage interface {
+	BalanceStorage
+	AssertAccount(ctx context.Context, id string) (*account.Account, error)
+	StatementPayments(ctx context.Context, accountID string, from, to time.Time, sink Sink) error
+}
+
+// Service generates statements
+type Service struct {
+	storage Storage
+}
+
+// New is constructor
+func New(storage Storage) *Service {
+	return &Service{
+		storage: storage,
+	}
+}
+
+// Prepare check period, format and access of principal to account, so statement
+// is rejected before anything is written, empty format is CSV
+func (s *Service) Prepare(
+	ctx context.Context, accountID string, from, to time.Time, format string) (*Statement, error) {
+
+	if format == "" {
+		format = FormatCSV
+	}
+	if !contains(format, Formats) {
+		return nil, ErrorUnknownFormat
+	}
+	if !from.Before(to) {
+		return nil, ErrorPeriod
+	}
+
+	acc, err := s.storage.AssertAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err = account.Authorize(ctx, acc); err != nil {
+		return nil, err
+	}
+
+	return &Statement{
+		Account: acc,
+		From:    from.UTC(),
+		To:      to.UTC(),
+		Format:  format,
+	}, nil
+}
+
+// Write stream statement to w in its format, payments are not kept in memory
+func (s *Service) Write(ctx context.Context, st *Statement, w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	writer := newWriter(st.Format, buf)
+	if err := s.storage.StatementPayments(ctx, st.Account.ID, st.From, st.To, &sink{st: st, w: writer}); err != nil {
+		return err
+	}
+	if err := writer.Closing(st); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+type sink struct {
+	st *Statement
+	w  writer
+}
+
+func (s *sink) Opening(balance float64) error {
+	s.st.OpeningBalance = round(balance)
+	s.st.ClosingBalance = s.st.OpeningBalance
+	return s.w.Opening(s.st)
+}
+
+func (s *sink) Payment(p *payment.Payment) error {
+	amount := p.Amount
+	if p.Direction == outgoingPayment {
+		amount = -amount
+	}
+	s.st.ClosingBalance = round(s.st.ClosingBalance + amount)
+	return s.w.Line(&Line{Payment: p, Amount: amount, Balance: s.st.ClosingBalance})
+}
+
+// ParseTime parse bound of period, RFC 3339 time or date meaning its midnight in UTC
+func ParseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(dateLayout, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// StartOfMonth midnight of first day of month of t in UTC, start of default period
+func StartOfMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// round balance to cents, so sums of amounts do not accumulate float errors
+func round(v float64) float64 {
+	return math.Round(v*balancePrecision) / balancePrecision
+}
+
+func contains(str string, arr []string) bool {
+	for _, item := range arr {
+		if str == item {
+			return true
+		}
+	}
+	return false
+}
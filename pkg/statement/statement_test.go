@@ -0,0 +1,210 @@
+package statement
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbutakov/wallet/pkg/account"
+	"github.com/sbutakov/wallet/pkg/auth"
+	"github.com/sbutakov/wallet/pkg/customer"
+	"github.com/sbutakov/wallet/pkg/payment"
+)
+
+type dummyStorage struct {
+	payments []*payment.Payment
+}
+
+func (d *dummyStorage) AssertAccount(_ context.Context, id string) (*account.Account, error) {
+	if id != "dummy" {
+		return nil, account.ErrorNotFound
+	}
+	return &account.Account{ID: id, Name: "dummy", Currency: "usd", CustomerID: "owner"}, nil
+}
+
+func (d *dummyStorage) StatementPayments(_ context.Context, _ string, _, _ time.Time, sink Sink) error {
+	if err := sink.Opening(100); err != nil {
+		return err
+	}
+	for _, p := range d.payments {
+		if err := sink.Payment(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *dummyStorage) BalanceAt(context.Context, string, time.Time) (float64, error) {
+	return 100.004, nil
+}
+
+func (d *dummyStorage) BalancesAt(_ context.Context, customerID string, _ time.Time) ([]*Balance, error) {
+	return []*Balance{{AccountID: customerID, Balance: 0.1 + 0.2}}, nil
+}
+
+func TestService_Prepare(t *testing.T) {
+	service := New(&dummyStorage{})
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	st, err := service.Prepare(context.Background(), "dummy", from, to, "")
+	if err != nil || st.Format != FormatCSV {
+		t.Errorf("expected csv statement by default, got %v", err)
+	}
+
+	if _, err = service.Prepare(context.Background(), "dummy", from, to, "pdf"); err != ErrorUnknownFormat {
+		t.Errorf("expected unknown format error, got %v", err)
+	}
+
+	if _, err = service.Prepare(context.Background(), "dummy", to, from, FormatCSV); err != ErrorPeriod {
+		t.Errorf("expected period error, got %v", err)
+	}
+
+	if _, err = service.Prepare(context.Background(), "unknown", from, to, FormatCSV); err != account.ErrorNotFound {
+		t.Errorf("expected account not found error, got %v", err)
+	}
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{ID: "key", CustomerID: "stranger"})
+	if _, err = service.Prepare(ctx, "dummy", from, to, FormatCSV); err != customer.ErrorNotOwner {
+		t.Error("expected statement of account of other customer rejected")
+	}
+}
+
+func TestService_Write(t *testing.T) {
+	createdAt := time.Date(2026, 9, 2, 10, 0, 0, 0, time.UTC)
+	service := New(&dummyStorage{payments: []*payment.Payment{
+		{ID: "1", AccountTo: "other", Amount: 0.1, Direction: "incoming", CreatedAt: createdAt},
+		{ID: "2", AccountTo: "other", Amount: 0.2, Direction: "incoming", CreatedAt: createdAt},
+		{ID: "3", AccountTo: "other", Amount: 50, Direction: "outgoing", CreatedAt: createdAt,
+			Description: "rent, july", Category: "bills"},
+	}})
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	for format, expected := range map[string][]string{
+		FormatCSV: {
+			"type,created_at,payment_id,direction,counterparty,amount,balance,currency,category,description",
+			"opening_balance,2026-09-01T00:00:00Z,,,,,100.00,usd,,",
+			"payment,2026-09-02T10:00:00Z,1,incoming,other,0.10,100.10,usd,,",
+			"payment,2026-09-02T10:00:00Z,2,incoming,other,0.20,100.30,usd,,",
+			`payment,2026-09-02T10:00:00Z,3,outgoing,other,-50.00,50.30,usd,bills,"rent, july"`,
+			"closing_balance,2026-10-01T00:00:00Z,,,,,50.30,usd,,",
+		},
+		FormatJSONLines: {
+			`{"type":"opening_balance","account_id":"dummy","currency":"usd",` +
+				`"from":"2026-09-01T00:00:00Z","to":"2026-10-01T00:00:00Z","balance":"100.00"}`,
+			`{"type":"payment","payment_id":"1","created_at":"2026-09-02T10:00:00Z","direction":"incoming",` +
+				`"counterparty":"other","amount":"0.10","balance":"100.10"}`,
+			`{"type":"payment","payment_id":"2","created_at":"2026-09-02T10:00:00Z","direction":"incoming",` +
+				`"counterparty":"other","amount":"0.20","balance":"100.30"}`,
+			`{"type":"payment","payment_id":"3","created_at":"2026-09-02T10:00:00Z","direction":"outgoing",` +
+				`"counterparty":"other","amount":"-50.00","balance":"50.30","category":"bills","description":"rent, july"}`,
+			`{"type":"closing_balance","balance":"50.30"}`,
+		},
+	} {
+		st, err := service.Prepare(context.Background(), "dummy", from, from.AddDate(0, 1, 0), format)
+		if err != nil {
+			t.Fatal("unexpected error on prepare statement")
+		}
+
+		out := new(bytes.Buffer)
+		if err = service.Write(context.Background(), st, out); err != nil {
+			t.Fatalf("unexpected error on write %s statement: %v", format, err)
+		}
+		if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); strings.Join(lines, "\n") !=
+			strings.Join(expected, "\n") {
+			t.Errorf("unexpected %s statement:\n%s", format, out.String())
+		}
+	}
+
+	st, err := service.Prepare(context.Background(), "dummy", from, from.AddDate(0, 1, 0), FormatText)
+	if err != nil {
+		t.Fatal("unexpected error on prepare statement")
+	}
+	out := new(bytes.Buffer)
+	if err = service.Write(context.Background(), st, out); err != nil {
+		t.Fatal("unexpected error on write text statement")
+	}
+	if !strings.Contains(out.String(), "Opening balance: 100.00") ||
+		!strings.Contains(out.String(), "Closing balance: 50.30") {
+		t.Errorf("unexpected text statement:\n%s", out.String())
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	for value, expected := range map[string]time.Time{
+		"2026-09-01":                time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+		"2026-09-01T12:30:00Z":      time.Date(2026, 9, 1, 12, 30, 0, 0, time.UTC),
+		"2026-09-01T12:30:00+02:00": time.Date(2026, 9, 1, 10, 30, 0, 0, time.UTC),
+	} {
+		if parsed, err := ParseTime(value); err != nil || !parsed.Equal(expected) {
+			t.Errorf("unexpected time %v of %s", parsed, value)
+		}
+	}
+
+	if _, err := ParseTime("yesterday"); err == nil {
+		t.Error("expected error on parse invalid time")
+	}
+
+	if !StartOfMonth(time.Date(2026, 9, 15, 23, 0, 0, 0, time.UTC)).Equal(time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("unexpected start of month")
+	}
+}
+
+func TestService_Balance(t *testing.T) {
+	service := New(&dummyStorage{})
+	at := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	balance, err := service.Balance(context.Background(), "dummy", at)
+	if err != nil || balance.Balance != 100 || balance.Currency != "usd" || !balance.AsOf.Equal(at) {
+		t.Errorf("unexpected balance %v, %v", balance, err)
+	}
+
+	if _, err = service.Balance(context.Background(), "dummy", time.Now().Add(time.Hour)); err != ErrorFutureBalance {
+		t.Error("expected balance in future rejected")
+	}
+
+	if _, err = service.Balance(context.Background(), "dummy", time.Time{}); err != ErrorNotOpened {
+		t.Error("expected balance before account is opened rejected")
+	}
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{ID: "key", CustomerID: "stranger"})
+	if _, err = service.Balance(ctx, "dummy", at); err != customer.ErrorNotOwner {
+		t.Error("expected balance of account of other customer rejected")
+	}
+
+	balances, err := service.Balances(ctx, at)
+	if err != nil || len(balances) != 1 || balances[0].AccountID != "stranger" || balances[0].Balance != 0.3 {
+		t.Errorf("expected rounded balances of accounts of customer, got %v, %v", balances, err)
+	}
+}
+
+func TestParseAsOf(t *testing.T) {
+	at, err := ParseAsOf("2026-06-30")
+	if err != nil || !at.Equal(time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected date parsed as end of day, got %v", at)
+	}
+
+	at, err = ParseAsOf("2026-06-30T12:00:00Z")
+	if err != nil || !at.Equal(time.Date(2026, 6, 30, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected time parsed as is, got %v", at)
+	}
+
+	if _, err = ParseAsOf("june"); err == nil {
+		t.Error("expected malformed time rejected")
+	}
+
+	today := time.Now().UTC()
+	if at, err = ParseAsOf(today.Format(dateLayout)); err != nil || at.Before(today) || at.After(time.Now()) {
+		t.Errorf("expected today parsed as current time, got %v", at)
+	}
+	if _, err = New(&dummyStorage{}).Balance(context.Background(), "dummy", at); err != nil {
+		t.Errorf("unexpected error on balance as of today, %v", err)
+	}
+
+	tomorrow := today.AddDate(0, 0, 1).Format(dateLayout)
+	if at, err = ParseAsOf(tomorrow); err != nil || !at.After(time.Now()) {
+		t.Errorf("expected tomorrow parsed as end of day, got %v", at)
+	}
+}
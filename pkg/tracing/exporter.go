@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Exporter interface for sending finished spans to tracing backend
+type Exporter interface {
+	Export(span *SpanData)
+}
+
+// WriterExporter write finished spans as JSON lines, e.g. to stdout
+type WriterExporter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewWriterExporter is constructor
+func NewWriterExporter(w io.Writer) *WriterExporter {
+	return &WriterExporter{
+		encoder: json.NewEncoder(w),
+	}
+}
+
+// Export write span
+func (e *WriterExporter) Export(span *SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.encoder.Encode(span) // nolint: errcheck
+}
+
+// MemoryExporter keep finished spans in memory, useful in tests and offline debugging
+type MemoryExporter struct {
+	mu    sync.RWMutex
+	spans []*SpanData
+	limit int
+}
+
+// NewMemoryExporter is constructor, limit restricts count of kept spans, zero means unlimited
+func NewMemoryExporter(limit int) *MemoryExporter {
+	return &MemoryExporter{
+		limit: limit,
+	}
+}
+
+// Export keep span, the oldest span is dropped when limit is reached
+func (e *MemoryExporter) Export(span *SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.limit > 0 && len(e.spans) >= e.limit {
+		e.spans = e.spans[1:]
+	}
+	e.spans = append(e.spans, span)
+}
+
+// Spans return kept spans in order of finishing
+func (e *MemoryExporter) Spans() []*SpanData {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	spans := make([]*SpanData, len(e.spans))
+	copy(spans, e.spans)
+	return spans
+}
+
+// Reset drop kept spans
+func (e *MemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// ServeHTTP write kept spans as JSON
+func (e *MemoryExporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(e.Spans()) // nolint: errcheck
+}
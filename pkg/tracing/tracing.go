@@ -0,0 +1,290 @@
+// Package tracing provides methods for recording spans of distributed traces
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// TraceParentHeader header of W3C trace context propagation
+	TraceParentHeader = "traceparent"
+
+	traceParentVersion = "00"
+	flagSampled        = 0x01
+)
+
+var (
+	// ErrorInvalidTraceParent malformed traceparent header
+	ErrorInvalidTraceParent = errors.New("invalid traceparent")
+	// ErrorUnknownExporter unknown exporter type
+	ErrorUnknownExporter = errors.New("unknown exporter")
+)
+
+const (
+	// ExporterNone tracing is disabled
+	ExporterNone = ""
+	// ExporterStdout spans are written to stdout
+	ExporterStdout = "stdout"
+	// ExporterMemory spans are kept in memory
+	ExporterMemory = "memory"
+)
+
+// Config configuration params of tracing
+type Config struct {
+	Exporter    string
+	MemoryLimit int `default:"1000"`
+}
+
+// NewExporter make exporter by config, nil exporter means tracing is disabled
+func NewExporter(config Config) (Exporter, error) {
+	switch config.Exporter {
+	case ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		return NewWriterExporter(os.Stdout), nil
+	case ExporterMemory:
+		return NewMemoryExporter(config.MemoryLimit), nil
+	}
+	return nil, ErrorUnknownExporter
+}
+
+type contextKey int
+
+const (
+	contextKeySpan contextKey = iota
+	contextKeyRemote
+)
+
+// SpanContext identifies span within trace
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// Valid trace and span identifiers are not zero
+func (c SpanContext) Valid() bool {
+	return c.TraceID != [16]byte{} && c.SpanID != [8]byte{}
+}
+
+// TraceParent format span context as traceparent header value
+func (c SpanContext) TraceParent() string {
+	flags := "00"
+	if c.Sampled {
+		flags = "01"
+	}
+	return strings.Join([]string{
+		traceParentVersion,
+		hex.EncodeToString(c.TraceID[:]),
+		hex.EncodeToString(c.SpanID[:]),
+		flags,
+	}, "-")
+}
+
+// ParseTraceParent parse traceparent header value
+func ParseTraceParent(value string) (SpanContext, error) {
+	sc := SpanContext{}
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, ErrorInvalidTraceParent
+	}
+	if parts[0] == traceParentVersion && len(parts) != 4 {
+		return sc, ErrorInvalidTraceParent
+	}
+
+	traceID, err := hex.DecodeString(parts[1])
+	if err != nil || len(traceID) != len(sc.TraceID) {
+		return sc, ErrorInvalidTraceParent
+	}
+	spanID, err := hex.DecodeString(parts[2])
+	if err != nil || len(spanID) != len(sc.SpanID) {
+		return sc, ErrorInvalidTraceParent
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return sc, ErrorInvalidTraceParent
+	}
+
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Sampled = flags[0]&flagSampled != 0
+	if !sc.Valid() {
+		return sc, ErrorInvalidTraceParent
+	}
+	return sc, nil
+}
+
+// SpanData finished span passed to exporter
+type SpanData struct {
+	Name       string            `json:"name"`
+	TraceID    string            `json:"trace_id"`
+	SpanID     string            `json:"span_id"`
+	ParentID   string            `json:"parent_id,omitempty"`
+	Start      time.Time         `json:"start"`
+	End        time.Time         `json:"end"`
+	Duration   time.Duration     `json:"duration"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// Span single operation within trace
+type Span struct {
+	mu       sync.Mutex
+	tracer   *Tracer
+	context  SpanContext
+	parentID [8]byte
+	name     string
+	start    time.Time
+	attrs    map[string]string
+	err      error
+	finished bool
+}
+
+// Context return span context, zero value for nil span
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.context
+}
+
+// SetAttribute add attribute to span
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attrs == nil {
+		s.attrs = make(map[string]string)
+	}
+	s.attrs[key] = value
+}
+
+// SetError mark span failed
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// Finish end span and pass it to exporter when trace is sampled
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.finished {
+		s.mu.Unlock()
+		return
+	}
+	s.finished = true
+	end := time.Now()
+	data := &SpanData{
+		Name:       s.name,
+		TraceID:    hex.EncodeToString(s.context.TraceID[:]),
+		SpanID:     hex.EncodeToString(s.context.SpanID[:]),
+		Start:      s.start,
+		End:        end,
+		Duration:   end.Sub(s.start),
+		Attributes: s.attrs,
+	}
+	if s.parentID != [8]byte{} {
+		data.ParentID = hex.EncodeToString(s.parentID[:])
+	}
+	if s.err != nil {
+		data.Error = s.err.Error()
+	}
+	s.mu.Unlock()
+
+	if s.context.Sampled {
+		s.tracer.exporter.Export(data)
+	}
+}
+
+// Tracer starts spans and passes finished ones to exporter, nil tracer records nothing
+type Tracer struct {
+	exporter Exporter
+}
+
+// New is constructor, returns nil tracer when exporter is nil
+func New(exporter Exporter) *Tracer {
+	if exporter == nil {
+		return nil
+	}
+	return &Tracer{
+		exporter: exporter,
+	}
+}
+
+// StartSpan start span as child of span or remote span context stored in context
+func (t *Tracer) StartSpan(ctx context.Context, name string) (*Span, context.Context) {
+	if t == nil {
+		return nil, ctx
+	}
+
+	span := &Span{
+		tracer: t,
+		name:   name,
+		start:  time.Now(),
+	}
+
+	parent, ok := parentContext(ctx)
+	if ok {
+		span.context.TraceID = parent.TraceID
+		span.context.Sampled = parent.Sampled
+		span.parentID = parent.SpanID
+	} else {
+		randomID(span.context.TraceID[:])
+		span.context.Sampled = true
+	}
+	randomID(span.context.SpanID[:])
+
+	return span, context.WithValue(ctx, contextKeySpan, span)
+}
+
+// SpanFromContext return current span, nil when context has no span
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(contextKeySpan).(*Span)
+	return span
+}
+
+// ContextWithRemoteParent store span context received from remote service
+func ContextWithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, contextKeyRemote, sc)
+}
+
+func parentContext(ctx context.Context) (SpanContext, bool) {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.context, true
+	}
+	if sc, ok := ctx.Value(contextKeyRemote).(SpanContext); ok && sc.Valid() {
+		return sc, true
+	}
+	return SpanContext{}, false
+}
+
+func randomID(id []byte) {
+	for {
+		if _, err := rand.Read(id); err != nil {
+			panic(errors.Wrap(err, "error on generate identifier"))
+		}
+		for _, b := range id {
+			if b != 0 {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	value := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceParent(value)
+	if err != nil {
+		t.Fatal("unexpected error on parse traceparent")
+	}
+
+	if !sc.Sampled {
+		t.Error("expected sampled span context")
+	}
+
+	if sc.TraceParent() != value {
+		t.Error("unexpected formatted traceparent")
+	}
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+	}
+	for _, value := range invalid {
+		if _, err := ParseTraceParent(value); err != ErrorInvalidTraceParent {
+			t.Errorf("expected error on parse %q", value)
+		}
+	}
+}
+
+func TestTracer_StartSpan(t *testing.T) {
+	exporter := NewMemoryExporter(0)
+	tracer := New(exporter)
+
+	remote, _ := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := ContextWithRemoteParent(context.Background(), remote)
+
+	parent, ctx := tracer.StartSpan(ctx, "parent")
+	child, _ := tracer.StartSpan(ctx, "child")
+	child.SetError(errors.New("dummy error"))
+	child.Finish()
+	parent.Finish()
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatal("unexpected count of exported spans")
+	}
+
+	if spans[0].Name != "child" || spans[0].Error != "dummy error" {
+		t.Error("unexpected child span")
+	}
+
+	if spans[0].ParentID != spans[1].SpanID {
+		t.Error("child span must refer to parent span")
+	}
+
+	if spans[1].ParentID != "00f067aa0ba902b7" || spans[1].TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Error("parent span must continue remote trace")
+	}
+}
+
+func TestTracer_Nil(t *testing.T) {
+	var tracer *Tracer
+	span, ctx := tracer.StartSpan(context.Background(), "dummy")
+	span.SetAttribute("key", "value")
+	span.Finish()
+
+	if SpanFromContext(ctx) != nil {
+		t.Error("unexpected span of nil tracer")
+	}
+}
@@ -0,0 +1,201 @@
+// Package validation provides declarative validation of request fields by struct tags
+//
+// Rules are listed in `validate` tag separated by comma, field name is taken from `json` tag:
+//
+//	type request struct {
+//		Name string `json:"name" validate:"required,max=50,name"`
+//	}
+//
+// Supported rules: required, uuid, min=N, max=N (length of string or slice),
+// name, currency, email, date (day as YYYY-MM-DD), precision=N (decimal places of number),
+// metadata (limits of keys and values of metadata map).
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sbutakov/wallet/pkg/apperror"
+	"github.com/sbutakov/wallet/pkg/metadata"
+)
+
+const (
+	tagName = "validate"
+
+	// CodeValidationFailed code of error on invalid request fields
+	CodeValidationFailed = "validation_failed"
+)
+
+var (
+	uuidPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	currencyPattern = regexp.MustCompile(`^[a-zA-Z]{3}$`)
+	emailPattern    = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	datePattern     = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}$`)
+)
+
+// rule check value of field, returns reason of rejection or empty string
+type rule func(value reflect.Value, param string) string
+
+var rules = map[string]rule{
+	"required":  required,
+	"uuid":      matches(uuidPattern, "must be uuid"),
+	"currency":  matches(currencyPattern, "must be three letters currency code"),
+	"email":     matches(emailPattern, "must be email address"),
+	"date":      matches(datePattern, "must be date YYYY-MM-DD"),
+	"name":      name,
+	"min":       minLength,
+	"max":       maxLength,
+	"precision": precision,
+	"metadata":  metadataLimits,
+}
+
+// Validate check fields of struct by rules of their tags, returns error
+// with reasons of all rejected fields
+func Validate(v interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []apperror.FieldError
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		tag := field.Tag.Get(tagName)
+		if tag == "" {
+			continue
+		}
+
+		for _, item := range strings.Split(tag, ",") {
+			ruleName, param := item, ""
+			if n := strings.Index(item, "="); n >= 0 {
+				ruleName, param = item[:n], item[n+1:]
+			}
+
+			check, ok := rules[ruleName]
+			if !ok {
+				panic(fmt.Sprintf("validation: unknown rule %q of field %s", ruleName, field.Name))
+			}
+
+			fieldValue := value.Field(i)
+			if ruleName != "required" && isZero(fieldValue) {
+				continue
+			}
+			if reason := check(fieldValue, param); reason != "" {
+				fields = append(fields, apperror.FieldError{Field: fieldName(field), Reason: reason})
+				break
+			}
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+	return &apperror.Error{
+		Kind:    apperror.KindInvalidArgument,
+		Code:    CodeValidationFailed,
+		Message: "request validation failed",
+		Fields:  fields,
+	}
+}
+
+func fieldName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+	return field.Name
+}
+
+func isZero(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
+
+func required(value reflect.Value, _ string) string {
+	if isZero(value) {
+		return "is required"
+	}
+	return ""
+}
+
+func matches(pattern *regexp.Regexp, reason string) rule {
+	return func(value reflect.Value, _ string) string {
+		if value.Kind() != reflect.String || !pattern.MatchString(value.String()) {
+			return reason
+		}
+		return ""
+	}
+}
+
+// name allows letters, digits, spaces and punctuation usual for names
+func name(value reflect.Value, _ string) string {
+	for _, r := range value.String() {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(" -_.,'&", r) {
+			return "must contain only letters, digits, spaces and -_.,'&"
+		}
+	}
+	return ""
+}
+
+func length(value reflect.Value) int {
+	if value.Kind() == reflect.String {
+		return utf8.RuneCountInString(value.String())
+	}
+	return value.Len()
+}
+
+func minLength(value reflect.Value, param string) string {
+	if length(value) < intParam(param) {
+		return "must be at least " + param + " long"
+	}
+	return ""
+}
+
+func maxLength(value reflect.Value, param string) string {
+	if length(value) > intParam(param) {
+		return "must be at most " + param + " long"
+	}
+	return ""
+}
+
+// precision allows at most param digits after decimal point of shortest representation of number
+func precision(value reflect.Value, param string) string {
+	formatted := strconv.FormatFloat(value.Float(), 'f', -1, 64)
+	if n := strings.Index(formatted, "."); n >= 0 && len(formatted)-n-1 > intParam(param) {
+		return "must have at most " + param + " decimal places"
+	}
+	return ""
+}
+
+// metadataLimits limit number of keys of metadata and length of keys and values
+func metadataLimits(value reflect.Value, _ string) string {
+	if value.Len() > metadata.MaxKeys {
+		return fmt.Sprintf("must have at most %d keys", metadata.MaxKeys)
+	}
+	for _, key := range value.MapKeys() {
+		if n := utf8.RuneCountInString(key.String()); n == 0 || n > metadata.MaxKeyLength {
+			return fmt.Sprintf("keys must be 1 to %d characters long", metadata.MaxKeyLength)
+		}
+		if utf8.RuneCountInString(value.MapIndex(key).String()) > metadata.MaxValueLength {
+			return fmt.Sprintf("values must be at most %d characters long", metadata.MaxValueLength)
+		}
+	}
+	return ""
+}
+
+func intParam(param string) int {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		panic(fmt.Sprintf("validation: param %q is not integer", param))
+	}
+	return n
+}
@@ -0,0 +1,77 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sbutakov/wallet/pkg/apperror"
+)
+
+type dummyRequest struct {
+	ID       string   `json:"id" validate:"uuid"`
+	Name     string   `json:"name" validate:"required,max=5,name"`
+	Currency string   `json:"currency" validate:"currency"`
+	Email    string   `json:"email" validate:"email"`
+	Day      string   `json:"day" validate:"date"`
+	Amount   float64  `json:"amount" validate:"precision=2"`
+	Scopes   []string `json:"scopes" validate:"required,min=2"`
+
+	Metadata map[string]string `json:"metadata" validate:"metadata"`
+}
+
+func TestValidate(t *testing.T) {
+	valid := dummyRequest{
+		ID:       "7b6c7d2a-3f0e-4d8e-9a51-1f3c2b4a5d60",
+		Name:     "Élan",
+		Currency: "usd",
+		Email:    "alice@example.com",
+		Day:      "2026-10-01",
+		Amount:   100.01,
+		Scopes:   []string{"a", "b"},
+	}
+	if err := Validate(valid); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if err := Validate(&dummyRequest{Name: "Bob", Scopes: []string{"a", "b"}}); err != nil {
+		t.Error("expected optional fields are skipped when empty")
+	}
+
+	invalid := dummyRequest{
+		ID:       "7b6c7d2a3f0e4d8e9a511f3c2b4a5d60",
+		Name:     strings.Repeat("a", 6),
+		Currency: "us",
+		Email:    "alice",
+		Day:      "01.10.2026",
+		Amount:   0.001,
+		Scopes:   []string{"a"},
+		Metadata: map[string]string{"order": strings.Repeat("a", 501)},
+	}
+	e, ok := Validate(invalid).(*apperror.Error)
+	if !ok || e.Code != CodeValidationFailed || len(e.Fields) != 8 {
+		t.Fatalf("expected all fields rejected, got %+v", e)
+	}
+
+	e = Validate(dummyRequest{Name: "<b>", Scopes: []string{"a", "b"}}).(*apperror.Error)
+	if e.Fields[0].Field != "name" || e.Fields[0].Reason == "" {
+		t.Error("expected name with markup rejected")
+	}
+}
+
+func TestValidate_Metadata(t *testing.T) {
+	keys := map[string]string{}
+	for i := 0; i < 21; i++ {
+		keys[strings.Repeat("k", i+1)] = "v"
+	}
+
+	for _, m := range []map[string]string{keys, {"": "v"}, {strings.Repeat("k", 41): "v"}} {
+		if Validate(dummyRequest{Name: "Bob", Scopes: []string{"a", "b"}, Metadata: m}) == nil {
+			t.Errorf("expected metadata %v rejected", m)
+		}
+	}
+
+	m := map[string]string{"order": "42", strings.Repeat("k", 40): strings.Repeat("v", 500)}
+	if err := Validate(dummyRequest{Name: "Bob", Scopes: []string{"a", "b"}, Metadata: m}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}